@@ -0,0 +1,126 @@
+// Command jsonschema validates JSON documents against a JSON Schema.
+//
+// Usage:
+//
+//	jsonschema -schema schema.json [-lines] [-workers n] [file ...]
+//
+// Each file, or standard input when no files are given, is validated as a
+// single JSON document. With -lines every line of the input is validated
+// as a separate record, as for JSON Lines or NDJSON, and errors are
+// reported with their line numbers. jsonschema exits with status 1 when
+// any input is invalid.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+
+	"github.com/qri-io/jsonschema"
+)
+
+func main() {
+	os.Exit(cli(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// cli runs the command with args, returning its exit status: 0 when all
+// inputs are valid, 1 when any is invalid and 2 on errors
+func cli(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("jsonschema", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	schemaPath := flags.String("schema", "", "path to the JSON Schema to validate against")
+	lines := flags.Bool("lines", false, "validate each line of the input as a separate JSON document")
+	workers := flags.Int("workers", runtime.NumCPU(), "number of lines validated concurrently with -lines")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: jsonschema -schema schema.json [-lines] [-workers n] [file ...]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *schemaPath == "" {
+		flags.Usage()
+		return 2
+	}
+
+	ok, err := run(ctx, *schemaPath, flags.Args(), *lines, *workers, stdin, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "jsonschema: %s\n", err)
+		return 2
+	}
+	if !ok {
+		return 1
+	}
+	return 0
+}
+
+// run validates the named files, or stdin, against the schema at
+// schemaPath, writing errors to w. It reports whether all inputs are valid
+func run(ctx context.Context, schemaPath string, files []string, lines bool, workers int, stdin io.Reader, w io.Writer) (bool, error) {
+	data, err := ioutil.ReadFile(schemaPath)
+	if err != nil {
+		return false, err
+	}
+	rs := &jsonschema.Schema{}
+	if err := rs.UnmarshalJSON(data); err != nil {
+		return false, fmt.Errorf("%s: %w", schemaPath, err)
+	}
+
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	valid := true
+	for _, name := range files {
+		ok, err := validateFile(ctx, rs, name, lines, workers, stdin, w)
+		if err != nil {
+			return false, err
+		}
+		valid = valid && ok
+	}
+	return valid, nil
+}
+
+// validateFile validates the named file, or stdin for "-", writing errors
+// to w. It reports whether the file is valid
+func validateFile(ctx context.Context, rs *jsonschema.Schema, name string, lines bool, workers int, stdin io.Reader, w io.Writer) (bool, error) {
+	r := stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return false, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	valid := true
+	if lines {
+		opts := jsonschema.LinesOptions{Workers: workers}
+		err := rs.ValidateLinesWithOptions(ctx, r, opts, func(line int, errs []jsonschema.KeyError) {
+			for _, e := range errs {
+				valid = false
+				fmt.Fprintf(w, "%s:%d: %s\n", name, line, e.Error())
+			}
+		})
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", name, err)
+		}
+		return valid, nil
+	}
+
+	errs, err := rs.ValidateReader(ctx, r)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", name, err)
+	}
+	for _, e := range errs {
+		valid = false
+		fmt.Fprintf(w, "%s: %s\n", name, e.Error())
+	}
+	return valid, nil
+}
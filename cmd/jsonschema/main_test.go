@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCLI(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	schema := write("schema.json", `{"type": "object", "properties": {"id": {"type": "integer"}}}`)
+	valid := write("valid.json", `{"id": 1}`)
+	invalid := write("invalid.json", `{"id": "a"}`)
+	var records strings.Builder
+	for i := 1; i <= 100; i++ {
+		if i%10 == 0 {
+			fmt.Fprintf(&records, "{\"id\": \"%d\"}\n", i)
+			continue
+		}
+		fmt.Fprintf(&records, "{\"id\": %d}\n", i)
+	}
+	lines := write("records.jsonl", records.String())
+	var linesOutput strings.Builder
+	for i := 10; i <= 100; i += 10 {
+		fmt.Fprintf(&linesOutput, "%s:%d: /id: %q type should be integer, got string\n", lines, i, fmt.Sprint(i))
+	}
+
+	cases := []struct {
+		args   []string
+		stdin  string
+		status int
+		stdout string
+		stderr string
+	}{
+		{[]string{"-schema", schema, valid}, "", 0, "", ""},
+		{[]string{"-schema", schema, valid, invalid}, "", 1, invalid + `: /id: "a" type should be integer, got string` + "\n", ""},
+		{[]string{"-schema", schema}, `{"id": 2}`, 0, "", ""},
+		{[]string{"-schema", schema, "-"}, `{"id": true}`, 1, "-: /id: true type should be integer, got boolean\n", ""},
+		{[]string{"-schema", schema, "-lines", "-workers", "1", valid}, "", 0, "", ""},
+		{[]string{"-schema", schema, "-lines", "-workers", "1", lines}, "", 1, linesOutput.String(), ""},
+		{[]string{"-schema", schema, "-lines", "-workers", "8", lines}, "", 1, linesOutput.String(), ""},
+		{[]string{"-schema", schema, "-lines"}, "{\"id\": 1}\n{\"id\": null}\n", 1, "-:2: /id: type should be integer, got null\n", ""},
+		{[]string{"-schema", schema, filepath.Join(dir, "missing.json")}, "", 2, "", "no such file or directory"},
+		{[]string{"-schema", filepath.Join(dir, "missing.json"), valid}, "", 2, "", "no such file or directory"},
+		{[]string{valid}, "", 2, "", "usage: jsonschema"},
+		{[]string{"-unknown"}, "", 2, "", "flag provided but not defined"},
+	}
+
+	for i, c := range cases {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		status := cli(context.Background(), c.args, strings.NewReader(c.stdin), stdout, stderr)
+		if status != c.status {
+			t.Errorf("case %d %v: expected exit status %d. got: %d. stderr: %s", i, c.args, c.status, status, stderr)
+		}
+		if stdout.String() != c.stdout {
+			t.Errorf("case %d %v: output mismatch. expected:\n%s\ngot:\n%s", i, c.args, c.stdout, stdout)
+		}
+		if !strings.Contains(stderr.String(), c.stderr) {
+			t.Errorf("case %d %v: expected stderr to include %q. got: %s", i, c.args, c.stderr, stderr)
+		}
+	}
+}
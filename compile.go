@@ -0,0 +1,281 @@
+package jsonschema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CompiledSchema is a schema with all references resolved ahead of
+// validation. Validating with a CompiledSchema doesn't modify the schema
+// or any shared registry, making it safe for concurrent use
+type CompiledSchema struct {
+	schema *Schema
+	// known maps the URIs of the schema resources and dynamic anchors
+	// reachable from the schema to the schemas they identify
+	known map[string]*Schema
+	// formats holds the format checkers registered globally when the
+	// schema was compiled
+	formats map[string]FormatChecker
+	// registry is the registry the references of the schema were
+	// resolved with
+	registry *SchemaRegistry
+}
+
+// Compile resolves all references of the schema and the schemas it refers
+// to, returning an error listing any reference that can't be resolved.
+// In FormatAssertAll mode, see WithFormatMode, unknown formats are errors.
+// Schemas are registered and resolved with the registry of ctx, see
+// WithSchemaRegistry, which the compiled schema keeps using whatever the
+// registry of the context it validates with. Formats are checked with the checkers registered
+// globally at the time of compiling. The schema must not be validated
+// while compiling
+func Compile(ctx context.Context, s *Schema) (*CompiledSchema, error) {
+	if s == nil {
+		return nil, fmt.Errorf("schema is nil")
+	}
+
+	c := &compiler{
+		ctx:     ctx,
+		visited: map[*Schema]bool{},
+		known:   map[string]*Schema{},
+	}
+	state := newContextValidationState(ctx, s)
+	c.walk(state, s)
+	for len(c.pending) > 0 {
+		ref := c.pending[0]
+		c.pending = c.pending[1:]
+		c.resolve(ref)
+	}
+
+	if len(c.denied) > 0 {
+		return nil, &UnresolvableReferenceError{URIs: c.denied}
+	}
+	if len(c.unresolved) > 0 {
+		return nil, fmt.Errorf("failed to resolve schema for ref %s", strings.Join(c.unresolved, ", "))
+	}
+	if len(c.unknownFormats) > 0 {
+		return nil, fmt.Errorf("unknown format %s", strings.Join(c.unknownFormats, ", "))
+	}
+	return &CompiledSchema{schema: s, known: c.known, formats: state.formats, registry: state.registry()}, nil
+}
+
+// Schema returns the compiled schema
+func (cs *CompiledSchema) Schema() *Schema {
+	return cs.schema
+}
+
+// Validate uses the compiled schema to check an instance, collecting
+// validation errors in the returned state
+func (cs *CompiledSchema) Validate(ctx context.Context, data interface{}) *ValidationState {
+	currentState := cs.validationState(ctx)
+	doc, err := jsonValue(data)
+	if err != nil {
+		currentState.AddError(data, fmt.Sprintf("invalid instance: %s", err.Error()))
+		return currentState
+	}
+	cs.schema.ValidateKeyword(ctx, currentState, doc)
+	return currentState
+}
+
+// validationState creates the state validating with the compiled schema,
+// resolving with the registry the schema was compiled with
+func (cs *CompiledSchema) validationState(ctx context.Context) *ValidationState {
+	currentState := newFormatsValidationState(ctx, cs.schema, cs.formats)
+	currentState.LocalRegistry.parent = cs.registry
+	currentState.known = cs.known
+	return currentState
+}
+
+// ValidateBytes performs validation against a slice of json byte data
+func (cs *CompiledSchema) ValidateBytes(ctx context.Context, data []byte) ([]KeyError, error) {
+	var doc interface{}
+	if err := decodeJSON(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing JSON bytes: %w", err)
+	}
+	vs := cs.Validate(ctx, doc)
+	return *vs.Errs, nil
+}
+
+// compiler walks a schema and all schemas it references, resolving
+// references in the scope they would be evaluated in
+type compiler struct {
+	ctx        context.Context
+	visited    map[*Schema]bool
+	known      map[string]*Schema
+	pending    []pendingRef
+	unresolved []string
+	// denied lists the URIs of schemas that may not be fetched
+	denied []string
+	// unknownFormats lists the formats without checker when asserting
+	// all formats
+	unknownFormats []string
+}
+
+// pendingRef is a reference keyword waiting to be resolved along with
+// the state of the schema declaring it
+type pendingRef struct {
+	state   *ValidationState
+	keyword Keyword
+}
+
+// walk enters sch and its subschemas, queueing their references for
+// resolution once the schemas of the current document are registered
+func (c *compiler) walk(currentState *ValidationState, sch *Schema) {
+	if sch == nil || sch.schemaType != schemaTypeObject || c.visited[sch] {
+		return
+	}
+	c.visited[sch] = true
+	sch.enterScope(currentState)
+
+	if sch.docPath != "" && currentState.BaseURI != "" {
+		c.known[currentState.BaseURI] = sch
+	}
+	if anchor := sch.dynamicAnchor(); anchor != "" {
+		c.known[currentState.BaseURI+"#"+anchor] = sch
+	}
+
+	for _, name := range sch.orderedkeywords {
+		keyword := sch.keywords[name]
+		switch k := keyword.(type) {
+		case *Ref, *RecursiveRef, *DynamicRef:
+			c.pending = append(c.pending, pendingRef{state: currentState.NewSubState(), keyword: keyword})
+		case *Format:
+			c.checkFormat(currentState, string(*k))
+		case *AnnotatedFormat:
+			c.checkFormat(currentState, string(*k))
+		}
+		for _, sub := range subschemas(keyword) {
+			c.walk(currentState.NewSubState(), sub)
+		}
+	}
+}
+
+// checkFormat records a format without checker if all formats are asserted
+func (c *compiler) checkFormat(currentState *ValidationState, name string) {
+	if FormatModeFromContext(c.ctx) != FormatAssertAll {
+		return
+	}
+	if _, known := currentState.formatChecker(name); known {
+		return
+	}
+	for _, f := range c.unknownFormats {
+		if f == name {
+			return
+		}
+	}
+	c.unknownFormats = append(c.unknownFormats, name)
+}
+
+// resolve resolves a queued reference and walks the referenced schema
+func (c *compiler) resolve(p pendingRef) {
+	var res *refResolution
+	switch k := p.keyword.(type) {
+	case *Ref:
+		res = k.resolve(c.ctx, p.state)
+	case *DynamicRef:
+		res = k.resolve(c.ctx, p.state)
+	case *RecursiveRef:
+		res = k.resolve(c.ctx, p.state)
+	}
+	reference, resolved, resolvedRoot, resolveErr := res.reference, res.resolved, res.resolvedRoot, res.resolveErr
+
+	if resolved == nil {
+		var unresolvable *UnresolvableReferenceError
+		if errors.As(resolveErr, &unresolvable) {
+			c.denied = append(c.denied, unresolvable.URIs...)
+			return
+		}
+		var policyErr *PolicyError
+		if errors.As(resolveErr, &policyErr) {
+			reference = fmt.Sprintf("%s (%s)", reference, policyErr.Error())
+		}
+		c.unresolved = append(c.unresolved, reference)
+		return
+	}
+
+	subState := p.state.NewSubState()
+	if resolvedRoot != nil {
+		subState.BaseURI = resolvedRoot.docPath
+		subState.Root = resolvedRoot
+		c.walk(subState.NewSubState(), resolvedRoot)
+	}
+	c.walk(subState, resolved)
+}
+
+// subschemas lists the schemas embedded in a keyword
+func subschemas(keyword Keyword) []*Schema {
+	switch k := keyword.(type) {
+	case *Schema:
+		return []*Schema{k}
+	case *If:
+		return []*Schema{(*Schema)(k)}
+	case *Then:
+		return []*Schema{(*Schema)(k)}
+	case *Else:
+		return []*Schema{(*Schema)(k)}
+	case *Not:
+		return []*Schema{(*Schema)(k)}
+	case *Contains:
+		return []*Schema{(*Schema)(k)}
+	case *AdditionalItems:
+		return []*Schema{(*Schema)(k)}
+	case *UnevaluatedItems:
+		return []*Schema{(*Schema)(k)}
+	case *AdditionalProperties:
+		return []*Schema{(*Schema)(k)}
+	case *PropertyNames:
+		return []*Schema{(*Schema)(k)}
+	case *UnevaluatedProperties:
+		return []*Schema{(*Schema)(k)}
+	case *ContentSchema:
+		return []*Schema{(*Schema)(k)}
+	case *AllOf:
+		return *k
+	case *AnyOf:
+		return *k
+	case *OneOf:
+		return *k
+	case *PrefixItems:
+		return *k
+	case *Items:
+		return k.Schemas
+	case *Properties:
+		return schemaMapValues(*k)
+	case *Defs:
+		return schemaMapValues(*k)
+	case *Definitions:
+		return schemaMapValues(*k)
+	case *PatternProperties:
+		res := make([]*Schema, 0, len(*k))
+		for _, ps := range *k {
+			res = append(res, ps.schema)
+		}
+		return res
+	case *DependentSchemas:
+		res := make([]*Schema, 0, len(*k))
+		for _, dep := range *k {
+			res = append(res, dep.schema)
+		}
+		return res
+	case *SchemaDependency:
+		return []*Schema{k.schema}
+	case *Dependencies:
+		var res []*Schema
+		for _, dep := range *k {
+			res = append(res, subschemas(dep)...)
+		}
+		return res
+	}
+	return nil
+}
+
+// schemaMapValues returns the schemas of a keyword keyed by name
+func schemaMapValues(m map[string]*Schema) []*Schema {
+	res := make([]*Schema, 0, len(m))
+	for _, sch := range m {
+		res = append(res, sch)
+	}
+	return res
+}
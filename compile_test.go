@@ -0,0 +1,108 @@
+package jsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	ctx := context.Background()
+
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{
+		"properties": {
+			"a": { "$ref": "#/$defs/a" },
+			"b": { "$ref": "#/$defs/missing" }
+		},
+		"$defs": { "a": { "type": "string" } }
+	}`), rs); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Compile(ctx, rs)
+	if err == nil {
+		t.Fatal("expected an unresolved reference to error")
+	}
+	if !strings.Contains(err.Error(), "#/$defs/missing") {
+		t.Errorf("expected error to list the unresolved reference. got: %s", err)
+	}
+
+	rs = &Schema{}
+	if err := json.Unmarshal([]byte(`{
+		"$id": "http://example.com/compile/tree",
+		"type": "object",
+		"properties": {
+			"value": { "type": "number" },
+			"children": { "type": "array", "items": { "$ref": "#" } }
+		}
+	}`), rs); err != nil {
+		t.Fatal(err)
+	}
+	cs, err := Compile(ctx, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs, err := cs.ValidateBytes(ctx, []byte(`{"value": 1, "children": [{"value": 2}, {"value": "3"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || errs[0].PropertyPath != "/children/1/value" {
+		t.Errorf("expected a single error at /children/1/value. got: %v", errs)
+	}
+}
+
+// TestCompiledSchemaConcurrentValidate validates with compiled schemas from
+// many goroutines at once. Run with -race to check for data races
+func TestCompiledSchemaConcurrentValidate(t *testing.T) {
+	ctx := context.Background()
+	LoadDraft2020_12()
+	defer LoadDraft2019_09()
+
+	type compiledSet struct {
+		schema *CompiledSchema
+		tests  []TestCase
+	}
+	var sets []compiledSet
+	for _, path := range []string{
+		"testdata/draft2020-12/ref.json",
+		"testdata/draft2020-12/anchor.json",
+		"testdata/draft2020-12/dynamicRef.json",
+		"testdata/draft2020-12/unevaluatedItems.json",
+		"testdata/draft2020-12/if-then-else.json",
+	} {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testSets := []*TestSet{}
+		if err := json.Unmarshal(data, &testSets); err != nil {
+			t.Fatalf("error unmarshaling test set %s: %s", path, err)
+		}
+		for _, ts := range testSets {
+			cs, err := Compile(ctx, ts.Schema)
+			if err != nil {
+				t.Fatalf("%s: %s: %s", path, ts.Description, err)
+			}
+			sets = append(sets, compiledSet{schema: cs, tests: ts.Tests})
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, set := range sets {
+				for _, c := range set.tests {
+					if valid := set.schema.Validate(ctx, c.Data).IsValid(); valid != c.Valid {
+						t.Errorf("%s: expected valid to be %t", c.Description, c.Valid)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,284 @@
+package jsonschema
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// DefaultsOptions configures how defaults are filled into an instance
+type DefaultsOptions struct {
+	// CreateIntermediate creates objects missing from the instance when
+	// the schema of the missing property provides defaults for its own
+	// properties. Otherwise defaults are only filled into existing objects
+	CreateIntermediate bool
+}
+
+// ApplyDefaults returns a copy of data with the default values of the
+// schema filled in for missing object properties. Defaults are applied
+// following properties, items, prefixItems, allOf, $ref and the matching
+// branch of if/then/else, leaving out the siblings of $ref in draft 7 and
+// earlier as validation does. Go values such as structs are converted to
+// their JSON form first like Validate does, so the result holds maps and
+// slices. data itself is left untouched
+func (s *Schema) ApplyDefaults(ctx context.Context, data interface{}) (interface{}, error) {
+	return s.ApplyDefaultsWithOptions(ctx, data, DefaultsOptions{})
+}
+
+// ApplyDefaultsWithOptions works like ApplyDefaults, configured by opts
+func (s *Schema) ApplyDefaultsWithOptions(ctx context.Context, data interface{}, opts DefaultsOptions) (interface{}, error) {
+	doc, err := jsonValue(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid instance: %w", err)
+	}
+	d := &defaulter{ctx: ctx, opts: opts, ancestors: map[*Schema]bool{}}
+	return d.apply(newContextValidationState(ctx, s), s, deepCopy(doc), false)
+}
+
+// appliedKeyword returns the keyword of sch if it applies to instances,
+// which the siblings of $ref don't in draft 7 and earlier
+func appliedKeyword(sch *Schema, keyword string) Keyword {
+	for _, key := range sch.orderedkeywords {
+		if key == keyword {
+			return sch.keywords[keyword]
+		}
+	}
+	return nil
+}
+
+// defaulter walks a schema alongside an instance, filling in defaults
+type defaulter struct {
+	ctx  context.Context
+	opts DefaultsOptions
+	// ancestors holds the schemas currently being applied
+	ancestors map[*Schema]bool
+}
+
+// apply fills the defaults of sch into value, returning the result.
+// creating marks values created for missing properties, which recursive
+// schemas must not expand any further
+func (d *defaulter) apply(currentState *ValidationState, sch *Schema, value interface{}, creating bool) (interface{}, error) {
+	if sch == nil || sch.schemaType != schemaTypeObject {
+		return value, nil
+	}
+	if d.ancestors[sch] {
+		if creating {
+			return value, nil
+		}
+	} else {
+		d.ancestors[sch] = true
+		defer delete(d.ancestors, sch)
+	}
+	sch.enterScope(currentState)
+
+	var err error
+	if ref, ok := appliedKeyword(sch, "$ref").(*Ref); ok {
+		target, subState, err := d.resolveRef(currentState, ref)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = d.apply(subState, target, value, creating); err != nil {
+			return nil, err
+		}
+	}
+
+	if allOf, ok := appliedKeyword(sch, "allOf").(*AllOf); ok {
+		for _, sub := range *allOf {
+			if value, err = d.apply(currentState.NewSubState(), sub, value, creating); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if props, ok := appliedKeyword(sch, "properties").(*Properties); ok {
+			if err := d.applyProperties(currentState, *props, v, creating); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		if err := d.applyItems(currentState, sch, v, creating); err != nil {
+			return nil, err
+		}
+	}
+
+	if f, ok := appliedKeyword(sch, "if").(*If); ok {
+		subState := currentState.NewSubState()
+		subState.Errs = &[]KeyError{}
+		(*Schema)(f).ValidateKeyword(d.ctx, subState, value)
+
+		branch := "else"
+		if subState.IsValid() {
+			branch = "then"
+		}
+		var target *Schema
+		switch kw := appliedKeyword(sch, branch).(type) {
+		case *Then:
+			target = (*Schema)(kw)
+		case *Else:
+			target = (*Schema)(kw)
+		}
+		if value, err = d.apply(currentState.NewSubState(), target, value, creating); err != nil {
+			return nil, err
+		}
+	}
+
+	return value, nil
+}
+
+// applyProperties fills the defaults of property schemas into obj
+func (d *defaulter) applyProperties(currentState *ValidationState, props Properties, obj map[string]interface{}, creating bool) error {
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		prop := props[key]
+		subState := currentState.NewSubState()
+		subState.DescendInstanceFromState(currentState, key)
+
+		if v, ok := obj[key]; ok {
+			res, err := d.apply(subState, prop, v, creating)
+			if err != nil {
+				return err
+			}
+			obj[key] = res
+			continue
+		}
+
+		def, ok, err := d.defaultValue(currentState.NewSubState(), prop)
+		if err != nil {
+			return err
+		}
+		if ok {
+			res, err := d.apply(subState, prop, deepCopy(def), creating)
+			if err != nil {
+				return err
+			}
+			obj[key] = res
+			continue
+		}
+
+		if d.opts.CreateIntermediate {
+			res, err := d.apply(subState, prop, map[string]interface{}{}, true)
+			if err != nil {
+				return err
+			}
+			if m, ok := res.(map[string]interface{}); ok && len(m) > 0 {
+				obj[key] = m
+			}
+		}
+	}
+	return nil
+}
+
+// applyItems fills defaults into the elements of arr
+func (d *defaulter) applyItems(currentState *ValidationState, sch *Schema, arr []interface{}, creating bool) error {
+	var positional []*Schema
+	if prefixItems, ok := appliedKeyword(sch, "prefixItems").(*PrefixItems); ok {
+		positional = *prefixItems
+	}
+
+	var rest *Schema
+	if items, ok := appliedKeyword(sch, "items").(*Items); ok {
+		if items.single {
+			if len(items.Schemas) > 0 {
+				rest = items.Schemas[0]
+			}
+		} else {
+			positional = items.Schemas
+		}
+	}
+
+	for i, elem := range arr {
+		var target *Schema
+		if i < len(positional) {
+			target = positional[i]
+		} else if rest != nil {
+			target = rest
+		} else {
+			continue
+		}
+
+		subState := currentState.NewSubState()
+		subState.DescendInstanceFromState(currentState, strconv.Itoa(i))
+		res, err := d.apply(subState, target, elem, creating)
+		if err != nil {
+			return err
+		}
+		arr[i] = res
+	}
+	return nil
+}
+
+// defaultValue finds the default of a schema, looking through $ref
+// and allOf if the schema doesn't declare one itself
+func (d *defaulter) defaultValue(currentState *ValidationState, sch *Schema) (interface{}, bool, error) {
+	if sch == nil || sch.schemaType != schemaTypeObject {
+		return nil, false, nil
+	}
+	sch.enterScope(currentState)
+
+	if def, ok := appliedKeyword(sch, "default").(*Default); ok {
+		return def.data, true, nil
+	}
+
+	if ref, ok := appliedKeyword(sch, "$ref").(*Ref); ok {
+		target, subState, err := d.resolveRef(currentState, ref)
+		if err != nil {
+			return nil, false, err
+		}
+		if def, ok, err := d.defaultValue(subState, target); ok || err != nil {
+			return def, ok, err
+		}
+	}
+
+	if allOf, ok := appliedKeyword(sch, "allOf").(*AllOf); ok {
+		for _, sub := range *allOf {
+			if def, ok, err := d.defaultValue(currentState.NewSubState(), sub); ok || err != nil {
+				return def, ok, err
+			}
+		}
+	}
+	return nil, false, nil
+}
+
+// resolveRef resolves a reference the way validation does, returning
+// the referenced schema and a state scoped to its schema resource
+func (d *defaulter) resolveRef(currentState *ValidationState, ref *Ref) (*Schema, *ValidationState, error) {
+	res := ref.resolve(d.ctx, currentState)
+	if res.resolved == nil {
+		return nil, nil, fmt.Errorf("failed to resolve schema for ref %s", ref.reference)
+	}
+
+	subState := currentState.NewSubState()
+	if res.resolvedRoot != nil {
+		subState.BaseURI = res.resolvedRoot.docPath
+		subState.Root = res.resolvedRoot
+	}
+	return res.resolved, subState, nil
+}
+
+// deepCopy copies decoded JSON values so modifications of the copy
+// don't affect the original
+func deepCopy(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(t))
+		for key, val := range t {
+			res[key] = deepCopy(val)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(t))
+		for i, val := range t {
+			res[i] = deepCopy(val)
+		}
+		return res
+	}
+	return v
+}
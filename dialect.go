@@ -0,0 +1,128 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Meta-schema URIs identifying the dialects supported by the "$schema" keyword
+const (
+	DialectDraft4       = "http://json-schema.org/draft-04/schema#"
+	DialectDraft6       = "http://json-schema.org/draft-06/schema#"
+	DialectDraft7       = "http://json-schema.org/draft-07/schema#"
+	DialectDraft2019_09 = "https://json-schema.org/draft/2019-09/schema"
+	DialectDraft2020_12 = "https://json-schema.org/draft/2020-12/schema"
+)
+
+// dialects maps normalized meta-schema URIs to the loader
+// of the matching draft keyword set
+var dialects = map[string]func(r *KeywordRegistry){
+	normalizeDialectURI(DialectDraft4):       (*KeywordRegistry).LoadDraft4,
+	normalizeDialectURI(DialectDraft6):       (*KeywordRegistry).LoadDraft6,
+	normalizeDialectURI(DialectDraft7):       (*KeywordRegistry).LoadDraft7,
+	normalizeDialectURI(DialectDraft2019_09): (*KeywordRegistry).LoadDraft2019_09,
+	normalizeDialectURI(DialectDraft2020_12): (*KeywordRegistry).LoadDraft2020_12,
+}
+
+// normalizeDialectURI strips the scheme and empty fragment of a meta-schema
+// URI as both are used interchangeably by schemas in the wild
+func normalizeDialectURI(uri string) string {
+	uri = strings.TrimSpace(uri)
+	uri = strings.TrimSuffix(uri, "#")
+	uri = strings.TrimPrefix(uri, "http://")
+	uri = strings.TrimPrefix(uri, "https://")
+	return uri
+}
+
+// IsKnownDialect checks if the given meta-schema URI identifies
+// one of the supported drafts
+func IsKnownDialect(uri string) bool {
+	_, ok := dialects[normalizeDialectURI(uri)]
+	return ok
+}
+
+// LoadDialect loads the keywords of the draft identified by the given
+// meta-schema URI
+// schemas omitting "$schema" are parsed with the keywords of the global
+// registry, making this the way to configure the fallback dialect
+func LoadDialect(uri string) error {
+	r, release := getGlobalKeywordRegistry()
+	defer release()
+
+	return r.LoadDialect(uri)
+}
+
+// LoadDialect loads the keywords of the draft identified by the given
+// meta-schema URI
+// keywords of any previously loaded draft are replaced while
+// custom keywords are left in place
+func (r *KeywordRegistry) LoadDialect(uri string) error {
+	load, ok := dialects[normalizeDialectURI(uri)]
+	if !ok {
+		return fmt.Errorf("unknown dialect %q", uri)
+	}
+	load(r)
+	return nil
+}
+
+// registryUnmarshaler is implemented by keywords holding schemas or
+// patterns. Schemas decode their keywords with the keyword registry of
+// their dialect, which embedded schemas inherit unless they declare a
+// "$schema" of their own
+type registryUnmarshaler interface {
+	unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error
+}
+
+// unmarshalKeyword decodes a keyword with the keyword registry of the
+// schema holding it
+func unmarshalKeyword(data []byte, keyword Keyword, registry *KeywordRegistry) error {
+	if ru, ok := keyword.(registryUnmarshaler); ok {
+		return ru.unmarshalJSONWithRegistry(data, registry)
+	}
+	return json.Unmarshal(data, keyword)
+}
+
+// unmarshalSchema decodes an embedded schema with the keyword registry of
+// its parent, a nil registry decoding a top level schema
+func unmarshalSchema(data []byte, registry *KeywordRegistry) (*Schema, error) {
+	sch := &Schema{}
+	if err := sch.unmarshalJSONWithRegistry(data, registry); err != nil {
+		return nil, err
+	}
+	return sch, nil
+}
+
+// unmarshalSchemaList decodes an array of embedded schemas
+func unmarshalSchemaList(data []byte, registry *KeywordRegistry) ([]*Schema, error) {
+	raws := []json.RawMessage{}
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return nil, err
+	}
+	schemas := make([]*Schema, len(raws))
+	for i, raw := range raws {
+		sch, err := unmarshalSchema(raw, registry)
+		if err != nil {
+			return nil, err
+		}
+		schemas[i] = sch
+	}
+	return schemas, nil
+}
+
+// unmarshalSchemaMap decodes an object of embedded schemas
+func unmarshalSchemaMap(data []byte, registry *KeywordRegistry) (map[string]*Schema, error) {
+	raws := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return nil, err
+	}
+	schemas := make(map[string]*Schema, len(raws))
+	for key, raw := range raws {
+		sch, err := unmarshalSchema(raw, registry)
+		if err != nil {
+			return nil, err
+		}
+		schemas[key] = sch
+	}
+	return schemas, nil
+}
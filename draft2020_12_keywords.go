@@ -0,0 +1,114 @@
+package jsonschema
+
+// LoadDraft2020_12 loads the keywords for schema validation
+// based on draft2020_12
+func LoadDraft2020_12() {
+	r, release := getGlobalKeywordRegistry()
+	defer release()
+
+	r.LoadDraft2020_12()
+}
+
+// LoadDraft2020_12 loads the keywords for schema validation
+// based on draft2020_12
+// keywords of any previously loaded draft are replaced while
+// custom keywords are left in place
+func (r *KeywordRegistry) LoadDraft2020_12() {
+	r.loadDraft(DialectDraft2020_12, func() {
+		// core keywords
+		r.RegisterKeyword("$schema", NewSchemaURI)
+		r.RegisterKeyword("$id", NewID)
+		r.RegisterKeyword("$vocabulary", NewVocabularies)
+		r.RegisterKeyword("description", NewDescription)
+		r.RegisterKeyword("title", NewTitle)
+		r.RegisterKeyword("$comment", NewComment)
+		r.RegisterKeyword("examples", NewExamples)
+		r.RegisterKeyword("readOnly", NewReadOnly)
+		r.RegisterKeyword("writeOnly", NewWriteOnly)
+		r.RegisterKeyword("$ref", NewRef)
+		r.RegisterKeyword("$dynamicRef", NewDynamicRef)
+		r.RegisterKeyword("$anchor", NewAnchor)
+		r.RegisterKeyword("$dynamicAnchor", NewDynamicAnchor)
+		r.RegisterKeyword("$defs", NewDefs)
+		r.RegisterKeyword("default", NewDefault)
+
+		r.SetKeywordOrder("$ref", 0)
+		r.SetKeywordOrder("$dynamicRef", 0)
+
+		// standard keywords
+		r.RegisterKeyword("type", NewType)
+		r.RegisterKeyword("enum", NewEnum)
+		r.RegisterKeyword("const", NewConst)
+
+		// numeric keywords
+		r.RegisterKeyword("multipleOf", NewMultipleOf)
+		r.RegisterKeyword("maximum", NewMaximum)
+		r.RegisterKeyword("exclusiveMaximum", NewExclusiveMaximum)
+		r.RegisterKeyword("minimum", NewMinimum)
+		r.RegisterKeyword("exclusiveMinimum", NewExclusiveMinimum)
+
+		// string keywords
+		r.RegisterKeyword("maxLength", NewMaxLength)
+		r.RegisterKeyword("minLength", NewMinLength)
+		r.RegisterKeyword("pattern", NewPattern)
+
+		// boolean keywords
+		r.RegisterKeyword("allOf", NewAllOf)
+		r.RegisterKeyword("anyOf", NewAnyOf)
+		r.RegisterKeyword("oneOf", NewOneOf)
+		r.RegisterKeyword("not", NewNot)
+
+		// object keywords
+		r.RegisterKeyword("properties", NewProperties)
+		r.RegisterKeyword("patternProperties", NewPatternProperties)
+		r.RegisterKeyword("additionalProperties", NewAdditionalProperties)
+		r.RegisterKeyword("required", NewRequired)
+		r.RegisterKeyword("propertyNames", NewPropertyNames)
+		r.RegisterKeyword("maxProperties", NewMaxProperties)
+		r.RegisterKeyword("minProperties", NewMinProperties)
+		r.RegisterKeyword("dependentSchemas", NewDependentSchemas)
+		r.RegisterKeyword("dependentRequired", NewDependentRequired)
+		r.RegisterKeyword("unevaluatedProperties", NewUnevaluatedProperties)
+
+		r.SetKeywordOrder("properties", 2)
+		r.SetKeywordOrder("additionalProperties", 3)
+		r.SetKeywordOrder("unevaluatedProperties", 4)
+
+		// array keywords
+		// items takes the place of additionalItems and only
+		// applies to the elements following prefixItems
+		r.RegisterKeyword("prefixItems", NewPrefixItems)
+		r.RegisterKeyword("items", NewItems)
+		r.RegisterKeyword("maxItems", NewMaxItems)
+		r.RegisterKeyword("minItems", NewMinItems)
+		r.RegisterKeyword("uniqueItems", NewUniqueItems)
+		r.RegisterKeyword("contains", NewContains)
+		r.RegisterKeyword("maxContains", NewMaxContains)
+		r.RegisterKeyword("minContains", NewMinContains)
+		r.RegisterKeyword("unevaluatedItems", NewUnevaluatedItems)
+
+		r.SetKeywordOrder("maxContains", 2)
+		r.SetKeywordOrder("minContains", 2)
+		r.SetKeywordOrder("items", 3)
+		r.SetKeywordOrder("unevaluatedItems", 4)
+
+		// conditional keywords
+		r.RegisterKeyword("if", NewIf)
+		r.RegisterKeyword("then", NewThen)
+		r.RegisterKeyword("else", NewElse)
+
+		r.SetKeywordOrder("then", 2)
+		r.SetKeywordOrder("else", 2)
+
+		// formats are annotations unless asserted with WithFormatMode
+		r.RegisterKeyword("format", NewAnnotatedFormat)
+
+		// content keywords
+		r.RegisterKeyword("contentEncoding", NewContentEncoding)
+		r.RegisterKeyword("contentMediaType", NewContentMediaType)
+		r.RegisterKeyword("contentSchema", NewContentSchema)
+
+		r.SetKeywordOrder("contentMediaType", 2)
+		r.SetKeywordOrder("contentSchema", 3)
+	})
+}
@@ -0,0 +1,79 @@
+package jsonschema
+
+// LoadDraft4 loads the keywords for schema validation
+// based on draft4
+func LoadDraft4() {
+	r, release := getGlobalKeywordRegistry()
+	defer release()
+
+	r.LoadDraft4()
+}
+
+// LoadDraft4 loads the keywords for schema validation
+// based on draft4
+// keywords of any previously loaded draft are replaced while
+// custom keywords are left in place
+func (r *KeywordRegistry) LoadDraft4() {
+	r.loadDraft(DialectDraft4, func() {
+		// core keywords
+		r.RegisterKeyword("$schema", NewSchemaURI)
+		r.RegisterKeyword("id", NewID)
+		r.RegisterKeyword("description", NewDescription)
+		r.RegisterKeyword("title", NewTitle)
+		r.RegisterKeyword("$ref", NewRef)
+		r.RegisterKeyword("definitions", NewDefinitions)
+		r.RegisterKeyword("default", NewDefault)
+
+		r.SetKeywordOrder("$ref", 0)
+
+		// in draft4 the base URI is set by "id" rather than "$id"
+		r.idKeyword = "id"
+		r.refOverridesSiblings = true
+
+		// standard keywords
+		r.RegisterKeyword("type", NewType)
+		r.RegisterKeyword("enum", NewEnum)
+
+		// numeric keywords
+		r.RegisterKeyword("multipleOf", NewMultipleOf)
+		r.RegisterKeyword("maximum", NewMaximum)
+		r.RegisterKeyword("exclusiveMaximum", NewDraft4ExclusiveMaximum)
+		r.RegisterKeyword("minimum", NewMinimum)
+		r.RegisterKeyword("exclusiveMinimum", NewDraft4ExclusiveMinimum)
+
+		// string keywords
+		r.RegisterKeyword("maxLength", NewMaxLength)
+		r.RegisterKeyword("minLength", NewMinLength)
+		r.RegisterKeyword("pattern", NewPattern)
+
+		// boolean keywords
+		r.RegisterKeyword("allOf", NewAllOf)
+		r.RegisterKeyword("anyOf", NewAnyOf)
+		r.RegisterKeyword("oneOf", NewOneOf)
+		r.RegisterKeyword("not", NewNot)
+
+		// object keywords
+		r.RegisterKeyword("properties", NewProperties)
+		r.RegisterKeyword("patternProperties", NewPatternProperties)
+		r.RegisterKeyword("additionalProperties", NewAdditionalProperties)
+		r.RegisterKeyword("required", NewRequired)
+		r.RegisterKeyword("maxProperties", NewMaxProperties)
+		r.RegisterKeyword("minProperties", NewMinProperties)
+		r.RegisterKeyword("dependencies", NewDependencies)
+
+		r.SetKeywordOrder("properties", 2)
+		r.SetKeywordOrder("additionalProperties", 3)
+
+		// array keywords
+		r.RegisterKeyword("items", NewItems)
+		r.RegisterKeyword("additionalItems", NewAdditionalItems)
+		r.RegisterKeyword("maxItems", NewMaxItems)
+		r.RegisterKeyword("minItems", NewMinItems)
+		r.RegisterKeyword("uniqueItems", NewUniqueItems)
+
+		r.SetKeywordOrder("additionalItems", 3)
+
+		//optional formats
+		r.RegisterKeyword("format", NewFormat)
+	})
+}
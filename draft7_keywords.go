@@ -0,0 +1,99 @@
+package jsonschema
+
+// LoadDraft7 loads the keywords for schema validation
+// based on draft7
+func LoadDraft7() {
+	r, release := getGlobalKeywordRegistry()
+	defer release()
+
+	r.LoadDraft7()
+}
+
+// LoadDraft7 loads the keywords for schema validation
+// based on draft7
+// keywords of any previously loaded draft are replaced while
+// custom keywords are left in place
+func (r *KeywordRegistry) LoadDraft7() {
+	r.loadDraft(DialectDraft7, func() {
+		// core keywords
+		r.RegisterKeyword("$schema", NewSchemaURI)
+		r.RegisterKeyword("$id", NewID)
+		r.RegisterKeyword("description", NewDescription)
+		r.RegisterKeyword("title", NewTitle)
+		r.RegisterKeyword("$comment", NewComment)
+		r.RegisterKeyword("examples", NewExamples)
+		r.RegisterKeyword("readOnly", NewReadOnly)
+		r.RegisterKeyword("writeOnly", NewWriteOnly)
+		r.RegisterKeyword("$ref", NewRef)
+		r.RegisterKeyword("definitions", NewDefinitions)
+		r.RegisterKeyword("default", NewDefault)
+
+		r.SetKeywordOrder("$ref", 0)
+
+		// in draft7 and earlier all sibling keywords of $ref are ignored
+		r.refOverridesSiblings = true
+
+		// standard keywords
+		r.RegisterKeyword("type", NewType)
+		r.RegisterKeyword("enum", NewEnum)
+		r.RegisterKeyword("const", NewConst)
+
+		// numeric keywords
+		r.RegisterKeyword("multipleOf", NewMultipleOf)
+		r.RegisterKeyword("maximum", NewMaximum)
+		r.RegisterKeyword("exclusiveMaximum", NewExclusiveMaximum)
+		r.RegisterKeyword("minimum", NewMinimum)
+		r.RegisterKeyword("exclusiveMinimum", NewExclusiveMinimum)
+
+		// string keywords
+		r.RegisterKeyword("maxLength", NewMaxLength)
+		r.RegisterKeyword("minLength", NewMinLength)
+		r.RegisterKeyword("pattern", NewPattern)
+
+		// boolean keywords
+		r.RegisterKeyword("allOf", NewAllOf)
+		r.RegisterKeyword("anyOf", NewAnyOf)
+		r.RegisterKeyword("oneOf", NewOneOf)
+		r.RegisterKeyword("not", NewNot)
+
+		// object keywords
+		r.RegisterKeyword("properties", NewProperties)
+		r.RegisterKeyword("patternProperties", NewPatternProperties)
+		r.RegisterKeyword("additionalProperties", NewAdditionalProperties)
+		r.RegisterKeyword("required", NewRequired)
+		r.RegisterKeyword("propertyNames", NewPropertyNames)
+		r.RegisterKeyword("maxProperties", NewMaxProperties)
+		r.RegisterKeyword("minProperties", NewMinProperties)
+		r.RegisterKeyword("dependencies", NewDependencies)
+
+		r.SetKeywordOrder("properties", 2)
+		r.SetKeywordOrder("additionalProperties", 3)
+
+		// array keywords
+		r.RegisterKeyword("items", NewItems)
+		r.RegisterKeyword("additionalItems", NewAdditionalItems)
+		r.RegisterKeyword("maxItems", NewMaxItems)
+		r.RegisterKeyword("minItems", NewMinItems)
+		r.RegisterKeyword("uniqueItems", NewUniqueItems)
+		r.RegisterKeyword("contains", NewContains)
+
+		r.SetKeywordOrder("additionalItems", 3)
+
+		// conditional keywords
+		r.RegisterKeyword("if", NewIf)
+		r.RegisterKeyword("then", NewThen)
+		r.RegisterKeyword("else", NewElse)
+
+		r.SetKeywordOrder("then", 2)
+		r.SetKeywordOrder("else", 2)
+
+		//optional formats
+		r.RegisterKeyword("format", NewFormat)
+
+		// content keywords
+		r.RegisterKeyword("contentEncoding", NewContentEncoding)
+		r.RegisterKeyword("contentMediaType", NewContentMediaType)
+
+		r.SetKeywordOrder("contentMediaType", 2)
+	})
+}
@@ -1,6 +1,6 @@
 module github.com/qri-io/jsonschema
 
-go 1.13
+go 1.16
 
 require (
 	github.com/qri-io/jsonpointer v0.1.1
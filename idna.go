@@ -0,0 +1,426 @@
+package jsonschema
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// punycode parameters as defined by RFC 3492, section 5
+// https://tools.ietf.org/html/rfc3492#section-5
+const (
+	punycodeBase        int32 = 36
+	punycodeTMin        int32 = 1
+	punycodeTMax        int32 = 26
+	punycodeSkew        int32 = 38
+	punycodeDamp        int32 = 700
+	punycodeInitialBias int32 = 72
+	punycodeInitialN    int32 = 128
+
+	// acePrefix marks labels encoded with punycode
+	acePrefix = "xn--"
+)
+
+// punycodeAdapt is the bias adaptation function of RFC 3492, section 6.1
+func punycodeAdapt(delta, numPoints int32, firstTime bool) int32 {
+	if firstTime {
+		delta /= punycodeDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+	k := int32(0)
+	for delta > ((punycodeBase-punycodeTMin)*punycodeTMax)/2 {
+		delta /= punycodeBase - punycodeTMin
+		k += punycodeBase
+	}
+	return k + (punycodeBase-punycodeTMin+1)*delta/(delta+punycodeSkew)
+}
+
+// punycodeThreshold returns the threshold t for the digit position k
+func punycodeThreshold(k, bias int32) int32 {
+	switch {
+	case k <= bias:
+		return punycodeTMin
+	case k >= bias+punycodeTMax:
+		return punycodeTMax
+	}
+	return k - bias
+}
+
+// punycodeDigit encodes a digit value as a lowercase basic code point
+func punycodeDigit(d int32) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
+
+// punycodeDigitValue decodes a basic code point into its digit value
+func punycodeDigitValue(c byte) (int32, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int32(c-'0') + 26, true
+	case c >= 'a' && c <= 'z':
+		return int32(c - 'a'), true
+	case c >= 'A' && c <= 'Z':
+		return int32(c - 'A'), true
+	}
+	return 0, false
+}
+
+// punycodeEncode encodes a unicode label following RFC 3492, section 6.3,
+// without the ACE prefix
+func punycodeEncode(label string) (string, error) {
+	runes := []rune(label)
+	var out []byte
+	for _, r := range runes {
+		if r < 0x80 {
+			out = append(out, byte(r))
+		}
+	}
+	basic := int32(len(out))
+	handled := basic
+	if basic > 0 {
+		out = append(out, '-')
+	}
+
+	n, delta, bias := punycodeInitialN, int32(0), punycodeInitialBias
+	for handled < int32(len(runes)) {
+		m := int32(unicode.MaxRune + 1)
+		for _, r := range runes {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+		if (m - n) > (1<<31-1-delta)/(handled+1) {
+			return "", fmt.Errorf("punycode overflow")
+		}
+		delta += (m - n) * (handled + 1)
+		n = m
+		for _, r := range runes {
+			if r < n {
+				delta++
+				if delta < 0 {
+					return "", fmt.Errorf("punycode overflow")
+				}
+			}
+			if r != n {
+				continue
+			}
+			q := delta
+			for k := punycodeBase; ; k += punycodeBase {
+				t := punycodeThreshold(k, bias)
+				if q < t {
+					break
+				}
+				out = append(out, punycodeDigit(t+(q-t)%(punycodeBase-t)))
+				q = (q - t) / (punycodeBase - t)
+			}
+			out = append(out, punycodeDigit(q))
+			bias = punycodeAdapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return string(out), nil
+}
+
+// punycodeDecode decodes a punycode label without the ACE prefix
+// following RFC 3492, section 6.2
+func punycodeDecode(encoded string) (string, error) {
+	var output []rune
+	pos := 0
+	if i := strings.LastIndexByte(encoded, '-'); i >= 0 {
+		for j := 0; j < i; j++ {
+			if encoded[j] >= 0x80 {
+				return "", fmt.Errorf("punycode contains non-basic code point")
+			}
+			output = append(output, rune(encoded[j]))
+		}
+		pos = i + 1
+	}
+
+	n, i, bias := punycodeInitialN, int32(0), punycodeInitialBias
+	for pos < len(encoded) {
+		oldi, w := i, int32(1)
+		for k := punycodeBase; ; k += punycodeBase {
+			if pos >= len(encoded) {
+				return "", fmt.Errorf("punycode ends unexpectedly")
+			}
+			digit, ok := punycodeDigitValue(encoded[pos])
+			if !ok {
+				return "", fmt.Errorf("punycode contains invalid digit %q", encoded[pos])
+			}
+			pos++
+			if digit > (1<<31-1-i)/w {
+				return "", fmt.Errorf("punycode overflow")
+			}
+			i += digit * w
+			t := punycodeThreshold(k, bias)
+			if digit < t {
+				break
+			}
+			if w > (1<<31-1)/(punycodeBase-t) {
+				return "", fmt.Errorf("punycode overflow")
+			}
+			w *= punycodeBase - t
+		}
+		length := int32(len(output) + 1)
+		bias = punycodeAdapt(i-oldi, length, oldi == 0)
+		if i/length > unicode.MaxRune-n {
+			return "", fmt.Errorf("punycode overflow")
+		}
+		n += i / length
+		i %= length
+		if n < punycodeInitialN || !utf8.ValidRune(n) {
+			return "", fmt.Errorf("punycode decodes to invalid code point %U", n)
+		}
+		output = append(output, 0)
+		copy(output[i+1:], output[i:])
+		output[i] = n
+		i++
+	}
+	return string(output), nil
+}
+
+// isIDNLabelSeparator reports whether r separates the labels of an
+// internationalized domain name, see RFC 3490, section 3.1
+// https://tools.ietf.org/html/rfc3490#section-3.1
+func isIDNLabelSeparator(r rune) bool {
+	return r == '.' || r == '\u3002' || r == '\uff0e' || r == '\uff61'
+}
+
+// idnaToASCII converts an internationalized domain name to its ASCII
+// form, validating every label against the IDNA2008 rules of RFC 5891
+// and the contextual rules of RFC 5892, appendix A
+// https://tools.ietf.org/html/rfc5891#section-4
+// https://tools.ietf.org/html/rfc5892#appendix-A
+func idnaToASCII(name string) (string, error) {
+	labels := splitIDNLabels(name)
+	ascii := make([]string, len(labels))
+	for i, label := range labels {
+		alabel, err := idnaLabelToASCII(label)
+		if err != nil {
+			return "", err
+		}
+		ascii[i] = alabel
+	}
+	res := strings.Join(ascii, ".")
+	if len(res) > 253 {
+		return "", fmt.Errorf("name exceeds 253 characters")
+	}
+	return res, nil
+}
+
+// splitIDNLabels splits a domain name into its labels
+func splitIDNLabels(name string) []string {
+	var labels []string
+	start := 0
+	for i, r := range name {
+		if isIDNLabelSeparator(r) {
+			labels = append(labels, name[start:i])
+			start = i + utf8.RuneLen(r)
+		}
+	}
+	return append(labels, name[start:])
+}
+
+// idnaLabelToASCII validates a single label returning its A-label
+func idnaLabelToASCII(label string) (string, error) {
+	alabel := label
+	if isASCII(label) {
+		if !strings.HasPrefix(strings.ToLower(label), acePrefix) {
+			if err := checkLDHLabel(label); err != nil {
+				return "", err
+			}
+			return label, nil
+		}
+		ulabel, err := punycodeDecode(label[len(acePrefix):])
+		if err != nil {
+			return "", fmt.Errorf("invalid label %q: %s", label, err.Error())
+		}
+		if encoded, err := punycodeEncode(ulabel); err != nil || isASCII(ulabel) || !strings.EqualFold(encoded, label[len(acePrefix):]) {
+			return "", fmt.Errorf("invalid label %q: not a valid A-label", label)
+		}
+		if err := checkULabel(ulabel); err != nil {
+			return "", err
+		}
+	} else {
+		if err := checkULabel(label); err != nil {
+			return "", err
+		}
+		encoded, err := punycodeEncode(label)
+		if err != nil {
+			return "", err
+		}
+		alabel = acePrefix + encoded
+	}
+
+	if len(alabel) > 63 {
+		return "", fmt.Errorf("label %q exceeds 63 characters", label)
+	}
+	return alabel, nil
+}
+
+// checkLDHLabel validates an ASCII label of letters, digits and hyphens
+// as defined by RFC 1034, section 3.1 and RFC 5891, section 4.2.3.1
+func checkLDHLabel(label string) error {
+	if len(label) == 0 || len(label) > 63 {
+		return fmt.Errorf("label %q must have 1 to 63 characters", label)
+	}
+	for i := 0; i < len(label); i++ {
+		c := label[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return fmt.Errorf("label %q contains illegal character %q", label, c)
+		}
+	}
+	return checkLabelHyphens(label)
+}
+
+// checkLabelHyphens rejects labels starting or ending with a hyphen and
+// labels with hyphens in the third and fourth position
+func checkLabelHyphens(label string) error {
+	if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+		return fmt.Errorf("label %q must not start or end with a hyphen", label)
+	}
+	if len(label) >= 4 && label[2:4] == "--" {
+		return fmt.Errorf("label %q must not contain hyphens in the third and fourth position", label)
+	}
+	return nil
+}
+
+// checkULabel validates a unicode label following RFC 5891, section 4.2.3
+func checkULabel(label string) error {
+	if label == "" {
+		return fmt.Errorf("empty label")
+	}
+	if err := checkLabelHyphens(label); err != nil {
+		return err
+	}
+
+	runes := []rune(label)
+	if unicode.Is(unicode.M, runes[0]) {
+		return fmt.Errorf("label %q must not start with a combining mark", label)
+	}
+	for i, r := range runes {
+		if disallowedIdnChars[string(r)] {
+			return fmt.Errorf("contains illegal character %#U", r)
+		}
+		if err := checkContextualRune(runes, i); err != nil {
+			return err
+		}
+	}
+	return checkArabicIndicDigits(runes)
+}
+
+// checkContextualRune validates the code point at position i of a label.
+// Letters, marks, digits and hyphens are allowed while the few code points
+// of other categories permitted by IDNA2008 need a specific context
+func checkContextualRune(runes []rune, i int) error {
+	r := runes[i]
+	switch r {
+	case '\u00df', '\u03c2', '\u06fd', '\u06fe', '\u0f0b', '\u3007':
+		// exceptions of RFC 5892, section 2.6 which are PVALID
+		return nil
+	case '\u200c':
+		// ZERO WIDTH NON-JOINER must follow a virama or sit between
+		// joining characters, see isJoining
+		if i > 0 && unicode.Is(unicode.Mn, runes[i-1]) {
+			return nil
+		}
+		if !isJoining(runes[:i], true) || !isJoining(runes[i+1:], false) {
+			return fmt.Errorf("%#U must follow a virama or join two characters", r)
+		}
+		return nil
+	case '\u200d':
+		// ZERO WIDTH JOINER must follow a virama, approximated
+		// by any nonspacing mark as the combining class isn't available
+		if i == 0 || !unicode.Is(unicode.Mn, runes[i-1]) {
+			return fmt.Errorf("%#U must follow a virama", r)
+		}
+		return nil
+	case '\u00b7':
+		// MIDDLE DOT is only allowed between two 'l'
+		if i == 0 || i == len(runes)-1 || runes[i-1] != 'l' || runes[i+1] != 'l' {
+			return fmt.Errorf("%#U must appear between two 'l'", r)
+		}
+		return nil
+	case '\u0375':
+		// GREEK LOWER NUMERAL SIGN (KERAIA) must precede a Greek character
+		if i == len(runes)-1 || !unicode.Is(unicode.Greek, runes[i+1]) {
+			return fmt.Errorf("%#U must be followed by a Greek character", r)
+		}
+		return nil
+	case '\u05f3', '\u05f4':
+		// HEBREW PUNCTUATION GERESH and GERSHAYIM must follow a Hebrew character
+		if i == 0 || !unicode.Is(unicode.Hebrew, runes[i-1]) {
+			return fmt.Errorf("%#U must follow a Hebrew character", r)
+		}
+		return nil
+	case '\u30fb':
+		// KATAKANA MIDDLE DOT needs a Hiragana, Katakana or Han character in the label
+		for _, o := range runes {
+			if o != r && unicode.In(o, unicode.Hiragana, unicode.Katakana, unicode.Han) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%#U needs a Hiragana, Katakana or Han character in the label", r)
+	case '-':
+		return nil
+	}
+
+	if unicode.IsLetter(r) || unicode.IsMark(r) || unicode.Is(unicode.Nd, r) {
+		return nil
+	}
+	return fmt.Errorf("contains illegal character %#U", r)
+}
+
+// isJoining reports whether the runes before or after a ZERO WIDTH
+// NON-JOINER end or start with a joining character, skipping transparent
+// nonspacing marks as RFC 5892, appendix A.1 does. The joining types
+// aren't available and are approximated by the letters of cursive scripts
+func isJoining(runes []rune, before bool) bool {
+	for j := range runes {
+		r := runes[j]
+		if before {
+			r = runes[len(runes)-1-j]
+		}
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		return unicode.IsLetter(r) && unicode.In(r, unicode.Arabic, unicode.Syriac, unicode.Nko, unicode.Mongolian)
+	}
+	return false
+}
+
+// checkArabicIndicDigits rejects labels mixing ARABIC-INDIC DIGITS with
+// EXTENDED ARABIC-INDIC DIGITS
+func checkArabicIndicDigits(runes []rune) error {
+	var arabicIndic, extended bool
+	for _, r := range runes {
+		if r >= '\u0660' && r <= '\u0669' {
+			arabicIndic = true
+		}
+		if r >= '\u06f0' && r <= '\u06f9' {
+			extended = true
+		}
+	}
+	if arabicIndic && extended {
+		return fmt.Errorf("must not mix Arabic-Indic and extended Arabic-Indic digits")
+	}
+	return nil
+}
+
+// isASCII reports whether s only holds ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package jsonschema
+
+import (
+	"testing"
+)
+
+func TestPunycode(t *testing.T) {
+	// samples from RFC 3492, section 7.1
+	cases := []struct {
+		unicode, encoded string
+	}{
+		{"ليهمابتكلموشعربي؟", "egbpdaj6bu4bxfgehfvwxn"},
+		{"他们为什么不说中文", "ihqwcrb4cv8a8dqg056pqjye"},
+		{"почемужеонинеговорятпорусски", "b1abfaaepdrnnbgefbadotcwatmq2g4l"},
+		{"3年B組金八先生", "3B-ww4c5e180e575a65lsy2b"},
+		{"bücher", "bcher-kva"},
+	}
+	for _, c := range cases {
+		got, err := punycodeEncode(c.unicode)
+		if err != nil {
+			t.Errorf("encoding %q: %s", c.unicode, err)
+		} else if got != c.encoded {
+			t.Errorf("encoding %q: expected %q. got: %q", c.unicode, c.encoded, got)
+		}
+
+		decoded, err := punycodeDecode(c.encoded)
+		if err != nil {
+			t.Errorf("decoding %q: %s", c.encoded, err)
+		} else if decoded != c.unicode {
+			t.Errorf("decoding %q: expected %q. got: %q", c.encoded, c.unicode, decoded)
+		}
+	}
+}
+
+func TestIDNAToASCII(t *testing.T) {
+	cases := []struct {
+		name, ascii string
+		valid       bool
+	}{
+		{"example.com", "example.com", true},
+		{"bücher.example", "xn--bcher-kva.example", true},
+		{"xn--bcher-kva.example", "xn--bcher-kva.example", true},
+		{"실례。테스트", "xn--9n2bp8q.xn--9t4b11yi5a", true},
+		{"xn--X", "", false},
+		{"ab--cd", "", false},
+		{"-example", "", false},
+		{"example..com", "", false},
+		{"l·l", "xn--ll-0ea", true},
+		{"a·l", "", false},
+		{"α͵β", "", true},
+		{"α͵", "", false},
+		{"͵a", "", false},
+		{"א׳ב", "xn--4dbc5h", true},
+		{"׳ב", "", false},
+		{"ア・カ", "", true},
+		{"a・b", "", false},
+		{"क्‍ष", "", true},
+		{"क‍ष", "", false},
+		{"٠۰", "", false},
+		{"a b", "", false},
+	}
+	for _, c := range cases {
+		got, err := idnaToASCII(c.name)
+		if c.valid != (err == nil) {
+			t.Errorf("%q: expected valid to be %t. got error: %v", c.name, c.valid, err)
+			continue
+		}
+		if c.ascii != "" && got != c.ascii {
+			t.Errorf("%q: expected %q. got: %q", c.name, c.ascii, got)
+		}
+	}
+}
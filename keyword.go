@@ -11,14 +11,8 @@ import (
 )
 
 var notSupported = map[string]bool{
-	// core
-	"$vocabulary": true,
-
 	// other
-	"contentEncoding":  true,
-	"contentMediaType": true,
-	"contentSchema":    true,
-	"deprecated":       true,
+	"deprecated": true,
 
 	// backward compatibility with draft7
 	"definitions":  true,
@@ -34,6 +28,27 @@ type KeywordRegistry struct {
 	keywordRegistry    map[string]KeyMaker
 	keywordOrder       map[string]int
 	keywordInsertOrder map[string]int
+
+	// draftKeywords tracks the keywords registered by the currently
+	// loaded draft so they can be swapped out for another draft
+	draftKeywords map[string]bool
+	// refOverridesSiblings causes a "$ref" to disable all sibling
+	// keywords of a schema as required by draft7 and earlier
+	refOverridesSiblings bool
+	// idKeyword is the keyword setting the base URI of a schema,
+	// an empty value defaults to "$id"
+	idKeyword string
+	// vocabularies holds custom vocabularies by URI
+	vocabularies map[string]map[string]KeyMaker
+	// dialect is the meta-schema URI of the currently loaded draft
+	dialect string
+	// formats holds the checkers of custom and overridden formats
+	formats map[string]FormatChecker
+	// regexpEngine compiles patterns, defaulting to RE2Engine
+	regexpEngine RegexpEngine
+	// metaSchemaCtx is the context custom meta-schemas are resolved
+	// with while decoding a schema, see UnmarshalSchema
+	metaSchemaCtx context.Context
 }
 
 func getGlobalKeywordRegistry() (*KeywordRegistry, func()) {
@@ -54,12 +69,46 @@ func copyGlobalKeywordRegistry() *KeywordRegistry {
 	return kr.Copy()
 }
 
+// NewKeywordRegistry creates a KeywordRegistry populated with the keywords
+// and formats of the global registry
+func NewKeywordRegistry() *KeywordRegistry {
+	r := copyGlobalKeywordRegistry()
+	r.DefaultIfEmpty()
+	return r
+}
+
+// keywordRegistryCtxKey is the context key of the keyword registry
+type keywordRegistryCtxKey struct{}
+
+// WithKeywordRegistry returns a context validating formats with the
+// checkers registered with the given registry ahead of the global ones
+// when passed to Validate or Compile
+func WithKeywordRegistry(ctx context.Context, registry *KeywordRegistry) context.Context {
+	return context.WithValue(ctx, keywordRegistryCtxKey{}, registry)
+}
+
+// KeywordRegistryFromContext returns the registry set by WithKeywordRegistry
+// or nil
+func KeywordRegistryFromContext(ctx context.Context) *KeywordRegistry {
+	registry, _ := ctx.Value(keywordRegistryCtxKey{}).(*KeywordRegistry)
+	return registry
+}
+
 // Copy creates a new KeywordRegistry populated with the same data.
 func (r *KeywordRegistry) Copy() *KeywordRegistry {
 	dest := &KeywordRegistry{
 		keywordRegistry:    make(map[string]KeyMaker, len(r.keywordRegistry)),
 		keywordOrder:       make(map[string]int, len(r.keywordOrder)),
 		keywordInsertOrder: make(map[string]int, len(r.keywordInsertOrder)),
+		draftKeywords:      make(map[string]bool, len(r.draftKeywords)),
+		vocabularies:       make(map[string]map[string]KeyMaker, len(r.vocabularies)),
+		formats:            make(map[string]FormatChecker, len(r.formats)),
+
+		refOverridesSiblings: r.refOverridesSiblings,
+		idKeyword:            r.idKeyword,
+		dialect:              r.dialect,
+		regexpEngine:         r.regexpEngine,
+		metaSchemaCtx:        r.metaSchemaCtx,
 	}
 
 	for k, v := range r.keywordRegistry {
@@ -74,6 +123,18 @@ func (r *KeywordRegistry) Copy() *KeywordRegistry {
 		dest.keywordInsertOrder[k] = v
 	}
 
+	for k, v := range r.draftKeywords {
+		dest.draftKeywords[k] = v
+	}
+
+	for k, v := range r.vocabularies {
+		dest.vocabularies[k] = v
+	}
+
+	for k, v := range r.formats {
+		dest.formats[k] = v
+	}
+
 	return dest
 }
 
@@ -123,6 +184,15 @@ func SetKeywordOrder(prop string, order int) {
 	r.SetKeywordOrder(prop, order)
 }
 
+// IDKeyword returns the keyword used to identify a schema
+// and set its base URI
+func (r *KeywordRegistry) IDKeyword() string {
+	if r.idKeyword == "" {
+		return "$id"
+	}
+	return r.idKeyword
+}
+
 // IsNotSupportedKeyword is a utility function to clarify when
 // a given keyword, while expected is not supported
 func (r *KeywordRegistry) IsNotSupportedKeyword(prop string) bool {
@@ -135,6 +205,38 @@ func (r *KeywordRegistry) IsRegistryLoaded() bool {
 	return r.keywordRegistry != nil && len(r.keywordRegistry) > 0
 }
 
+// Dialect returns the meta-schema URI of the currently loaded draft
+func (r *KeywordRegistry) Dialect() string {
+	return r.dialect
+}
+
+// loadDraft replaces the keywords of a previously loaded draft with the
+// ones registered by load. Custom keywords are left untouched
+func (r *KeywordRegistry) loadDraft(dialect string, load func()) {
+	for prop := range r.draftKeywords {
+		delete(r.keywordRegistry, prop)
+		delete(r.keywordOrder, prop)
+		delete(r.keywordInsertOrder, prop)
+	}
+	r.refOverridesSiblings = false
+	r.idKeyword = ""
+	r.dialect = dialect
+
+	custom := make(map[string]bool, len(r.keywordRegistry))
+	for prop := range r.keywordRegistry {
+		custom[prop] = true
+	}
+
+	load()
+
+	r.draftKeywords = map[string]bool{}
+	for prop := range r.keywordRegistry {
+		if !custom[prop] {
+			r.draftKeywords[prop] = true
+		}
+	}
+}
+
 // RegisterKeyword registers a keyword with the registry
 func (r *KeywordRegistry) RegisterKeyword(prop string, maker KeyMaker) {
 	r.keywordRegistry[prop] = maker
@@ -190,6 +292,12 @@ type KeyError struct {
 	// PropertyPath is a string path that leads to the
 	// property that produced the error
 	PropertyPath string `json:"propertyPath,omitempty"`
+	// KeywordLocation is a JSON pointer to the keyword that produced
+	// the error following the evaluation path
+	KeywordLocation string `json:"keywordLocation,omitempty"`
+	// AbsoluteKeywordLocation is the absolute URI of the keyword that
+	// produced the error within its schema resource
+	AbsoluteKeywordLocation string `json:"absoluteKeywordLocation,omitempty"`
 	// InvalidValue is the value that returned the error
 	InvalidValue interface{} `json:"invalidValue,omitempty"`
 	// Message is a human-readable description of the error
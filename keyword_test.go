@@ -5,7 +5,12 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/qri-io/jsonpointer"
 	jptr "github.com/qri-io/jsonpointer"
@@ -65,17 +70,17 @@ func (f *IsFoo) ValidateKeyword(ctx context.Context, currentState *ValidationSta
 	}
 }
 
-// ContentEncoding represents a "custom" Schema property
-type ContentEncoding string
+// CustomContentEncoding represents a "custom" Schema property
+type CustomContentEncoding string
 
-// newContentEncoding allocates a new ContentEncoding validator
+// newContentEncoding allocates a new CustomContentEncoding validator
 func newContentEncoding() Keyword {
-	return new(ContentEncoding)
+	return new(CustomContentEncoding)
 }
 
-func (c ContentEncoding) Validate(propPath string, data interface{}, errs *[]KeyError) {}
+func (c CustomContentEncoding) Validate(propPath string, data interface{}, errs *[]KeyError) {}
 
-func (c ContentEncoding) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+func (c CustomContentEncoding) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	if obj, ok := data.(string); ok {
 		switch c {
 		case "base64":
@@ -91,13 +96,13 @@ func (c ContentEncoding) ValidateKeyword(ctx context.Context, currentState *Vali
 	}
 }
 
-func (c ContentEncoding) Register(uri string, registry *SchemaRegistry) {}
+func (c CustomContentEncoding) Register(uri string, registry *SchemaRegistry) {}
 
-func (c ContentEncoding) Resolve(pointer jsonpointer.Pointer, uri string) *Schema {
+func (c CustomContentEncoding) Resolve(pointer jsonpointer.Pointer, uri string) *Schema {
 	return nil
 }
 
-func ExampleCustomValidator() {
+func Example_customValidator() {
 
 	// register a custom validator by supplying a function
 	// that creates new instances of your Validator.
@@ -121,7 +126,7 @@ func ExampleCustomValidator() {
 	// Output: /: "bar" should be foo. plz make 'bar' == foo. plz
 }
 
-func ExampleCustomSchemaValidator() {
+func Example_customSchemaValidator() {
 
 	// register a custom validator by supplying a function
 	// that creates new instances of your Validator.
@@ -178,3 +183,296 @@ func TestRegisterFooKeyword(t *testing.T) {
 		t.Errorf("expected %s to be added as a default validator", "foo")
 	}
 }
+
+func TestLoadDraftKeepsCustomKeywords(t *testing.T) {
+	r := &KeywordRegistry{
+		keywordRegistry:    map[string]KeyMaker{},
+		keywordOrder:       map[string]int{},
+		keywordInsertOrder: map[string]int{},
+	}
+	r.RegisterKeyword("foo", func() Keyword { return new(FooKeyword) })
+
+	r.LoadDraft2019_09()
+	if !r.IsRegisteredKeyword("$defs") {
+		t.Errorf("expected draft2019_09 to register %s", "$defs")
+	}
+
+	r.LoadDraft7()
+	if r.IsRegisteredKeyword("$defs") {
+		t.Errorf("expected draft7 to unregister %s", "$defs")
+	}
+	if !r.IsRegisteredKeyword("definitions") {
+		t.Errorf("expected draft7 to register %s", "definitions")
+	}
+	if !r.IsRegisteredKeyword("foo") {
+		t.Errorf("expected custom keyword %s to survive loading a draft", "foo")
+	}
+}
+
+func TestCustomVocabulary(t *testing.T) {
+	ctx := context.Background()
+	RegisterVocabulary("https://example.com/vocab/foo", map[string]KeyMaker{
+		"foo": newIsFoo,
+	})
+
+	meta := &Schema{}
+	if err := json.Unmarshal([]byte(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id": "https://example.com/meta/foo",
+		"$vocabulary": {
+			"https://json-schema.org/draft/2020-12/vocab/core": true,
+			"https://json-schema.org/draft/2020-12/vocab/applicator": true,
+			"https://json-schema.org/draft/2020-12/vocab/validation": true,
+			"https://example.com/vocab/foo": true,
+			"https://example.com/vocab/optional": false
+		}
+	}`), meta); err != nil {
+		t.Fatal(err)
+	}
+	meta.Register("", &SchemaRegistry{})
+
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{
+		"$schema": "https://example.com/meta/foo",
+		"type": "string",
+		"format": "email",
+		"foo": true
+	}`), rs); err != nil {
+		t.Fatal(err)
+	}
+
+	errs, err := rs.ValidateBytes(ctx, []byte(`"bar"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("expected exactly one error from the foo vocabulary. got: %v", errs)
+	}
+	if errs[0].Message != "should be foo. plz make 'bar' == foo. plz" {
+		t.Errorf("unexpected error message: %s", errs[0].Message)
+	}
+
+	errs, err = rs.ValidateBytes(ctx, []byte(`1`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 {
+		t.Errorf("expected the validation vocabulary to stay enabled. got: %v", errs)
+	}
+
+	unknown := &Schema{}
+	if err := json.Unmarshal([]byte(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id": "https://example.com/meta/unknown",
+		"$vocabulary": {
+			"https://json-schema.org/draft/2020-12/vocab/core": true,
+			"https://example.com/vocab/unknown": true
+		}
+	}`), unknown); err != nil {
+		t.Fatal(err)
+	}
+	unknown.Register("", &SchemaRegistry{})
+
+	err = json.Unmarshal([]byte(`{"$schema": "https://example.com/meta/unknown"}`), &Schema{})
+	if err == nil {
+		t.Errorf("expected an unknown required vocabulary to error")
+	}
+}
+
+func TestMetaSchemaResolution(t *testing.T) {
+	ctx := context.Background()
+	metaSchema := func(id string) string {
+		return fmt.Sprintf(`{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"$id": %q,
+			"$vocabulary": {
+				"https://json-schema.org/draft/2020-12/vocab/core": true,
+				"https://json-schema.org/draft/2020-12/vocab/applicator": true
+			}
+		}`, id)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, metaSchema("http://"+r.Host+r.URL.Path))
+	}))
+	defer ts.Close()
+
+	// without the validation vocabulary "type" is ignored
+	errCount := func(rs *Schema) int {
+		errs, err := rs.ValidateBytes(ctx, []byte(`1`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(errs)
+	}
+
+	registry := NewSchemaRegistry()
+	if err := registry.PreloadBundle([]byte(metaSchema("urn:example:meta"))); err != nil {
+		t.Fatal(err)
+	}
+	schema := []byte(`{"$schema": "urn:example:meta", "type": "string"}`)
+	rs := &Schema{}
+	if err := UnmarshalSchema(WithSchemaRegistry(ctx, registry), schema, rs); err != nil {
+		t.Fatal(err)
+	}
+	if n := errCount(rs); n != 0 {
+		t.Errorf("expected the meta-schema of the scoped registry to apply. got %d errors", n)
+	}
+	err := json.Unmarshal(schema, &Schema{})
+	if err == nil || !strings.Contains(err.Error(), `unresolvable $schema "urn:example:meta"`) {
+		t.Errorf("expected a meta-schema outside of the global registry to be unresolvable. got: %v", err)
+	}
+
+	rs = &Schema{}
+	if err := json.Unmarshal([]byte(fmt.Sprintf(`{"$schema": "%s/meta", "type": "string"}`, ts.URL)), rs); err != nil {
+		t.Fatal(err)
+	}
+	if n := errCount(rs); n != 0 {
+		t.Errorf("expected the fetched meta-schema to apply. got %d errors", n)
+	}
+
+	err = json.Unmarshal([]byte(`{"$schema": "file:///missing/meta.json"}`), &Schema{})
+	if err == nil || !strings.Contains(err.Error(), "unresolvable $schema") {
+		t.Errorf("expected a missing meta-schema to error. got: %v", err)
+	}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	ctx := context.Background()
+	int32Format := func(data interface{}) error {
+		num, ok := data.(json.Number)
+		if !ok {
+			return nil
+		}
+		if _, err := strconv.ParseInt(string(num), 10, 32); err != nil {
+			return fmt.Errorf("%v is out of range", num)
+		}
+		return nil
+	}
+	RegisterFormat("int32", int32Format)
+	RegisterFormat("email", func(data interface{}) error {
+		if str, ok := data.(string); ok && len(str) > 0 && str[0] == '_' {
+			return fmt.Errorf("must not start with an underscore")
+		}
+		return nil
+	})
+	defer func() {
+		r, release := getGlobalKeywordRegistry()
+		formats := map[string]FormatChecker{}
+		for k, v := range r.formats {
+			if k != "int32" && k != "email" {
+				formats[k] = v
+			}
+		}
+		r.formats = formats
+		release()
+	}()
+
+	cases := []struct {
+		schema, doc string
+		errs        int
+	}{
+		{`{"format": "int32"}`, `12`, 0},
+		{`{"format": "int32"}`, `4294967296`, 1},
+		{`{"format": "int32"}`, `"4294967296"`, 0},
+		{`{"format": "email"}`, `"not an email"`, 0},
+		{`{"format": "email"}`, `"_a@example.com"`, 1},
+		{`{"format": "semver"}`, `"not a version"`, 0},
+	}
+	for i, c := range cases {
+		rs := &Schema{}
+		if err := json.Unmarshal([]byte(c.schema), rs); err != nil {
+			t.Fatal(err)
+		}
+		errs, err := rs.ValidateBytes(ctx, []byte(c.doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(errs) != c.errs {
+			t.Errorf("case %d: expected %d errors. got: %v", i, c.errs, errs)
+		}
+	}
+
+	r := NewKeywordRegistry()
+	r.RegisterFormat("semver", func(data interface{}) error {
+		if str, ok := data.(string); ok && !strings.HasPrefix(str, "v") {
+			return fmt.Errorf("must start with v")
+		}
+		return nil
+	})
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{"format": "semver"}`), rs); err != nil {
+		t.Fatal(err)
+	}
+	if errs, _ := rs.ValidateBytes(WithKeywordRegistry(ctx, r), []byte(`"1.0.0"`)); len(errs) != 1 {
+		t.Errorf("expected the format of the context registry to apply. got: %v", errs)
+	}
+	if errs, _ := rs.ValidateBytes(ctx, []byte(`"1.0.0"`)); len(errs) != 0 {
+		t.Errorf("expected the format to be unknown outside of the registry. got: %v", errs)
+	}
+
+	// compiled schemas check formats without locking the global registry
+	if err := json.Unmarshal([]byte(`{"items": {"format": "int32"}}`), rs); err != nil {
+		t.Fatal(err)
+	}
+	cs, err := Compile(ctx, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, release := getGlobalKeywordRegistry()
+	done := make(chan []KeyError)
+	go func() {
+		errs, _ := cs.ValidateBytes(ctx, []byte(`[1, 4294967296]`))
+		done <- errs
+	}()
+	select {
+	case errs := <-done:
+		if len(errs) != 1 {
+			t.Errorf("expected the compiled format to apply. got: %v", errs)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("expected validating a compiled schema not to lock the keyword registry")
+	}
+	release()
+}
+
+func TestFormatMode(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		mode   FormatMode
+		schema string
+		doc    string
+		errs   int
+	}{
+		{FormatAssertKnown, `{"format": "email"}`, `"not an email"`, 1},
+		{FormatAssertKnown, `{"format": "emial"}`, `"not an email"`, 0},
+		{FormatAnnotation, `{"format": "email"}`, `"not an email"`, 0},
+		{FormatAnnotation, `{"format": "emial"}`, `"not an email"`, 0},
+		{FormatAssertAll, `{"format": "email"}`, `"a@example.com"`, 0},
+		{FormatAssertAll, `{"format": "emial"}`, `"a@example.com"`, 1},
+	}
+	for i, c := range cases {
+		rs := &Schema{}
+		if err := json.Unmarshal([]byte(c.schema), rs); err != nil {
+			t.Fatal(err)
+		}
+		errs, err := rs.ValidateBytes(WithFormatMode(ctx, c.mode), []byte(c.doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(errs) != c.errs {
+			t.Errorf("case %d: expected %d errors. got: %v", i, c.errs, errs)
+		}
+	}
+
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{"properties": {"a": {"format": "emial"}}}`), rs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Compile(ctx, rs); err != nil {
+		t.Errorf("expected unknown formats to compile by default. got: %s", err)
+	}
+	_, err := Compile(WithFormatMode(ctx, FormatAssertAll), rs)
+	if err == nil || err.Error() != "unknown format emial" {
+		t.Errorf("expected compiling an unknown format to fail. got: %v", err)
+	}
+}
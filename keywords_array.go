@@ -1,10 +1,10 @@
 package jsonschema
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"reflect"
 	"strconv"
 
 	jptr "github.com/qri-io/jsonpointer"
@@ -38,6 +38,10 @@ func (it *Items) Resolve(pointer jptr.Pointer, uri string) *Schema {
 		return nil
 	}
 
+	if it.single {
+		return it.Schemas[0].Resolve(pointer, uri)
+	}
+
 	pos, err := strconv.Atoi(*current)
 	if err != nil {
 		return nil
@@ -55,30 +59,34 @@ func (it Items) ValidateKeyword(ctx context.Context, currentState *ValidationSta
 	schemaDebug("[Items] Validating")
 	if arr, ok := data.([]interface{}); ok {
 		if it.single {
+			// items only applies to elements not covered by a sibling prefixItems
+			start := 0
+			if prefixItemsCount, ok := currentState.Misc["prefixItemsCount"]; ok {
+				start = prefixItemsCount.(int)
+			}
 			subState := currentState.NewSubState()
 			subState.DescendBase("items")
 			subState.DescendRelative("items")
-			for i, elem := range arr {
+			for i := start; i < len(arr); i++ {
+				elem := arr[i]
+				currentState.SetEvaluatedIndex(i)
 				subState.ClearState()
 				subState.DescendInstanceFromState(currentState, strconv.Itoa(i))
 				it.Schemas[0].ValidateKeyword(ctx, subState, elem)
-				subState.SetEvaluatedIndex(i)
-				// TODO(arqu): this might clash with additional/unevaluated
-				// Properties/Items, should separate out
-				currentState.UpdateEvaluatedPropsAndItems(subState)
 			}
 		} else {
+			// additionalItems applies to the elements following the items
+			currentState.Misc["itemsCount"] = len(it.Schemas)
 			subState := currentState.NewSubState()
-			subState.DescendBase("items")
 			for i, vs := range it.Schemas {
 				if i < len(arr) {
+					currentState.SetEvaluatedIndex(i)
 					subState.ClearState()
+					subState.DescendBaseFromState(currentState, "items", strconv.Itoa(i))
 					subState.DescendRelativeFromState(currentState, "items", strconv.Itoa(i))
 					subState.DescendInstanceFromState(currentState, strconv.Itoa(i))
 
 					vs.ValidateKeyword(ctx, subState, arr[i])
-					subState.SetEvaluatedIndex(i)
-					currentState.UpdateEvaluatedPropsAndItems(subState)
 				}
 			}
 		}
@@ -108,13 +116,21 @@ func (it Items) JSONChildren() (res map[string]JSONPather) {
 
 // UnmarshalJSON implements the json.Unmarshaler interface for Items
 func (it *Items) UnmarshalJSON(data []byte) error {
-	s := &Schema{}
-	if err := json.Unmarshal(data, s); err == nil {
+	return it.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for Items
+func (it *Items) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	if s, err := unmarshalSchema(data, registry); err == nil {
 		*it = Items{single: true, Schemas: []*Schema{s}}
 		return nil
 	}
-	ss := []*Schema{}
-	if err := json.Unmarshal(data, &ss); err != nil {
+	if registry != nil && registry.IsRegisteredKeyword("prefixItems") && bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		// prefixItems replaces the array form of items since draft 2020-12
+		return fmt.Errorf("items must be a schema, use prefixItems for an array of schemas")
+	}
+	ss, err := unmarshalSchemaList(data, registry)
+	if err != nil {
 		return err
 	}
 	*it = Items{Schemas: ss}
@@ -129,6 +145,94 @@ func (it Items) MarshalJSON() ([]byte, error) {
 	return json.Marshal([]*Schema(it.Schemas))
 }
 
+// PrefixItems defines the prefixItems JSON Schema keyword
+type PrefixItems []*Schema
+
+// NewPrefixItems allocates a new PrefixItems keyword
+func NewPrefixItems() Keyword {
+	return &PrefixItems{}
+}
+
+// Register implements the Keyword interface for PrefixItems
+func (p *PrefixItems) Register(uri string, registry *SchemaRegistry) {
+	for _, v := range *p {
+		v.Register(uri, registry)
+	}
+}
+
+// Resolve implements the Keyword interface for PrefixItems
+func (p *PrefixItems) Resolve(pointer jptr.Pointer, uri string) *Schema {
+	if pointer == nil {
+		return nil
+	}
+	current := pointer.Head()
+	if current == nil {
+		return nil
+	}
+
+	pos, err := strconv.Atoi(*current)
+	if err != nil {
+		return nil
+	}
+
+	if pos < 0 || pos >= len(*p) {
+		return nil
+	}
+
+	return (*p)[pos].Resolve(pointer.Tail(), uri)
+}
+
+// ValidateKeyword implements the Keyword interface for PrefixItems
+func (p PrefixItems) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+	schemaDebug("[PrefixItems] Validating")
+	if arr, ok := data.([]interface{}); ok {
+		currentState.Misc["prefixItemsCount"] = len(p)
+		subState := currentState.NewSubState()
+		for i, vs := range p {
+			if i < len(arr) {
+				subState.ClearState()
+				subState.DescendBaseFromState(currentState, "prefixItems", strconv.Itoa(i))
+				subState.DescendRelativeFromState(currentState, "prefixItems", strconv.Itoa(i))
+				subState.DescendInstanceFromState(currentState, strconv.Itoa(i))
+
+				vs.ValidateKeyword(ctx, subState, arr[i])
+				currentState.SetEvaluatedIndex(i)
+			}
+		}
+	}
+}
+
+// JSONProp implements the JSONPather for PrefixItems
+func (p PrefixItems) JSONProp(name string) interface{} {
+	idx, err := strconv.Atoi(name)
+	if err != nil {
+		return nil
+	}
+	if idx >= len(p) || idx < 0 {
+		return nil
+	}
+	return p[idx]
+}
+
+// JSONChildren implements the JSONContainer interface for PrefixItems
+func (p PrefixItems) JSONChildren() (res map[string]JSONPather) {
+	res = map[string]JSONPather{}
+	for i, sch := range p {
+		res[strconv.Itoa(i)] = sch
+	}
+	return
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for PrefixItems
+func (p *PrefixItems) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	schemas, err := unmarshalSchemaList(data, registry)
+	if err != nil {
+		return err
+	}
+	*p = PrefixItems(schemas)
+	return nil
+}
+
 // MaxItems defines the maxItems JSON Schema keyword
 type MaxItems int
 
@@ -149,10 +253,14 @@ func (m *MaxItems) Resolve(pointer jptr.Pointer, uri string) *Schema {
 func (m MaxItems) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[MaxItems] Validating")
 	if arr, ok := data.([]interface{}); ok {
-		if len(arr) > int(m) {
-			currentState.AddError(data, fmt.Sprintf("array length %d exceeds %d max", len(arr), m))
-			return
-		}
+		m.validateLength(currentState, data, len(arr))
+	}
+}
+
+// validateLength checks the length of an array instance
+func (m MaxItems) validateLength(currentState *ValidationState, data interface{}, length int) {
+	if length > int(m) {
+		currentState.AddError(data, fmt.Sprintf("array length %d exceeds %d max", length, m))
 	}
 }
 
@@ -176,10 +284,14 @@ func (m *MinItems) Resolve(pointer jptr.Pointer, uri string) *Schema {
 func (m MinItems) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[MinItems] Validating")
 	if arr, ok := data.([]interface{}); ok {
-		if len(arr) < int(m) {
-			currentState.AddError(data, fmt.Sprintf("array length %d below %d minimum items", len(arr), m))
-			return
-		}
+		m.validateLength(currentState, data, len(arr))
+	}
+}
+
+// validateLength checks the length of an array instance
+func (m MinItems) validateLength(currentState *ValidationState, data interface{}, length int) {
+	if length < int(m) {
+		currentState.AddError(data, fmt.Sprintf("array length %d below %d minimum items", length, m))
 	}
 }
 
@@ -202,11 +314,14 @@ func (u *UniqueItems) Resolve(pointer jptr.Pointer, uri string) *Schema {
 // ValidateKeyword implements the Keyword interface for UniqueItems
 func (u UniqueItems) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[UniqueItems] Validating")
+	if !u {
+		return
+	}
 	if arr, ok := data.([]interface{}); ok {
 		found := []interface{}{}
 		for _, elem := range arr {
 			for _, f := range found {
-				if reflect.DeepEqual(f, elem) {
+				if jsonEqual(f, elem) {
 					currentState.AddError(data, fmt.Sprintf("array items must be unique. duplicated entry: %v", elem))
 					return
 				}
@@ -239,7 +354,6 @@ func (c *Contains) ValidateKeyword(ctx context.Context, currentState *Validation
 	schemaDebug("[Contains] Validating")
 	v := Schema(*c)
 	if arr, ok := data.([]interface{}); ok {
-		valid := false
 		matchCount := 0
 		subState := currentState.NewSubState()
 		subState.ClearState()
@@ -251,16 +365,29 @@ func (c *Contains) ValidateKeyword(ctx context.Context, currentState *Validation
 			subState.Errs = &[]KeyError{}
 			v.ValidateKeyword(ctx, subState, elem)
 			if subState.IsValid() {
-				valid = true
+				// matching elements count as evaluated for unevaluatedItems
+				currentState.SetEvaluatedIndex(i)
 				matchCount++
 			}
 		}
-		if valid {
-			currentState.Misc["containsCount"] = matchCount
-		} else {
-			currentState.AddError(data, fmt.Sprintf("must contain at least one of: %v", c))
+		c.validateCount(currentState, data, matchCount)
+	}
+}
+
+// validateCount checks the number of elements of an array instance
+// matching the contains schema, leaving the minimum to a sibling
+// minContains keyword
+func (c *Contains) validateCount(currentState *ValidationState, data interface{}, matchCount int) {
+	currentState.Misc["containsCount"] = matchCount
+	if matchCount > 0 {
+		return
+	}
+	if currentState.Local != nil {
+		if _, ok := currentState.Local.keywords["minContains"]; ok {
+			return
 		}
 	}
+	currentState.AddError(data, fmt.Sprintf("must contain at least one of: %v", c))
 }
 
 // JSONProp implements the JSONPather for Contains
@@ -275,14 +402,24 @@ func (c Contains) JSONChildren() (res map[string]JSONPather) {
 
 // UnmarshalJSON implements the json.Unmarshaler interface for Contains
 func (c *Contains) UnmarshalJSON(data []byte) error {
-	var sch Schema
-	if err := json.Unmarshal(data, &sch); err != nil {
+	return c.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for Contains
+func (c *Contains) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	sch, err := unmarshalSchema(data, registry)
+	if err != nil {
 		return err
 	}
-	*c = Contains(sch)
+	*c = Contains(*sch)
 	return nil
 }
 
+// MarshalJSON implements the json.Marshaler interface for Contains
+func (c Contains) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Schema(c))
+}
+
 // MaxContains defines the maxContains JSON Schema keyword
 type MaxContains int
 
@@ -303,10 +440,16 @@ func (m *MaxContains) Resolve(pointer jptr.Pointer, uri string) *Schema {
 func (m MaxContains) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[MaxContains] Validating")
 	if arr, ok := data.([]interface{}); ok {
-		if containsCount, ok := currentState.Misc["containsCount"]; ok {
-			if containsCount.(int) > int(m) {
-				currentState.AddError(data, fmt.Sprintf("contained items %d exceeds %d max", len(arr), m))
-			}
+		m.validateLength(currentState, data, len(arr))
+	}
+}
+
+// validateLength checks the number of contained elements recorded by
+// the contains keyword for an array instance of the given length
+func (m MaxContains) validateLength(currentState *ValidationState, data interface{}, length int) {
+	if containsCount, ok := currentState.Misc["containsCount"]; ok {
+		if containsCount.(int) > int(m) {
+			currentState.AddError(data, fmt.Sprintf("contained items %d exceeds %d max", length, m))
 		}
 	}
 }
@@ -331,10 +474,16 @@ func (m *MinContains) Resolve(pointer jptr.Pointer, uri string) *Schema {
 func (m MinContains) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[MinContains] Validating")
 	if arr, ok := data.([]interface{}); ok {
-		if containsCount, ok := currentState.Misc["containsCount"]; ok {
-			if containsCount.(int) < int(m) {
-				currentState.AddError(data, fmt.Sprintf("contained items %d bellow %d min", len(arr), m))
-			}
+		m.validateLength(currentState, data, len(arr))
+	}
+}
+
+// validateLength checks the number of contained elements recorded by
+// the contains keyword for an array instance of the given length
+func (m MinContains) validateLength(currentState *ValidationState, data interface{}, length int) {
+	if containsCount, ok := currentState.Misc["containsCount"]; ok {
+		if containsCount.(int) < int(m) {
+			currentState.AddError(data, fmt.Sprintf("contained items %d bellow %d min", length, m))
 		}
 	}
 }
@@ -361,36 +510,48 @@ func (ai *AdditionalItems) Resolve(pointer jptr.Pointer, uri string) *Schema {
 func (ai *AdditionalItems) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[AdditionalItems] Validating")
 	if arr, ok := data.([]interface{}); ok {
-		if currentState.LastEvaluatedIndex > -1 && currentState.LastEvaluatedIndex < len(arr) {
-			for i := currentState.LastEvaluatedIndex + 1; i < len(arr); i++ {
-				if ai.schemaType == schemaTypeFalse {
-					currentState.AddError(data, "additional items are not allowed")
-					return
-				}
-				subState := currentState.NewSubState()
-				subState.ClearState()
-				subState.SetEvaluatedIndex(i)
-				subState.DescendBase("additionalItems")
-				subState.DescendRelative("additionalItems")
-				subState.DescendInstance(strconv.Itoa(i))
-
-				(*Schema)(ai).ValidateKeyword(ctx, subState, arr[i])
-				currentState.UpdateEvaluatedPropsAndItems(subState)
+		itemsCount, ok := currentState.Misc["itemsCount"].(int)
+		if !ok {
+			// additionalItems only applies following an array of items
+			return
+		}
+		for i := itemsCount; i < len(arr); i++ {
+			if ai.schemaType == schemaTypeFalse {
+				currentState.AddError(data, "additional items are not allowed")
+				return
 			}
+			currentState.SetEvaluatedIndex(i)
+			subState := currentState.NewSubState()
+			subState.ClearState()
+			subState.DescendBase("additionalItems")
+			subState.DescendRelative("additionalItems")
+			subState.DescendInstance(strconv.Itoa(i))
+
+			(*Schema)(ai).ValidateKeyword(ctx, subState, arr[i])
 		}
 	}
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface for AdditionalItems
 func (ai *AdditionalItems) UnmarshalJSON(data []byte) error {
-	sch := &Schema{}
-	if err := json.Unmarshal(data, sch); err != nil {
+	return ai.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for AdditionalItems
+func (ai *AdditionalItems) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	sch, err := unmarshalSchema(data, registry)
+	if err != nil {
 		return err
 	}
-	*ai = (AdditionalItems)(*sch)
+	*ai = AdditionalItems(*sch)
 	return nil
 }
 
+// MarshalJSON implements the json.Marshaler interface for AdditionalItems
+func (ai AdditionalItems) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Schema(ai))
+}
+
 // UnevaluatedItems defines the unevaluatedItems JSON Schema keyword
 type UnevaluatedItems Schema
 
@@ -413,30 +574,42 @@ func (ui *UnevaluatedItems) Resolve(pointer jptr.Pointer, uri string) *Schema {
 func (ui *UnevaluatedItems) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[UnevaluatedItems] Validating")
 	if arr, ok := data.([]interface{}); ok {
-		if currentState.LastEvaluatedIndex < len(arr) {
-			for i := currentState.LastEvaluatedIndex + 1; i < len(arr); i++ {
-				if ui.schemaType == schemaTypeFalse {
-					currentState.AddError(data, "unevaluated items are not allowed")
-					return
-				}
-				subState := currentState.NewSubState()
-				subState.ClearState()
-				subState.DescendBase("unevaluatedItems")
-				subState.DescendRelative("unevaluatedItems")
-				subState.DescendInstance(strconv.Itoa(i))
-
-				(*Schema)(ui).ValidateKeyword(ctx, subState, arr[i])
+		for i := range arr {
+			if currentState.IsEvaluatedIndex(i) {
+				continue
 			}
+			if ui.schemaType == schemaTypeFalse {
+				currentState.AddError(data, "unevaluated items are not allowed")
+				return
+			}
+			currentState.SetEvaluatedIndex(i)
+			subState := currentState.NewSubState()
+			subState.ClearState()
+			subState.DescendBase("unevaluatedItems")
+			subState.DescendRelative("unevaluatedItems")
+			subState.DescendInstance(strconv.Itoa(i))
+
+			(*Schema)(ui).ValidateKeyword(ctx, subState, arr[i])
 		}
 	}
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface for UnevaluatedItems
 func (ui *UnevaluatedItems) UnmarshalJSON(data []byte) error {
-	sch := &Schema{}
-	if err := json.Unmarshal(data, sch); err != nil {
+	return ui.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for UnevaluatedItems
+func (ui *UnevaluatedItems) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	sch, err := unmarshalSchema(data, registry)
+	if err != nil {
 		return err
 	}
-	*ui = (UnevaluatedItems)(*sch)
+	*ui = UnevaluatedItems(*sch)
 	return nil
 }
+
+// MarshalJSON implements the json.Marshaler interface for UnevaluatedItems
+func (ui UnevaluatedItems) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Schema(ui))
+}
@@ -90,6 +90,16 @@ func (a AllOf) JSONChildren() (res map[string]JSONPather) {
 	return
 }
 
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for AllOf
+func (a *AllOf) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	schemas, err := unmarshalSchemaList(data, registry)
+	if err != nil {
+		return err
+	}
+	*a = AllOf(schemas)
+	return nil
+}
+
 // AnyOf defines the anyOf JSON Schema keyword
 type AnyOf []*Schema
 
@@ -130,6 +140,9 @@ func (a *AnyOf) Resolve(pointer jptr.Pointer, uri string) *Schema {
 // ValidateKeyword implements the Keyword interface for AnyOf
 func (a *AnyOf) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[AnyOf] Validating")
+	matched := false
+	// every schema is evaluated as all matching schemas
+	// contribute evaluated properties and items
 	for i, sch := range *a {
 		subState := currentState.NewSubState()
 		subState.ClearState()
@@ -139,11 +152,13 @@ func (a *AnyOf) ValidateKeyword(ctx context.Context, currentState *ValidationSta
 		sch.ValidateKeyword(ctx, subState, data)
 		if subState.IsValid() {
 			currentState.UpdateEvaluatedPropsAndItems(subState)
-			return
+			matched = true
 		}
 	}
 
-	currentState.AddError(data, "did Not match any specified AnyOf schemas")
+	if !matched {
+		currentState.AddError(data, "did Not match any specified AnyOf schemas")
+	}
 }
 
 // JSONProp implements the JSONPather for AnyOf
@@ -167,6 +182,16 @@ func (a AnyOf) JSONChildren() (res map[string]JSONPather) {
 	return
 }
 
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for AnyOf
+func (a *AnyOf) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	schemas, err := unmarshalSchemaList(data, registry)
+	if err != nil {
+		return err
+	}
+	*a = AnyOf(schemas)
+	return nil
+}
+
 // OneOf defines the oneOf JSON Schema keyword
 type OneOf []*Schema
 
@@ -217,8 +242,8 @@ func (o *OneOf) ValidateKeyword(ctx context.Context, currentState *ValidationSta
 		subState.DescendRelative("oneOf", strconv.Itoa(i))
 		subState.Errs = &[]KeyError{}
 		sch.ValidateKeyword(ctx, subState, data)
-		stateCopy.UpdateEvaluatedPropsAndItems(subState)
 		if subState.IsValid() {
+			stateCopy.UpdateEvaluatedPropsAndItems(subState)
 			if matched {
 				currentState.AddError(data, "matched more than one specified OneOf schemas")
 				return
@@ -254,6 +279,16 @@ func (o OneOf) JSONChildren() (res map[string]JSONPather) {
 	return
 }
 
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for OneOf
+func (o *OneOf) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	schemas, err := unmarshalSchemaList(data, registry)
+	if err != nil {
+		return err
+	}
+	*o = OneOf(schemas)
+	return nil
+}
+
 // Not defines the not JSON Schema keyword
 type Not Schema
 
@@ -276,6 +311,7 @@ func (n *Not) Resolve(pointer jptr.Pointer, uri string) *Schema {
 func (n *Not) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Not] Validating")
 	subState := currentState.NewSubState()
+	subState.ClearState()
 	subState.DescendBase("not")
 	subState.DescendRelative("not")
 
@@ -299,11 +335,16 @@ func (n Not) JSONChildren() (res map[string]JSONPather) {
 
 // UnmarshalJSON implements the json.Unmarshaler interface for Not
 func (n *Not) UnmarshalJSON(data []byte) error {
-	var sch Schema
-	if err := json.Unmarshal(data, &sch); err != nil {
+	return n.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for Not
+func (n *Not) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	sch, err := unmarshalSchema(data, registry)
+	if err != nil {
 		return err
 	}
-	*n = Not(sch)
+	*n = Not(*sch)
 	return nil
 }
 
@@ -28,15 +28,8 @@ func (f *If) Resolve(pointer jptr.Pointer, uri string) *Schema {
 // ValidateKeyword implements the Keyword interface for If
 func (f *If) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[If] Validating")
-	thenKW := currentState.Local.keywords["then"]
-	elseKW := currentState.Local.keywords["else"]
-
-	if thenKW == nil && elseKW == nil {
-		// no then or else for if, aborting validation
-		schemaDebug("[If] Aborting validation as no then or else is present")
-		return
-	}
-
+	// if is evaluated without then or else as well, as
+	// it may evaluate properties and items
 	subState := currentState.NewSubState()
 	subState.ClearState()
 	subState.DescendBase("if")
@@ -47,6 +40,9 @@ func (f *If) ValidateKeyword(ctx context.Context, currentState *ValidationState,
 	sch.ValidateKeyword(ctx, subState, data)
 
 	currentState.Misc["ifResult"] = subState.IsValid()
+	if subState.IsValid() {
+		currentState.UpdateEvaluatedPropsAndItems(subState)
+	}
 }
 
 // JSONProp implements the JSONPather for If
@@ -61,11 +57,16 @@ func (f If) JSONChildren() (res map[string]JSONPather) {
 
 // UnmarshalJSON implements the json.Unmarshaler interface for If
 func (f *If) UnmarshalJSON(data []byte) error {
-	var sch Schema
-	if err := json.Unmarshal(data, &sch); err != nil {
+	return f.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for If
+func (f *If) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	sch, err := unmarshalSchema(data, registry)
+	if err != nil {
 		return err
 	}
-	*f = If(sch)
+	*f = If(*sch)
 	return nil
 }
 
@@ -128,11 +129,16 @@ func (t Then) JSONChildren() (res map[string]JSONPather) {
 
 // UnmarshalJSON implements the json.Unmarshaler interface for Then
 func (t *Then) UnmarshalJSON(data []byte) error {
-	var sch Schema
-	if err := json.Unmarshal(data, &sch); err != nil {
+	return t.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for Then
+func (t *Then) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	sch, err := unmarshalSchema(data, registry)
+	if err != nil {
 		return err
 	}
-	*t = Then(sch)
+	*t = Then(*sch)
 	return nil
 }
 
@@ -192,11 +198,16 @@ func (e Else) JSONChildren() (res map[string]JSONPather) {
 
 // UnmarshalJSON implements the json.Unmarshaler interface for Else
 func (e *Else) UnmarshalJSON(data []byte) error {
-	var sch Schema
-	if err := json.Unmarshal(data, &sch); err != nil {
+	return e.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for Else
+func (e *Else) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	sch, err := unmarshalSchema(data, registry)
+	if err != nil {
 		return err
 	}
-	*e = Else(sch)
+	*e = Else(*sch)
 	return nil
 }
 
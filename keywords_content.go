@@ -0,0 +1,295 @@
+package jsonschema
+
+import (
+	"bytes"
+	"context"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+
+	jptr "github.com/qri-io/jsonpointer"
+)
+
+// ContentMode controls whether the content keywords assert the content
+// of string instances or only annotate them
+type ContentMode int
+
+const (
+	// ContentAnnotation only collects the content keywords as annotations.
+	// This is the default
+	ContentAnnotation ContentMode = iota
+	// ContentAssert decodes string instances following contentEncoding,
+	// parses them following contentMediaType and validates the resulting
+	// document against contentSchema
+	ContentAssert
+)
+
+// contentModeCtxKey is the context key of the content mode
+type contentModeCtxKey struct{}
+
+// WithContentMode returns a context validating the content keywords in
+// the given mode when passed to Validate
+func WithContentMode(ctx context.Context, mode ContentMode) context.Context {
+	return context.WithValue(ctx, contentModeCtxKey{}, mode)
+}
+
+// ContentModeFromContext returns the mode set by WithContentMode or
+// ContentAnnotation
+func ContentModeFromContext(ctx context.Context) ContentMode {
+	mode, _ := ctx.Value(contentModeCtxKey{}).(ContentMode)
+	return mode
+}
+
+// ContentEncoding defines the contentEncoding JSON Schema keyword
+type ContentEncoding string
+
+// NewContentEncoding allocates a new ContentEncoding keyword
+func NewContentEncoding() Keyword {
+	return new(ContentEncoding)
+}
+
+// Register implements the Keyword interface for ContentEncoding
+func (c *ContentEncoding) Register(uri string, registry *SchemaRegistry) {}
+
+// Resolve implements the Keyword interface for ContentEncoding
+func (c *ContentEncoding) Resolve(pointer jptr.Pointer, uri string) *Schema {
+	return nil
+}
+
+// ValidateKeyword implements the Keyword interface for ContentEncoding
+func (c ContentEncoding) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+	schemaDebug("[ContentEncoding] Validating")
+	str, ok := data.(string)
+	if !ok {
+		return
+	}
+	currentState.AddAnnotation(string(c))
+	if ContentModeFromContext(ctx) != ContentAssert {
+		return
+	}
+
+	decoded, err := decodeContent(string(c), str)
+	if err != nil {
+		currentState.AddError(data, fmt.Sprintf("invalid %s content: %s", c, err.Error()))
+		return
+	}
+	currentState.Misc["contentDecoded"] = decoded
+}
+
+// decodeContent decodes a string following the given content encoding
+// https://tools.ietf.org/html/rfc2045#section-6.1
+// https://tools.ietf.org/html/rfc4648
+func decodeContent(encoding, str string) ([]byte, error) {
+	switch strings.ToLower(encoding) {
+	case "7bit", "8bit", "binary":
+		return []byte(str), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(stripLineBreaks(str))
+	case "base64url":
+		return base64.URLEncoding.DecodeString(str)
+	case "base32":
+		return base32.StdEncoding.DecodeString(stripLineBreaks(str))
+	case "base32hex":
+		return base32.HexEncoding.DecodeString(str)
+	case "base16":
+		return decodeBase16(str)
+	case "quoted-printable":
+		return decodeQuotedPrintable(str)
+	}
+	return nil, fmt.Errorf("unsupported contentEncoding")
+}
+
+// stripLineBreaks removes the line breaks MIME inserts into encoded content
+func stripLineBreaks(str string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(str)
+}
+
+// decodeBase16 decodes the uppercase hexadecimal encoding of RFC 4648
+func decodeBase16(str string) ([]byte, error) {
+	if len(str)%2 != 0 {
+		return nil, fmt.Errorf("odd length")
+	}
+	res := make([]byte, len(str)/2)
+	for i := 0; i < len(str); i += 2 {
+		hi, lo := strings.IndexByte("0123456789ABCDEF", str[i]), strings.IndexByte("0123456789ABCDEF", str[i+1])
+		if hi < 0 || lo < 0 {
+			return nil, fmt.Errorf("illegal character at offset %d", i)
+		}
+		res[i/2] = byte(hi<<4 | lo)
+	}
+	return res, nil
+}
+
+// decodeQuotedPrintable strictly decodes the quoted-printable encoding of
+// RFC 2045, section 6.7, which lenient MIME readers pass through unchanged
+// https://tools.ietf.org/html/rfc2045#section-6.7
+func decodeQuotedPrintable(str string) ([]byte, error) {
+	var buf bytes.Buffer
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		switch {
+		case c == '=':
+			if strings.HasPrefix(str[i+1:], "\r\n") {
+				// soft line break
+				i += 2
+				continue
+			}
+			if i+2 >= len(str) {
+				return nil, fmt.Errorf("incomplete escape at offset %d", i)
+			}
+			b, err := decodeBase16(str[i+1 : i+3])
+			if err != nil {
+				return nil, fmt.Errorf("invalid escape at offset %d", i)
+			}
+			buf.WriteByte(b[0])
+			i += 2
+		case c == '\r' && strings.HasPrefix(str[i+1:], "\n"):
+			buf.WriteString("\r\n")
+			i++
+		case c == ' ' || c == '\t' || c >= 33 && c <= 126:
+			buf.WriteByte(c)
+		default:
+			return nil, fmt.Errorf("invalid character %q at offset %d", c, i)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// ContentMediaType defines the contentMediaType JSON Schema keyword
+type ContentMediaType string
+
+// NewContentMediaType allocates a new ContentMediaType keyword
+func NewContentMediaType() Keyword {
+	return new(ContentMediaType)
+}
+
+// Register implements the Keyword interface for ContentMediaType
+func (c *ContentMediaType) Register(uri string, registry *SchemaRegistry) {}
+
+// Resolve implements the Keyword interface for ContentMediaType
+func (c *ContentMediaType) Resolve(pointer jptr.Pointer, uri string) *Schema {
+	return nil
+}
+
+// ValidateKeyword implements the Keyword interface for ContentMediaType
+func (c ContentMediaType) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+	schemaDebug("[ContentMediaType] Validating")
+	str, ok := data.(string)
+	if !ok {
+		return
+	}
+	currentState.AddAnnotation(string(c))
+	if ContentModeFromContext(ctx) != ContentAssert {
+		return
+	}
+
+	content := []byte(str)
+	if currentState.Local != nil && currentState.Local.HasKeyword("contentEncoding") {
+		decoded, ok := currentState.Misc["contentDecoded"].([]byte)
+		if !ok {
+			// content that failed to decode has already been reported
+			return
+		}
+		content = decoded
+	}
+
+	mediaType, _, err := mime.ParseMediaType(string(c))
+	if err != nil {
+		currentState.AddError(data, fmt.Sprintf("invalid contentMediaType %s: %s", c, err.Error()))
+		return
+	}
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		// other media types are only annotated
+		return
+	}
+
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		currentState.AddError(data, fmt.Sprintf("invalid %s content: %s", mediaType, err.Error()))
+		return
+	}
+	if dec.More() {
+		currentState.AddError(data, fmt.Sprintf("invalid %s content: unexpected data after the document", mediaType))
+		return
+	}
+	currentState.Misc["contentDocument"] = doc
+}
+
+// ContentSchema defines the contentSchema JSON Schema keyword
+type ContentSchema Schema
+
+// NewContentSchema allocates a new ContentSchema keyword
+func NewContentSchema() Keyword {
+	return &ContentSchema{}
+}
+
+// Register implements the Keyword interface for ContentSchema
+func (c *ContentSchema) Register(uri string, registry *SchemaRegistry) {
+	(*Schema)(c).Register(uri, registry)
+}
+
+// Resolve implements the Keyword interface for ContentSchema
+func (c *ContentSchema) Resolve(pointer jptr.Pointer, uri string) *Schema {
+	return (*Schema)(c).Resolve(pointer, uri)
+}
+
+// ValidateKeyword implements the Keyword interface for ContentSchema
+func (c *ContentSchema) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+	schemaDebug("[ContentSchema] Validating")
+	if _, ok := data.(string); !ok {
+		return
+	}
+	if currentState.Local != nil && !currentState.Local.HasKeyword("contentMediaType") {
+		// contentSchema only applies along with contentMediaType
+		return
+	}
+	currentState.AddAnnotation((*Schema)(c))
+	if ContentModeFromContext(ctx) != ContentAssert {
+		return
+	}
+
+	doc, ok := currentState.Misc["contentDocument"]
+	if !ok {
+		return
+	}
+	subState := currentState.NewSubState()
+	subState.ClearState()
+	subState.DescendBase("contentSchema")
+	subState.DescendRelative("contentSchema")
+	(*Schema)(c).ValidateKeyword(ctx, subState, doc)
+}
+
+// JSONProp implements the JSONPather for ContentSchema
+func (c ContentSchema) JSONProp(name string) interface{} {
+	return Schema(c).JSONProp(name)
+}
+
+// JSONChildren implements the JSONContainer interface for ContentSchema
+func (c ContentSchema) JSONChildren() (res map[string]JSONPather) {
+	return Schema(c).JSONChildren()
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for ContentSchema
+func (c *ContentSchema) UnmarshalJSON(data []byte) error {
+	return c.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for ContentSchema
+func (c *ContentSchema) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	sch, err := unmarshalSchema(data, registry)
+	if err != nil {
+		return err
+	}
+	*c = ContentSchema(*sch)
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for ContentSchema
+func (c ContentSchema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Schema(c))
+}
@@ -3,9 +3,11 @@ package jsonschema
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
+	"sync"
 
 	jptr "github.com/qri-io/jsonpointer"
 )
@@ -53,6 +55,27 @@ func (i *ID) Resolve(pointer jptr.Pointer, uri string) *Schema {
 	return nil
 }
 
+// Vocabularies defines the $vocabulary JSON Schema keyword
+type Vocabularies map[string]bool
+
+// NewVocabularies allocates a new Vocabularies keyword
+func NewVocabularies() Keyword {
+	return &Vocabularies{}
+}
+
+// ValidateKeyword implements the Keyword interface for Vocabularies
+func (v *Vocabularies) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+	schemaDebug("[Vocabularies] Validating")
+}
+
+// Register implements the Keyword interface for Vocabularies
+func (v *Vocabularies) Register(uri string, registry *SchemaRegistry) {}
+
+// Resolve implements the Keyword interface for Vocabularies
+func (v *Vocabularies) Resolve(pointer jptr.Pointer, uri string) *Schema {
+	return nil
+}
+
 // Description defines the description JSON Schema keyword
 type Description string
 
@@ -64,6 +87,7 @@ func NewDescription() Keyword {
 // ValidateKeyword implements the Keyword interface for Description
 func (d *Description) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Description] Validating")
+	currentState.AddAnnotation(string(*d))
 }
 
 // Register implements the Keyword interface for Description
@@ -85,6 +109,7 @@ func NewTitle() Keyword {
 // ValidateKeyword implements the Keyword interface for Title
 func (t *Title) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Title] Validating")
+	currentState.AddAnnotation(string(*t))
 }
 
 // Register implements the Keyword interface for Title
@@ -103,7 +128,8 @@ func NewComment() Keyword {
 	return new(Comment)
 }
 
-// ValidateKeyword implements the Keyword interface for Comment
+// ValidateKeyword implements the Keyword interface for Comment. Comments
+// are meant for schema authors and produce no annotation
 func (c *Comment) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Comment] Validating")
 }
@@ -129,6 +155,7 @@ func NewDefault() Keyword {
 // ValidateKeyword implements the Keyword interface for Default
 func (d *Default) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Default] Validating")
+	currentState.AddAnnotation(d.data)
 }
 
 // Register implements the Keyword interface for Default
@@ -151,6 +178,11 @@ func (d *Default) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalJSON implements the json.Marshaler interface for Default
+func (d Default) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.data)
+}
+
 // Examples defines the examples JSON Schema keyword
 type Examples []interface{}
 
@@ -162,6 +194,7 @@ func NewExamples() Keyword {
 // ValidateKeyword implements the Keyword interface for Examples
 func (e *Examples) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Examples] Validating")
+	currentState.AddAnnotation([]interface{}(*e))
 }
 
 // Register implements the Keyword interface for Examples
@@ -183,6 +216,7 @@ func NewReadOnly() Keyword {
 // ValidateKeyword implements the Keyword interface for ReadOnly
 func (r *ReadOnly) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[ReadOnly] Validating")
+	currentState.AddAnnotation(bool(*r))
 }
 
 // Register implements the Keyword interface for ReadOnly
@@ -204,6 +238,7 @@ func NewWriteOnly() Keyword {
 // ValidateKeyword implements the Keyword interface for WriteOnly
 func (w *WriteOnly) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[WriteOnly] Validating")
+	currentState.AddAnnotation(bool(*w))
 }
 
 // Register implements the Keyword interface for WriteOnly
@@ -216,11 +251,54 @@ func (w *WriteOnly) Resolve(pointer jptr.Pointer, uri string) *Schema {
 
 // Ref defines the $ref JSON Schema keyword
 type Ref struct {
+	reference   string
+	resolutions *refResolutions
+}
+
+// refResolution is the outcome of resolving a reference keyword with a
+// schema registry
+type refResolution struct {
 	reference         string
 	resolved          *Schema
 	resolvedRoot      *Schema
 	resolvedFragment  *jptr.Pointer
 	fragmentLocalized bool
+	// resolveErr holds the error of fetching the referenced schema
+	resolveErr error
+}
+
+// refResolutions keeps the resolutions of a reference keyword apart for
+// each schema registry it is resolved with, as the same schema may be
+// validated with different registries, see WithSchemaRegistry. Failed
+// resolutions aren't kept, they are retried
+type refResolutions struct {
+	lock       sync.RWMutex
+	byRegistry map[*SchemaRegistry]*refResolution
+}
+
+// newRefResolutions creates an empty refResolutions
+func newRefResolutions() *refResolutions {
+	return &refResolutions{byRegistry: map[*SchemaRegistry]*refResolution{}}
+}
+
+// get returns the resolution made with registry, if any
+func (rs *refResolutions) get(registry *SchemaRegistry) *refResolution {
+	if rs == nil {
+		return nil
+	}
+	rs.lock.RLock()
+	defer rs.lock.RUnlock()
+	return rs.byRegistry[registry]
+}
+
+// put keeps a successful resolution made with registry
+func (rs *refResolutions) put(registry *SchemaRegistry, res *refResolution) {
+	if rs == nil || res.resolved == nil {
+		return
+	}
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	rs.byRegistry[registry] = res
 }
 
 // NewRef allocates a new Ref keyword
@@ -231,31 +309,53 @@ func NewRef() Keyword {
 // ValidateKeyword implements the Keyword interface for Ref
 func (r *Ref) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Ref] Validating")
-	if r.resolved == nil {
-		r._resolveRef(ctx, currentState)
-		if r.resolved == nil {
-			currentState.AddError(data, fmt.Sprintf("failed to resolve schema for ref %s", r.reference))
-		}
+	res := r.resolve(ctx, currentState)
+	if res.resolved == nil {
+		currentState.AddError(data, unresolvedRefMessage(r.reference, res.resolveErr))
 	}
 
 	subState := currentState.NewSubState()
 	subState.ClearState()
-	if r.resolvedRoot != nil {
-		subState.BaseURI = r.resolvedRoot.docPath
-		subState.Root = r.resolvedRoot
-	}
-	if r.resolvedFragment != nil && !r.resolvedFragment.IsEmpty() {
-		subState.BaseRelativeLocation = r.resolvedFragment
+	if res.resolvedRoot != nil {
+		subState.BaseURI = res.resolvedRoot.docPath
+		subState.Root = res.resolvedRoot
 	}
+	subState.BaseRelativeLocation = res.baseRelativeLocation()
 	subState.DescendRelative("$ref")
 
-	r.resolved.ValidateKeyword(ctx, subState, data)
+	res.resolved.ValidateKeyword(ctx, subState, data)
 
 	currentState.UpdateEvaluatedPropsAndItems(subState)
 }
 
+// unresolvedRefMessage describes a reference that failed to resolve
+func unresolvedRefMessage(reference string, err error) string {
+	var unresolvable *UnresolvableReferenceError
+	if errors.As(err, &unresolvable) {
+		return unresolvable.Error()
+	}
+	var policyErr *PolicyError
+	if errors.As(err, &policyErr) {
+		return fmt.Sprintf("failed to resolve schema for ref %s: %s", reference, policyErr.Error())
+	}
+	return fmt.Sprintf("failed to resolve schema for ref %s", reference)
+}
+
+// resolve returns the resolution of the reference with the registry of
+// the current state, resolving it unless it already was
+func (r *Ref) resolve(ctx context.Context, currentState *ValidationState) *refResolution {
+	registry := currentState.registry()
+	if res := r.resolutions.get(registry); res != nil {
+		return res
+	}
+	res := &refResolution{reference: r.reference}
+	res._resolveRef(ctx, currentState)
+	r.resolutions.put(registry, res)
+	return res
+}
+
 // _resolveRef attempts to resolve the reference from the top-level context
-func (r *Ref) _resolveRef(ctx context.Context, currentState *ValidationState) {
+func (r *refResolution) _resolveRef(ctx context.Context, currentState *ValidationState) {
 	if IsLocalSchemaID(r.reference) {
 		r.resolved = currentState.LocalRegistry.GetLocal(r.reference)
 		if r.resolved != nil {
@@ -263,6 +363,15 @@ func (r *Ref) _resolveRef(ctx context.Context, currentState *ValidationState) {
 		}
 	}
 
+	if u, err := url.Parse(r.reference); err == nil && u.IsAbs() {
+		// an absolute reference can directly identify a known schema
+		// including ones identified by an $id with a plain name fragment
+		if knownSchema := currentState.registry().GetKnown(r.reference); knownSchema != nil {
+			r.resolved = knownSchema
+			return
+		}
+	}
+
 	docPath := currentState.BaseURI
 	refParts := strings.Split(r.reference, "#")
 	address := ""
@@ -290,8 +399,8 @@ func (r *Ref) _resolveRef(ctx context.Context, currentState *ValidationState) {
 
 	if address != "" {
 		if u, err := url.Parse(address); err == nil {
+			// the base URI already accounts for an $id of the local schema
 			if !u.IsAbs() {
-				address = currentState.Local.id + address
 				if docPath != "" {
 					uriFolder := ""
 					if docPath[len(docPath)-1] == '/' {
@@ -306,7 +415,7 @@ func (r *Ref) _resolveRef(ctx context.Context, currentState *ValidationState) {
 				}
 			}
 		}
-		r.resolvedRoot = GetSchemaRegistry().Get(ctx, address)
+		r.resolvedRoot, r.resolveErr = currentState.registry().fetch(ctx, address, currentState.rootURI())
 	} else {
 		r.resolvedRoot = currentState.Root
 	}
@@ -315,7 +424,7 @@ func (r *Ref) _resolveRef(ctx context.Context, currentState *ValidationState) {
 		return
 	}
 
-	knownSchema := GetSchemaRegistry().GetKnown(r.reference)
+	knownSchema := currentState.registry().GetKnown(r.reference)
 	if knownSchema != nil {
 		r.resolved = knownSchema
 		return
@@ -337,7 +446,7 @@ func (r *Ref) _resolveRef(ctx context.Context, currentState *ValidationState) {
 }
 
 // _resolveLocalRef attempts to resolve the reference from a local context
-func (r *Ref) _resolveLocalRef(uri string) {
+func (r *refResolution) _resolveLocalRef(uri string) {
 	if r.resolvedFragment.IsEmpty() {
 		r.resolved = r.resolvedRoot
 		return
@@ -364,7 +473,8 @@ func (r *Ref) UnmarshalJSON(data []byte) error {
 	}
 	normalizedRef, _ := url.QueryUnescape(ref)
 	*r = Ref{
-		reference: normalizedRef,
+		reference:   normalizedRef,
+		resolutions: newRefResolutions(),
 	}
 	return nil
 }
@@ -374,14 +484,20 @@ func (r Ref) MarshalJSON() ([]byte, error) {
 	return json.Marshal(r.reference)
 }
 
+// baseRelativeLocation returns the location of the resolved schema
+// relative to its document. Plain name fragments don't map to a pointer
+// and yield the document root
+func (r *refResolution) baseRelativeLocation() *jptr.Pointer {
+	if r.resolvedFragment == nil || r.fragmentLocalized {
+		return &jptr.Pointer{}
+	}
+	return r.resolvedFragment
+}
+
 // RecursiveRef defines the $recursiveRef JSON Schema keyword
 type RecursiveRef struct {
-	reference        string
-	resolved         *Schema
-	resolvedRoot     *Schema
-	resolvedFragment *jptr.Pointer
-
-	validatingLocations map[string]bool
+	reference   string
+	resolutions *refResolutions
 }
 
 // NewRecursiveRef allocates a new RecursiveRef keyword
@@ -392,64 +508,57 @@ func NewRecursiveRef() Keyword {
 // ValidateKeyword implements the Keyword interface for RecursiveRef
 func (r *RecursiveRef) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[RecursiveRef] Validating")
-	if r.isLocationVisited(currentState.InstanceLocation.String()) {
+	if !currentState.enterRecursion(r) {
 		// recursion detected aborting further descent
 		return
 	}
+	defer currentState.exitRecursion(r)
 
-	if r.resolved == nil {
-		r._resolveRef(ctx, currentState)
-		if r.resolved == nil {
-			currentState.AddError(data, fmt.Sprintf("failed to resolve schema for ref %s", r.reference))
-		}
+	res := r.resolve(ctx, currentState)
+	if res.resolved == nil {
+		currentState.AddError(data, unresolvedRefMessage(r.reference, res.resolveErr))
 	}
 
 	subState := currentState.NewSubState()
 	subState.ClearState()
-	if r.resolvedRoot != nil {
-		subState.BaseURI = r.resolvedRoot.docPath
-		subState.Root = r.resolvedRoot
-	}
-	if r.resolvedFragment != nil && !r.resolvedFragment.IsEmpty() {
-		subState.BaseRelativeLocation = r.resolvedFragment
+	if res.resolvedRoot != nil {
+		subState.BaseURI = res.resolvedRoot.docPath
+		subState.Root = res.resolvedRoot
 	}
+	subState.BaseRelativeLocation = res.baseRelativeLocation()
 	subState.DescendRelative("$recursiveRef")
 
-	if r.validatingLocations == nil {
-		r.validatingLocations = map[string]bool{}
+	target := res.resolved
+	if currentState.RecursiveAnchor != nil && target != nil && target.HasKeyword("$recursiveAnchor") {
+		// a target declaring $recursiveAnchor defers to the outermost
+		// schema of the dynamic scope declaring it
+		target = currentState.RecursiveAnchor
+		subState.BaseURI = target.docPath
+		subState.Root = target
+		subState.BaseRelativeLocation = &jptr.Pointer{}
 	}
 
-	r.validatingLocations[currentState.InstanceLocation.String()] = true
-	r.resolved.ValidateKeyword(ctx, subState, data)
-	r.validatingLocations[currentState.InstanceLocation.String()] = false
+	target.ValidateKeyword(ctx, subState, data)
 
 	currentState.UpdateEvaluatedPropsAndItems(subState)
 }
 
-func (r *RecursiveRef) isLocationVisited(location string) bool {
-	if r.validatingLocations == nil {
-		return false
-	}
-	v, ok := r.validatingLocations[location]
-	if !ok {
-		return false
+// resolve returns the resolution of the reference with the registry of
+// the current state, resolving it unless it already was
+func (r *RecursiveRef) resolve(ctx context.Context, currentState *ValidationState) *refResolution {
+	registry := currentState.registry()
+	if res := r.resolutions.get(registry); res != nil {
+		return res
 	}
-	return v
+	res := &refResolution{reference: r.reference}
+	res._resolveRecursiveRef(ctx, currentState)
+	r.resolutions.put(registry, res)
+	return res
 }
 
-// _resolveRef attempts to resolve the reference from the top-level context
-func (r *RecursiveRef) _resolveRef(ctx context.Context, currentState *ValidationState) {
-	if currentState.RecursiveAnchor != nil {
-		if currentState.BaseURI == "" {
-			currentState.AddError(nil, fmt.Sprintf("base uri not set"))
-			return
-		}
-		baseSchema := GetSchemaRegistry().Get(ctx, currentState.BaseURI)
-		if baseSchema != nil && baseSchema.HasKeyword("$recursiveAnchor") {
-			r.resolvedRoot = currentState.RecursiveAnchor
-		}
-	}
-
+// _resolveRecursiveRef attempts to resolve the $recursiveRef reference
+// from the top-level context
+func (r *refResolution) _resolveRecursiveRef(ctx context.Context, currentState *ValidationState) {
 	if IsLocalSchemaID(r.reference) {
 		r.resolved = currentState.LocalRegistry.GetLocal(r.reference)
 		if r.resolved != nil {
@@ -501,7 +610,7 @@ func (r *RecursiveRef) _resolveRef(ctx context.Context, currentState *Validation
 					}
 				}
 			}
-			r.resolvedRoot = GetSchemaRegistry().Get(ctx, address)
+			r.resolvedRoot, r.resolveErr = currentState.registry().fetch(ctx, address, currentState.rootURI())
 		} else {
 			r.resolvedRoot = currentState.Root
 		}
@@ -511,7 +620,7 @@ func (r *RecursiveRef) _resolveRef(ctx context.Context, currentState *Validation
 		return
 	}
 
-	knownSchema := GetSchemaRegistry().GetKnown(r.reference)
+	knownSchema := currentState.registry().GetKnown(r.reference)
 	if knownSchema != nil {
 		r.resolved = knownSchema
 		return
@@ -524,18 +633,6 @@ func (r *RecursiveRef) _resolveRef(ctx context.Context, currentState *Validation
 	r._resolveLocalRef(localURI)
 }
 
-// _resolveLocalRef attempts to resolve the reference from a local context
-func (r *RecursiveRef) _resolveLocalRef(uri string) {
-	if r.resolvedFragment.IsEmpty() {
-		r.resolved = r.resolvedRoot
-		return
-	}
-
-	if r.resolvedRoot != nil {
-		r.resolved = r.resolvedRoot.Resolve(*r.resolvedFragment, uri)
-	}
-}
-
 // Register implements the Keyword interface for RecursiveRef
 func (r *RecursiveRef) Register(uri string, registry *SchemaRegistry) {}
 
@@ -551,7 +648,8 @@ func (r *RecursiveRef) UnmarshalJSON(data []byte) error {
 		return err
 	}
 	*r = RecursiveRef{
-		reference: ref,
+		reference:   ref,
+		resolutions: newRefResolutions(),
 	}
 	return nil
 }
@@ -582,6 +680,131 @@ func (a *Anchor) Resolve(pointer jptr.Pointer, uri string) *Schema {
 	return nil
 }
 
+// DynamicRef defines the $dynamicRef JSON Schema keyword
+type DynamicRef struct {
+	ref    Ref
+	anchor string
+}
+
+// NewDynamicRef allocates a new DynamicRef keyword
+func NewDynamicRef() Keyword {
+	return new(DynamicRef)
+}
+
+// ValidateKeyword implements the Keyword interface for DynamicRef
+func (r *DynamicRef) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+	schemaDebug("[DynamicRef] Validating")
+	res := r.resolve(ctx, currentState)
+	if res.resolved == nil {
+		currentState.AddError(data, unresolvedRefMessage(r.ref.reference, res.resolveErr))
+		return
+	}
+
+	subState := currentState.NewSubState()
+	subState.ClearState()
+	if res.resolvedRoot != nil {
+		subState.BaseURI = res.resolvedRoot.docPath
+		subState.Root = res.resolvedRoot
+	}
+	subState.BaseRelativeLocation = res.baseRelativeLocation()
+	subState.DescendRelative("$dynamicRef")
+
+	target := res.resolved
+	if r.anchor != "" && target.dynamicAnchor() == r.anchor {
+		// the outermost schema resource in the dynamic scope declaring
+		// the same dynamic anchor takes precedence
+		for _, uri := range currentState.DynamicScope {
+			if sch := currentState.knownSchema(uri + "#" + r.anchor); sch != nil && sch.dynamicAnchor() == r.anchor {
+				target = sch
+				subState.BaseURI = uri
+				if root := currentState.knownSchema(uri); root != nil {
+					subState.Root = root
+				}
+				break
+			}
+		}
+	}
+
+	target.ValidateKeyword(ctx, subState, data)
+
+	currentState.UpdateEvaluatedPropsAndItems(subState)
+}
+
+// resolve returns the resolution of the initial target of the reference
+// with the registry of the current state. The target is resolved relative
+// to the current base URI before falling back to $ref semantics
+func (r *DynamicRef) resolve(ctx context.Context, currentState *ValidationState) *refResolution {
+	registry := currentState.registry()
+	if res := r.ref.resolutions.get(registry); res != nil {
+		return res
+	}
+	res := &refResolution{reference: r.ref.reference}
+	if currentState.BaseURI != "" {
+		if address, err := SafeResolveURL(currentState.BaseURI, r.ref.reference); err == nil {
+			if sch := registry.GetKnown(address); sch != nil {
+				res.resolved = sch
+				res.resolvedRoot = registry.GetKnown(strings.Split(address, "#")[0])
+				res.resolvedFragment = &jptr.Pointer{}
+			}
+		}
+	}
+	if res.resolved == nil {
+		res._resolveRef(ctx, currentState)
+	}
+	r.ref.resolutions.put(registry, res)
+	return res
+}
+
+// Register implements the Keyword interface for DynamicRef
+func (r *DynamicRef) Register(uri string, registry *SchemaRegistry) {}
+
+// Resolve implements the Keyword interface for DynamicRef
+func (r *DynamicRef) Resolve(pointer jptr.Pointer, uri string) *Schema {
+	return nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for DynamicRef
+func (r *DynamicRef) UnmarshalJSON(data []byte) error {
+	ref := Ref{}
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return err
+	}
+	*r = DynamicRef{ref: ref}
+	// only plain name fragments are subject to dynamic resolution
+	if i := strings.Index(ref.reference, "#"); i >= 0 {
+		if frag := ref.reference[i+1:]; frag != "" && frag[0] != '/' {
+			r.anchor = frag
+		}
+	}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for DynamicRef
+func (r DynamicRef) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.ref.reference)
+}
+
+// DynamicAnchor defines the $dynamicAnchor JSON Schema keyword
+type DynamicAnchor string
+
+// NewDynamicAnchor allocates a new DynamicAnchor keyword
+func NewDynamicAnchor() Keyword {
+	return new(DynamicAnchor)
+}
+
+// ValidateKeyword implements the Keyword interface for DynamicAnchor
+func (a *DynamicAnchor) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+	schemaDebug("[DynamicAnchor] Validating")
+}
+
+// Register implements the Keyword interface for DynamicAnchor
+func (a *DynamicAnchor) Register(uri string, registry *SchemaRegistry) {}
+
+// Resolve implements the Keyword interface for DynamicAnchor
+func (a *DynamicAnchor) Resolve(pointer jptr.Pointer, uri string) *Schema {
+	return nil
+}
+
 // RecursiveAnchor defines the $recursiveAnchor JSON Schema keyword
 type RecursiveAnchor Schema
 
@@ -610,14 +833,24 @@ func (r *RecursiveAnchor) ValidateKeyword(ctx context.Context, currentState *Val
 
 // UnmarshalJSON implements the json.Unmarshaler interface for RecursiveAnchor
 func (r *RecursiveAnchor) UnmarshalJSON(data []byte) error {
-	sch := &Schema{}
-	if err := json.Unmarshal(data, sch); err != nil {
+	return r.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for RecursiveAnchor
+func (r *RecursiveAnchor) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	sch, err := unmarshalSchema(data, registry)
+	if err != nil {
 		return err
 	}
-	*r = (RecursiveAnchor)(*sch)
+	*r = RecursiveAnchor(*sch)
 	return nil
 }
 
+// MarshalJSON implements the json.Marshaler interface for RecursiveAnchor
+func (r RecursiveAnchor) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Schema(r))
+}
+
 // Defs defines the $defs JSON Schema keyword
 type Defs map[string]*Schema
 
@@ -669,6 +902,78 @@ func (d Defs) JSONChildren() (res map[string]JSONPather) {
 	return
 }
 
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for Defs
+func (d *Defs) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	schemas, err := unmarshalSchemaMap(data, registry)
+	if err != nil {
+		return err
+	}
+	*d = Defs(schemas)
+	return nil
+}
+
+// Definitions defines the definitions JSON Schema keyword
+// which was superseded by $defs in draft2019_09
+type Definitions map[string]*Schema
+
+// NewDefinitions allocates a new Definitions keyword
+func NewDefinitions() Keyword {
+	return &Definitions{}
+}
+
+// Register implements the Keyword interface for Definitions
+func (d *Definitions) Register(uri string, registry *SchemaRegistry) {
+	for _, v := range *d {
+		v.Register(uri, registry)
+	}
+}
+
+// Resolve implements the Keyword interface for Definitions
+func (d *Definitions) Resolve(pointer jptr.Pointer, uri string) *Schema {
+	if pointer == nil {
+		return nil
+	}
+	current := pointer.Head()
+	if current == nil {
+		return nil
+	}
+
+	if schema, ok := (*d)[*current]; ok {
+		return schema.Resolve(pointer.Tail(), uri)
+	}
+
+	return nil
+}
+
+// ValidateKeyword implements the Keyword interface for Definitions
+func (d Definitions) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+	schemaDebug("[Definitions] Validating")
+}
+
+// JSONProp implements the JSONPather for Definitions
+func (d Definitions) JSONProp(name string) interface{} {
+	return d[name]
+}
+
+// JSONChildren implements the JSONContainer interface for Definitions
+func (d Definitions) JSONChildren() (res map[string]JSONPather) {
+	res = map[string]JSONPather{}
+	for key, sch := range d {
+		res[key] = sch
+	}
+	return
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for Definitions
+func (d *Definitions) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	schemas, err := unmarshalSchemaMap(data, registry)
+	if err != nil {
+		return err
+	}
+	*d = Definitions(schemas)
+	return nil
+}
+
 // Void is a placeholder definition for a keyword
 type Void struct{}
 
@@ -1,14 +1,22 @@
 package jsonschema
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
 
 	jptr "github.com/qri-io/jsonpointer"
 )
 
 // MultipleOf defines the multipleOf JSON Schema keyword
-type MultipleOf float64
+type MultipleOf struct {
+	numericLimit
+}
 
 // NewMultipleOf allocates a new MultipleOf keyword
 func NewMultipleOf() Keyword {
@@ -26,16 +34,48 @@ func (m *MultipleOf) Resolve(pointer jptr.Pointer, uri string) *Schema {
 // ValidateKeyword implements the Keyword interface for MultipleOf
 func (m MultipleOf) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[MultipleOf] Validating")
-	if num, ok := convertNumberToFloat(data); ok {
-		div := num / float64(m)
-		if float64(int(div)) != div {
-			currentState.AddError(data, fmt.Sprintf("must be a multiple of %v", m))
+	if m.isInt && m.intVal != 0 {
+		if n, ok := instanceInt64(data); ok {
+			if n%m.intVal != 0 {
+				currentState.AddError(data, fmt.Sprintf("must be a multiple of %v", m))
+			}
+			return
+		}
+	}
+	num, ok := convertNumberToRat(data)
+	if !ok {
+		if err := numberError(data); err != nil {
+			currentState.AddError(data, err.Error())
 		}
+		return
+	}
+	if m.rat == nil || m.rat.Sign() == 0 {
+		return
+	}
+	if !num.Quo(num, m.rat).IsInt() {
+		currentState.AddError(data, fmt.Sprintf("must be a multiple of %v", m))
 	}
 }
 
+// UnmarshalJSON implements the json.Unmarshaler interface for MultipleOf
+func (m *MultipleOf) UnmarshalJSON(data []byte) error {
+	limit, err := parseNumericLimit(data)
+	if err != nil {
+		return err
+	}
+	*m = MultipleOf{limit}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for MultipleOf
+func (m MultipleOf) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.num)
+}
+
 // Maximum defines the maximum JSON Schema keyword
-type Maximum float64
+type Maximum struct {
+	numericLimit
+}
 
 // NewMaximum allocates a new Maximum keyword
 func NewMaximum() Keyword {
@@ -53,15 +93,33 @@ func (m *Maximum) Resolve(pointer jptr.Pointer, uri string) *Schema {
 // ValidateKeyword implements the Keyword interface for Maximum
 func (m Maximum) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Maximum] Validating")
-	if num, ok := convertNumberToFloat(data); ok {
-		if num > float64(m) {
-			currentState.AddError(data, fmt.Sprintf("must be less than or equal to %v", m))
-		}
+	cmp, ok, err := m.compare(data)
+	if err != nil {
+		currentState.AddError(data, err.Error())
+	} else if ok && cmp > 0 {
+		currentState.AddError(data, fmt.Sprintf("must be less than or equal to %v", m))
 	}
 }
 
+// UnmarshalJSON implements the json.Unmarshaler interface for Maximum
+func (m *Maximum) UnmarshalJSON(data []byte) error {
+	limit, err := parseNumericLimit(data)
+	if err != nil {
+		return err
+	}
+	*m = Maximum{limit}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for Maximum
+func (m Maximum) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.num)
+}
+
 // ExclusiveMaximum defines the exclusiveMaximum JSON Schema keyword
-type ExclusiveMaximum float64
+type ExclusiveMaximum struct {
+	numericLimit
+}
 
 // NewExclusiveMaximum allocates a new ExclusiveMaximum keyword
 func NewExclusiveMaximum() Keyword {
@@ -79,15 +137,33 @@ func (m *ExclusiveMaximum) Resolve(pointer jptr.Pointer, uri string) *Schema {
 // ValidateKeyword implements the Keyword interface for ExclusiveMaximum
 func (m ExclusiveMaximum) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[ExclusiveMaximum] Validating")
-	if num, ok := convertNumberToFloat(data); ok {
-		if num >= float64(m) {
-			currentState.AddError(data, fmt.Sprintf("%v must be less than %v", num, m))
-		}
+	cmp, ok, err := m.compare(data)
+	if err != nil {
+		currentState.AddError(data, err.Error())
+	} else if ok && cmp >= 0 {
+		currentState.AddError(data, fmt.Sprintf("%v must be less than %v", data, m))
 	}
 }
 
+// UnmarshalJSON implements the json.Unmarshaler interface for ExclusiveMaximum
+func (m *ExclusiveMaximum) UnmarshalJSON(data []byte) error {
+	limit, err := parseNumericLimit(data)
+	if err != nil {
+		return err
+	}
+	*m = ExclusiveMaximum{limit}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for ExclusiveMaximum
+func (m ExclusiveMaximum) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.num)
+}
+
 // Minimum defines the minimum JSON Schema keyword
-type Minimum float64
+type Minimum struct {
+	numericLimit
+}
 
 // NewMinimum allocates a new Minimum keyword
 func NewMinimum() Keyword {
@@ -105,15 +181,33 @@ func (m *Minimum) Resolve(pointer jptr.Pointer, uri string) *Schema {
 // ValidateKeyword implements the Keyword interface for Minimum
 func (m Minimum) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Minimum] Validating")
-	if num, ok := convertNumberToFloat(data); ok {
-		if num < float64(m) {
-			currentState.AddError(data, fmt.Sprintf("must be greater than or equal to %v", m))
-		}
+	cmp, ok, err := m.compare(data)
+	if err != nil {
+		currentState.AddError(data, err.Error())
+	} else if ok && cmp < 0 {
+		currentState.AddError(data, fmt.Sprintf("must be greater than or equal to %v", m))
+	}
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for Minimum
+func (m *Minimum) UnmarshalJSON(data []byte) error {
+	limit, err := parseNumericLimit(data)
+	if err != nil {
+		return err
 	}
+	*m = Minimum{limit}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for Minimum
+func (m Minimum) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.num)
 }
 
 // ExclusiveMinimum defines the exclusiveMinimum JSON Schema keyword
-type ExclusiveMinimum float64
+type ExclusiveMinimum struct {
+	numericLimit
+}
 
 // NewExclusiveMinimum allocates a new ExclusiveMinimum keyword
 func NewExclusiveMinimum() Keyword {
@@ -131,42 +225,343 @@ func (m *ExclusiveMinimum) Resolve(pointer jptr.Pointer, uri string) *Schema {
 // ValidateKeyword implements the Keyword interface for ExclusiveMinimum
 func (m ExclusiveMinimum) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[ExclusiveMinimum] Validating")
-	if num, ok := convertNumberToFloat(data); ok {
-		if num <= float64(m) {
-			currentState.AddError(data, fmt.Sprintf("%v must be greater than %v", num, m))
+	cmp, ok, err := m.compare(data)
+	if err != nil {
+		currentState.AddError(data, err.Error())
+	} else if ok && cmp <= 0 {
+		currentState.AddError(data, fmt.Sprintf("%v must be greater than %v", data, m))
+	}
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for ExclusiveMinimum
+func (m *ExclusiveMinimum) UnmarshalJSON(data []byte) error {
+	limit, err := parseNumericLimit(data)
+	if err != nil {
+		return err
+	}
+	*m = ExclusiveMinimum{limit}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for ExclusiveMinimum
+func (m ExclusiveMinimum) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.num)
+}
+
+// Draft4ExclusiveMaximum defines the boolean exclusiveMaximum JSON Schema
+// keyword of draft4 which turns a sibling maximum into an exclusive bound
+type Draft4ExclusiveMaximum bool
+
+// NewDraft4ExclusiveMaximum allocates a new Draft4ExclusiveMaximum keyword
+func NewDraft4ExclusiveMaximum() Keyword {
+	return new(Draft4ExclusiveMaximum)
+}
+
+// Register implements the Keyword interface for Draft4ExclusiveMaximum
+func (m *Draft4ExclusiveMaximum) Register(uri string, registry *SchemaRegistry) {}
+
+// Resolve implements the Keyword interface for Draft4ExclusiveMaximum
+func (m *Draft4ExclusiveMaximum) Resolve(pointer jptr.Pointer, uri string) *Schema {
+	return nil
+}
+
+// ValidateKeyword implements the Keyword interface for Draft4ExclusiveMaximum
+func (m Draft4ExclusiveMaximum) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+	schemaDebug("[Draft4ExclusiveMaximum] Validating")
+	if !m || currentState.Local == nil {
+		return
+	}
+	max, ok := currentState.Local.keywords["maximum"].(*Maximum)
+	if !ok {
+		return
+	}
+	// values above the maximum and numbers that can't be compared are
+	// already reported by the maximum keyword
+	if cmp, ok, err := max.compare(data); err == nil && ok && cmp == 0 {
+		currentState.AddError(data, fmt.Sprintf("%v must be less than %v", data, max))
+	}
+}
+
+// Draft4ExclusiveMinimum defines the boolean exclusiveMinimum JSON Schema
+// keyword of draft4 which turns a sibling minimum into an exclusive bound
+type Draft4ExclusiveMinimum bool
+
+// NewDraft4ExclusiveMinimum allocates a new Draft4ExclusiveMinimum keyword
+func NewDraft4ExclusiveMinimum() Keyword {
+	return new(Draft4ExclusiveMinimum)
+}
+
+// Register implements the Keyword interface for Draft4ExclusiveMinimum
+func (m *Draft4ExclusiveMinimum) Register(uri string, registry *SchemaRegistry) {}
+
+// Resolve implements the Keyword interface for Draft4ExclusiveMinimum
+func (m *Draft4ExclusiveMinimum) Resolve(pointer jptr.Pointer, uri string) *Schema {
+	return nil
+}
+
+// ValidateKeyword implements the Keyword interface for Draft4ExclusiveMinimum
+func (m Draft4ExclusiveMinimum) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+	schemaDebug("[Draft4ExclusiveMinimum] Validating")
+	if !m || currentState.Local == nil {
+		return
+	}
+	min, ok := currentState.Local.keywords["minimum"].(*Minimum)
+	if !ok {
+		return
+	}
+	// values below the minimum and numbers that can't be compared are
+	// already reported by the minimum keyword
+	if cmp, ok, err := min.compare(data); err == nil && ok && cmp == 0 {
+		currentState.AddError(data, fmt.Sprintf("%v must be greater than %v", data, min))
+	}
+}
+
+// numericLimit is the value of a numeric keyword, parsed once as the
+// schema is decoded
+type numericLimit struct {
+	// num is the number as written in the schema
+	num json.Number
+	rat *big.Rat
+	// intVal holds the number if isInt reports it is an integer in the
+	// range of int64, and floatVal if isFloat reports it is exactly a
+	// float64. Both spare common instances the conversion to a rational
+	intVal   int64
+	isInt    bool
+	floatVal float64
+	isFloat  bool
+}
+
+// parseNumericLimit decodes the value of a numeric keyword
+func parseNumericLimit(data []byte) (numericLimit, error) {
+	num, err := unmarshalNumber(data)
+	if err != nil {
+		return numericLimit{}, err
+	}
+	rat, err := parseRat(string(num))
+	if err != nil {
+		return numericLimit{}, err
+	}
+	l := numericLimit{num: num, rat: rat}
+	if rat.IsInt() && rat.Num().IsInt64() {
+		l.intVal, l.isInt = rat.Num().Int64(), true
+	}
+	l.floatVal, l.isFloat = rat.Float64()
+	return l, nil
+}
+
+// Number returns the value of the keyword as written in the schema
+func (l numericLimit) Number() json.Number {
+	return l.num
+}
+
+// String implements the fmt.Stringer interface for numericLimit
+func (l numericLimit) String() string {
+	return string(l.num)
+}
+
+// compare returns -1, 0 or +1 as the instance is less than, equal to or
+// greater than the limit, reporting false for instances that aren't
+// numbers. Numbers that can't be compared exactly are errors
+func (l numericLimit) compare(data interface{}) (int, bool, error) {
+	if l.isInt {
+		if n, ok := instanceInt64(data); ok {
+			return compareInt64(n, l.intVal), true, nil
+		}
+	}
+	if l.isFloat {
+		// a float differing from a limit that is exactly a float orders
+		// the same as the decimal it was parsed from or represents
+		var f float64
+		ok := false
+		switch v := data.(type) {
+		case float64:
+			f, ok = v, !math.IsNaN(v) && !math.IsInf(v, 0)
+		case json.Number:
+			var err error
+			f, err = strconv.ParseFloat(string(v), 64)
+			ok = err == nil
+		}
+		if ok && f < l.floatVal {
+			return -1, true, nil
 		}
+		if ok && f > l.floatVal {
+			return 1, true, nil
+		}
+	}
+
+	num, ok := convertNumberToRat(data)
+	if !ok {
+		return 0, false, numberError(data)
 	}
+	return num.Cmp(l.rat), true, nil
 }
 
-func convertNumberToFloat(data interface{}) (float64, bool) {
+// instanceInt64 returns integer instances within the range of int64,
+// including floats holding integers exactly represented by their
+// shortest decimal
+func instanceInt64(data interface{}) (int64, bool) {
 	switch v := data.(type) {
-	case uint:
-		return float64(v), true
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
 	case uint8:
-		return float64(v), true
+		return int64(v), true
 	case uint16:
-		return float64(v), true
+		return int64(v), true
 	case uint32:
-		return float64(v), true
+		return int64(v), true
+	case uint:
+		return int64(v), v <= math.MaxInt64
 	case uint64:
-		return float64(v), true
+		return int64(v), v <= math.MaxInt64
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) <= 1<<53 {
+			return int64(v), true
+		}
+	case json.Number:
+		if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// compareInt64 returns -1, 0 or +1 as a is less than, equal to or greater
+// than b
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// unmarshalNumber decodes the value of a numeric keyword, keeping the
+// number as written in the schema
+func unmarshalNumber(data []byte) (json.Number, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '"' {
+		return "", fmt.Errorf("expected a number. got: %s", trimmed)
+	}
+	var num json.Number
+	if err := json.Unmarshal(data, &num); err != nil {
+		return "", err
+	}
+	return num, nil
+}
+
+// maxExactExponent bounds the decimal exponent of numbers converted to
+// exact rationals, as their size grows with the exponent
+const maxExactExponent = 10000
+
+// convertNumberToRat converts numeric instances to exact rationals.
+// Floats are converted from the shortest decimal representing them, so
+// 0.1 converts to 1/10 rather than the binary fraction closest to it.
+// json.Number instances that can't be converted are reported by
+// numberError
+func convertNumberToRat(data interface{}) (*big.Rat, bool) {
+	switch v := data.(type) {
+	case json.Number:
+		r, err := parseRat(string(v))
+		return r, err == nil
+	case *big.Int:
+		if v == nil {
+			return nil, false
+		}
+		return new(big.Rat).SetInt(v), true
+	case *big.Float:
+		if v == nil || v.IsInf() {
+			return nil, false
+		}
+		r, _ := v.Rat(nil)
+		return r, true
+	case *big.Rat:
+		if v == nil {
+			return nil, false
+		}
+		return new(big.Rat).Set(v), true
+	case float32:
+		return convertFloatToRat(float64(v), 32)
+	case float64:
+		return convertFloatToRat(v, 64)
 	case int:
-		return float64(v), true
+		return big.NewRat(int64(v), 1), true
 	case int8:
-		return float64(v), true
+		return big.NewRat(int64(v), 1), true
 	case int16:
-		return float64(v), true
+		return big.NewRat(int64(v), 1), true
 	case int32:
-		return float64(v), true
+		return big.NewRat(int64(v), 1), true
 	case int64:
-		return float64(v), true
-	case float32:
-		return float64(v), true
-	case float64:
-		return float64(v), true
+		return big.NewRat(v, 1), true
+	case uint:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(uint64(v))), true
+	case uint8:
+		return big.NewRat(int64(v), 1), true
+	case uint16:
+		return big.NewRat(int64(v), 1), true
+	case uint32:
+		return big.NewRat(int64(v), 1), true
+	case uint64:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(v)), true
 	case uintptr:
-		return float64(v), true
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(uint64(v))), true
 	}
+	return nil, false
+}
 
-	return 0, false
+// numberError returns the error converting a json.Number instance to an
+// exact rational, nil for any other instance
+func numberError(data interface{}) error {
+	if num, ok := data.(json.Number); ok {
+		_, err := parseRat(string(num))
+		return err
+	}
+	return nil
+}
+
+// convertFloatToRat converts a float of the given bit size through the
+// shortest decimal representing it
+func convertFloatToRat(f float64, bitSize int) (*big.Rat, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, bitSize))
+}
+
+// parseRat parses a JSON number into an exact rational. Numbers with an
+// exponent beyond maxExactExponent are errors, except for zero
+func parseRat(s string) (*big.Rat, error) {
+	if exponentOutOfRange(s) {
+		i := strings.IndexAny(s, "eE")
+		mantissa, ok := new(big.Rat).SetString(s[:i])
+		if ok && mantissa.Sign() == 0 && strings.TrimLeft(s[i+1:], "+-0123456789") == "" {
+			return mantissa, nil
+		}
+		return nil, fmt.Errorf("cannot compare %s: exponent is out of range", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %s", s)
+	}
+	return r, nil
+}
+
+// exponentOutOfRange reports whether a JSON number has an exponent beyond
+// maxExactExponent
+func exponentOutOfRange(s string) bool {
+	i := strings.IndexAny(s, "eE")
+	if i < 0 {
+		return false
+	}
+	exp, err := strconv.Atoi(strings.TrimPrefix(s[i+1:], "+"))
+	return err != nil || exp > maxExactExponent || exp < -maxExactExponent
 }
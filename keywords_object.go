@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"regexp"
 	"strconv"
 
 	jptr "github.com/qri-io/jsonpointer"
@@ -58,9 +57,6 @@ func (p Properties) ValidateKeyword(ctx context.Context, currentState *Validatio
 				subState.Errs = &[]KeyError{}
 				p[key].ValidateKeyword(ctx, subState, obj[key])
 				currentState.AddSubErrors(*subState.Errs...)
-				if subState.IsValid() {
-					currentState.UpdateEvaluatedPropsAndItems(subState)
-				}
 			}
 		}
 	}
@@ -80,6 +76,16 @@ func (p Properties) JSONChildren() (res map[string]JSONPather) {
 	return
 }
 
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for Properties
+func (p *Properties) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	schemas, err := unmarshalSchemaMap(data, registry)
+	if err != nil {
+		return err
+	}
+	*p = Properties(schemas)
+	return nil
+}
+
 // Required defines the required JSON Schema keyword
 type Required []string
 
@@ -182,7 +188,7 @@ func NewPatternProperties() Keyword {
 
 type patternSchema struct {
 	key    string
-	re     *regexp.Regexp
+	re     Regexp
 	schema *Schema
 }
 
@@ -221,20 +227,22 @@ func (p PatternProperties) ValidateKeyword(ctx context.Context, currentState *Va
 	if obj, ok := data.(map[string]interface{}); ok {
 		for key, val := range obj {
 			for _, ptn := range p {
-				if ptn.re.Match([]byte(key)) {
+				matched, err := matchString(ptn.re, key)
+				if err != nil {
+					currentState.AddError(data, fmt.Sprintf("regexp pattern %s on property %s: %s", ptn.re.String(), key, err.Error()))
+					continue
+				}
+				if matched {
 					currentState.SetEvaluatedKey(key)
 					subState := currentState.NewSubState()
-					subState.DescendBase("patternProperties", key)
-					subState.DescendRelative("patternProperties", key)
+					subState.ClearState()
+					subState.DescendBase("patternProperties", ptn.key)
+					subState.DescendRelative("patternProperties", ptn.key)
 					subState.DescendInstance(key)
 
 					subState.Errs = &[]KeyError{}
 					ptn.schema.ValidateKeyword(ctx, subState, val)
 					currentState.AddSubErrors(*subState.Errs...)
-
-					if subState.IsValid() {
-						currentState.UpdateEvaluatedPropsAndItems(subState)
-					}
 				}
 			}
 		}
@@ -262,15 +270,20 @@ func (p PatternProperties) JSONChildren() (res map[string]JSONPather) {
 
 // UnmarshalJSON implements the json.Unmarshaler interface for PatternProperties
 func (p *PatternProperties) UnmarshalJSON(data []byte) error {
-	var props map[string]*Schema
-	if err := json.Unmarshal(data, &props); err != nil {
+	return p.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for PatternProperties
+func (p *PatternProperties) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	props, err := unmarshalSchemaMap(data, registry)
+	if err != nil {
 		return err
 	}
 
 	ptn := make(PatternProperties, len(props))
 	i := 0
 	for key, sch := range props {
-		re, err := regexp.Compile(key)
+		re, err := compilePattern(registry, key)
 		if err != nil {
 			return fmt.Errorf("invalid pattern: %s: %s", key, err.Error())
 		}
@@ -336,18 +349,22 @@ func (ap *AdditionalProperties) ValidateKeyword(ctx context.Context, currentStat
 			}
 
 			(*Schema)(ap).ValidateKeyword(ctx, subState, obj[key])
-			currentState.UpdateEvaluatedPropsAndItems(subState)
 		}
 	}
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface for AdditionalProperties
 func (ap *AdditionalProperties) UnmarshalJSON(data []byte) error {
-	sch := &Schema{}
-	if err := json.Unmarshal(data, sch); err != nil {
+	return ap.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for AdditionalProperties
+func (ap *AdditionalProperties) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	sch, err := unmarshalSchema(data, registry)
+	if err != nil {
 		return err
 	}
-	*ap = (AdditionalProperties)(*sch)
+	*ap = AdditionalProperties(*sch)
 	return nil
 }
 
@@ -400,11 +417,16 @@ func (p PropertyNames) JSONChildren() (res map[string]JSONPather) {
 
 // UnmarshalJSON implements the json.Unmarshaler interface for PropertyNames
 func (p *PropertyNames) UnmarshalJSON(data []byte) error {
-	var sch Schema
-	if err := json.Unmarshal(data, &sch); err != nil {
+	return p.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for PropertyNames
+func (p *PropertyNames) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	sch, err := unmarshalSchema(data, registry)
+	if err != nil {
 		return err
 	}
-	*p = PropertyNames(sch)
+	*p = PropertyNames(*sch)
 	return nil
 }
 
@@ -457,19 +479,21 @@ func (d *DependentSchemas) ValidateKeyword(ctx context.Context, currentState *Va
 	}
 }
 
-type _dependentSchemas map[string]Schema
-
 // UnmarshalJSON implements the json.Unmarshaler interface for DependentSchemas
 func (d *DependentSchemas) UnmarshalJSON(data []byte) error {
-	_d := _dependentSchemas{}
-	if err := json.Unmarshal(data, &_d); err != nil {
+	return d.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for DependentSchemas
+func (d *DependentSchemas) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	schemas, err := unmarshalSchemaMap(data, registry)
+	if err != nil {
 		return err
 	}
 	ds := DependentSchemas{}
-	for k, v := range _d {
-		sch := Schema(v)
+	for k, sch := range schemas {
 		ds[k] = SchemaDependency{
-			schema: &sch,
+			schema: sch,
 			prop:   k,
 		}
 	}
@@ -622,11 +646,11 @@ func (p *PropertyDependency) Resolve(pointer jptr.Pointer, uri string) *Schema {
 func (p *PropertyDependency) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[PropertyDependency] Validating")
 	if obj, ok := data.(map[string]interface{}); ok {
-		if obj[p.prop] == nil {
+		if _, ok := obj[p.prop]; !ok {
 			return
 		}
 		for _, dep := range p.dependencies {
-			if obj[dep] == nil {
+			if _, ok := obj[dep]; !ok {
 				currentState.AddError(data, fmt.Sprintf(`"%s" property is required`, dep))
 			}
 		}
@@ -645,6 +669,115 @@ func (p PropertyDependency) JSONProp(name string) interface{} {
 	return p.dependencies[idx]
 }
 
+// Dependencies defines the dependencies JSON Schema keyword
+// which combines dependentSchemas and dependentRequired
+// prior to draft2019_09
+type Dependencies map[string]Keyword
+
+// NewDependencies allocates a new Dependencies keyword
+func NewDependencies() Keyword {
+	return &Dependencies{}
+}
+
+// Register implements the Keyword interface for Dependencies
+func (d *Dependencies) Register(uri string, registry *SchemaRegistry) {
+	for _, v := range *d {
+		v.Register(uri, registry)
+	}
+}
+
+// Resolve implements the Keyword interface for Dependencies
+func (d *Dependencies) Resolve(pointer jptr.Pointer, uri string) *Schema {
+	if pointer == nil {
+		return nil
+	}
+	current := pointer.Head()
+	if current == nil {
+		return nil
+	}
+
+	if dep, ok := (*d)[*current]; ok {
+		return dep.Resolve(pointer.Tail(), uri)
+	}
+
+	return nil
+}
+
+// ValidateKeyword implements the Keyword interface for Dependencies
+func (d *Dependencies) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+	schemaDebug("[Dependencies] Validating")
+	for _, dep := range *d {
+		subState := currentState.NewSubState()
+		subState.DescendBase("dependencies")
+		subState.DescendRelative("dependencies")
+		subState.Misc["dependencyParent"] = "dependencies"
+		dep.ValidateKeyword(ctx, subState, data)
+	}
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for Dependencies
+func (d *Dependencies) UnmarshalJSON(data []byte) error {
+	return d.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for Dependencies
+func (d *Dependencies) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	_d := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &_d); err != nil {
+		return err
+	}
+	deps := Dependencies{}
+	for k, v := range _d {
+		props := []string{}
+		if err := json.Unmarshal(v, &props); err == nil {
+			deps[k] = &PropertyDependency{
+				dependencies: props,
+				prop:         k,
+			}
+			continue
+		}
+		sch, err := unmarshalSchema(v, registry)
+		if err != nil {
+			return err
+		}
+		deps[k] = &SchemaDependency{
+			schema: sch,
+			prop:   k,
+		}
+	}
+	*d = deps
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for Dependencies
+func (d Dependencies) MarshalJSON() ([]byte, error) {
+	obj := map[string]interface{}{}
+	for key, dep := range d {
+		if prop, ok := dep.(*PropertyDependency); ok {
+			obj[key] = prop.dependencies
+			continue
+		}
+		obj[key] = dep
+	}
+	return json.Marshal(obj)
+}
+
+// JSONProp implements the JSONPather for Dependencies
+func (d Dependencies) JSONProp(name string) interface{} {
+	return d[name]
+}
+
+// JSONChildren implements the JSONContainer interface for Dependencies
+func (d Dependencies) JSONChildren() (r map[string]JSONPather) {
+	r = map[string]JSONPather{}
+	for key, val := range d {
+		if jp, ok := val.(JSONPather); ok {
+			r[key] = jp
+		}
+	}
+	return
+}
+
 // UnevaluatedProperties defines the unevaluatedProperties JSON Schema keyword
 type UnevaluatedProperties Schema
 
@@ -679,6 +812,8 @@ func (up *UnevaluatedProperties) ValidateKeyword(ctx context.Context, currentSta
 				currentState.AddError(data, "unevaluated properties are not allowed")
 				return
 			}
+			currentState.SetEvaluatedKey(key)
+			subState.ClearState()
 			subState.DescendInstanceFromState(currentState, key)
 
 			(*Schema)(up).ValidateKeyword(ctx, subState, obj[key])
@@ -688,10 +823,20 @@ func (up *UnevaluatedProperties) ValidateKeyword(ctx context.Context, currentSta
 
 // UnmarshalJSON implements the json.Unmarshaler interface for UnevaluatedProperties
 func (up *UnevaluatedProperties) UnmarshalJSON(data []byte) error {
-	sch := &Schema{}
-	if err := json.Unmarshal(data, sch); err != nil {
+	return up.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for UnevaluatedProperties
+func (up *UnevaluatedProperties) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
+	sch, err := unmarshalSchema(data, registry)
+	if err != nil {
 		return err
 	}
-	*up = (UnevaluatedProperties)(*sch)
+	*up = UnevaluatedProperties(*sch)
 	return nil
 }
+
+// MarshalJSON implements the json.Marshaler interface for UnevaluatedProperties
+func (up UnevaluatedProperties) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Schema(up))
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"net"
 	"net/mail"
-	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -15,24 +14,25 @@ import (
 )
 
 const (
-	hostname       string = `^([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])(\.([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9\-]{0,61}[a-zA-Z0-9]))*$`
-	unescapedTilda        = `\~[^01]`
+	unescapedTilda string = `\~[^01]`
 	endingTilda           = `\~$`
-	schemePrefix          = `^[^\:]+\:`
 	uriTemplate           = `\{[^\{\}\\]*\}`
 	uuid                  = `^[[:xdigit:]]{8}-[[:xdigit:]]{4}-[[:xdigit:]]{4}-[[:xdigit:]]{4}-[[:xdigit:]]{12}$`
+	fullTime              = `^(\d{2}):(\d{2}):(\d{2})(?:\.\d+)?(?:[zZ]|([+-])(\d{2}):(\d{2}))$`
+	durTime               = `T(?:\d+H(?:\d+M(?:\d+S)?)?|\d+M(?:\d+S)?|\d+S)`
+	duration              = `^P(?:\d+W|(?:\d+Y(?:\d+M(?:\d+D)?)?|\d+M(?:\d+D)?|\d+D)(?:` + durTime + `)?|` + durTime + `)$`
 )
 
 var (
 	// emailPattern           = regexp.MustCompile(email)
-	hostnamePattern        = regexp.MustCompile(hostname)
 	unescaptedTildaPattern = regexp.MustCompile(unescapedTilda)
 	endingTildaPattern     = regexp.MustCompile(endingTilda)
-	schemePrefixPattern    = regexp.MustCompile(schemePrefix)
 	uriTemplatePattern     = regexp.MustCompile(uriTemplate)
 	uuidPattern            = regexp.MustCompile(uuid)
+	durationPattern        = regexp.MustCompile(duration)
+	fullTimePattern        = regexp.MustCompile(fullTime)
 
-	disallowedIdnChars = map[string]bool{"\u0020": true, "\u002D": true, "\u00A2": true, "\u00A3": true, "\u00A4": true, "\u00A5": true, "\u034F": true, "\u0640": true, "\u07FA": true, "\u180B": true, "\u180C": true, "\u180D": true, "\u200B": true, "\u2060": true, "\u2104": true, "\u2108": true, "\u2114": true, "\u2117": true, "\u2118": true, "\u211E": true, "\u211F": true, "\u2123": true, "\u2125": true, "\u2282": true, "\u2283": true, "\u2284": true, "\u2285": true, "\u2286": true, "\u2287": true, "\u2288": true, "\u2616": true, "\u2617": true, "\u2619": true, "\u262F": true, "\u2638": true, "\u266C": true, "\u266D": true, "\u266F": true, "\u2752": true, "\u2756": true, "\u2758": true, "\u275E": true, "\u2761": true, "\u2775": true, "\u2794": true, "\u2798": true, "\u27AF": true, "\u27B1": true, "\u27BE": true, "\u3004": true, "\u3012": true, "\u3013": true, "\u3020": true, "\u302E": true, "\u302F": true, "\u3031": true, "\u3032": true, "\u3035": true, "\u303B": true, "\u3164": true, "\uFFA0": true}
+	disallowedIdnChars = map[string]bool{"\u0020": true, "\u00A2": true, "\u00A3": true, "\u00A4": true, "\u00A5": true, "\u034F": true, "\u0640": true, "\u07FA": true, "\u180B": true, "\u180C": true, "\u180D": true, "\u200B": true, "\u2060": true, "\u2104": true, "\u2108": true, "\u2114": true, "\u2117": true, "\u2118": true, "\u211E": true, "\u211F": true, "\u2123": true, "\u2125": true, "\u2282": true, "\u2283": true, "\u2284": true, "\u2285": true, "\u2286": true, "\u2287": true, "\u2288": true, "\u2616": true, "\u2617": true, "\u2619": true, "\u262F": true, "\u2638": true, "\u266C": true, "\u266D": true, "\u266F": true, "\u2752": true, "\u2756": true, "\u2758": true, "\u275E": true, "\u2761": true, "\u2775": true, "\u2794": true, "\u2798": true, "\u27AF": true, "\u27B1": true, "\u27BE": true, "\u3004": true, "\u3012": true, "\u3013": true, "\u3020": true, "\u302E": true, "\u302F": true, "\u3031": true, "\u3032": true, "\u3035": true, "\u303B": true, "\u3164": true, "\uFFA0": true}
 )
 
 // Format defines the format JSON Schema keyword
@@ -54,52 +54,189 @@ func (f *Format) Resolve(pointer jptr.Pointer, uri string) *Schema {
 // ValidateKeyword implements the Keyword interface for Format
 func (f Format) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Format] Validating")
-	var err error
-	if str, ok := data.(string); ok {
-		switch f {
-		case "date-time":
-			err = isValidDateTime(str)
-		case "date":
-			err = isValidDate(str)
-		case "email":
-			err = isValidEmail(str)
-		case "hostname":
-			err = isValidHostname(str)
-		case "idn-email":
-			err = isValidIDNEmail(str)
-		case "idn-hostname":
-			err = isValidIDNHostname(str)
-		case "ipv4":
-			err = isValidIPv4(str)
-		case "ipv6":
-			err = isValidIPv6(str)
-		case "iri-reference":
-			err = isValidIriRef(str)
-		case "iri":
-			err = isValidIri(str)
-		case "json-pointer":
-			err = isValidJSONPointer(str)
-		case "regex":
-			err = isValidRegex(str)
-		case "relative-json-pointer":
-			err = isValidRelJSONPointer(str)
-		case "time":
-			err = isValidTime(str)
-		case "uri-reference":
-			err = isValidURIRef(str)
-		case "uri-template":
-			err = isValidURITemplate(str)
-		case "uri":
-			err = isValidURI(str)
-		case "uuid":
-			err = isValidUUID(str)
-		default:
-			err = nil
+	f.validate(currentState, data, FormatModeFromContext(ctx))
+}
+
+// validate annotates the instance with the format, asserting it in
+// the given mode
+func (f Format) validate(currentState *ValidationState, data interface{}, mode FormatMode) {
+	currentState.AddAnnotation(string(f))
+	if mode == FormatAnnotation {
+		return
+	}
+	checker, known := currentState.formatChecker(string(f))
+	if !known && mode == FormatAssertAll {
+		currentState.AddError(data, fmt.Sprintf("unknown format %s", f))
+		return
+	}
+	if checker == nil {
+		return
+	}
+	if err := checker(data); err != nil {
+		currentState.AddError(data, fmt.Sprintf("invalid %s: %s", f, err.Error()))
+	}
+}
+
+// AnnotatedFormat defines the format JSON Schema keyword of draft 2020-12,
+// which only annotates instances unless a format mode is set with
+// WithFormatMode
+type AnnotatedFormat string
+
+// NewAnnotatedFormat allocates a new AnnotatedFormat keyword
+func NewAnnotatedFormat() Keyword {
+	return new(AnnotatedFormat)
+}
+
+// Register implements the Keyword interface for AnnotatedFormat
+func (f *AnnotatedFormat) Register(uri string, registry *SchemaRegistry) {}
+
+// Resolve implements the Keyword interface for AnnotatedFormat
+func (f *AnnotatedFormat) Resolve(pointer jptr.Pointer, uri string) *Schema {
+	return nil
+}
+
+// ValidateKeyword implements the Keyword interface for AnnotatedFormat
+func (f AnnotatedFormat) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
+	schemaDebug("[AnnotatedFormat] Validating")
+	mode, ok := ctx.Value(formatModeCtxKey{}).(FormatMode)
+	if !ok {
+		mode = FormatAnnotation
+	}
+	Format(f).validate(currentState, data, mode)
+}
+
+// FormatMode controls whether the format keyword asserts the format
+// of instances or only annotates them
+type FormatMode int
+
+const (
+	// FormatAssertKnown asserts formats with a registered or built-in
+	// checker, ignoring unknown formats. This is the default for drafts
+	// prior to 2020-12, which only annotates formats by default
+	FormatAssertKnown FormatMode = iota
+	// FormatAnnotation only collects formats as annotations
+	FormatAnnotation
+	// FormatAssertAll asserts all formats, failing validation of any
+	// instance against an unknown format
+	FormatAssertAll
+)
+
+// formatModeCtxKey is the context key of the format mode
+type formatModeCtxKey struct{}
+
+// WithFormatMode returns a context validating formats in the given mode
+// when passed to Validate or Compile
+func WithFormatMode(ctx context.Context, mode FormatMode) context.Context {
+	return context.WithValue(ctx, formatModeCtxKey{}, mode)
+}
+
+// FormatModeFromContext returns the mode set by WithFormatMode or
+// FormatAssertKnown
+func FormatModeFromContext(ctx context.Context) FormatMode {
+	mode, _ := ctx.Value(formatModeCtxKey{}).(FormatMode)
+	return mode
+}
+
+// FormatChecker validates an instance against a format, returning an
+// error describing why the instance doesn't conform to it
+type FormatChecker func(data interface{}) error
+
+// builtinFormats holds the checkers of the formats defined by the specification
+var builtinFormats = map[string]FormatChecker{
+	"date-time":             stringFormat(isValidDateTime),
+	"date":                  stringFormat(isValidDate),
+	"duration":              stringFormat(isValidDuration),
+	"email":                 stringFormat(isValidEmail),
+	"hostname":              stringFormat(isValidHostname),
+	"idn-email":             stringFormat(isValidIDNEmail),
+	"idn-hostname":          stringFormat(isValidIDNHostname),
+	"ipv4":                  stringFormat(isValidIPv4),
+	"ipv6":                  stringFormat(isValidIPv6),
+	"iri-reference":         stringFormat(isValidIriRef),
+	"iri":                   stringFormat(isValidIri),
+	"json-pointer":          stringFormat(isValidJSONPointer),
+	"regex":                 stringFormat(isValidRegex),
+	"relative-json-pointer": stringFormat(isValidRelJSONPointer),
+	"time":                  stringFormat(isValidTime),
+	"uri-reference":         stringFormat(isValidURIRef),
+	"uri-template":          stringFormat(isValidURITemplate),
+	"uri":                   stringFormat(isValidURI),
+	"uuid":                  stringFormat(isValidUUID),
+}
+
+// stringFormat turns a string validator into a FormatChecker ignoring
+// instances which aren't strings
+func stringFormat(check func(string) error) FormatChecker {
+	return func(data interface{}) error {
+		if str, ok := data.(string); ok {
+			return check(str)
 		}
-		if err != nil {
-			currentState.AddError(data, fmt.Sprintf("invalid %s: %s", f, err.Error()))
+		return nil
+	}
+}
+
+// RegisterFormat registers a checker for the named format with the
+// registry, replacing any checker of the same name including built-in
+// ones. Checkers receive instances of any type. A nil checker disables
+// validation of the format
+func (r *KeywordRegistry) RegisterFormat(name string, checker FormatChecker) {
+	// the formats are replaced rather than modified as validations
+	// may be holding on to them, see globalFormats
+	formats := make(map[string]FormatChecker, len(r.formats)+1)
+	for k, v := range r.formats {
+		formats[k] = v
+	}
+	formats[name] = checker
+	r.formats = formats
+}
+
+// RegisterFormat registers a checker for the named format globally.
+// Validations already started and compiled schemas keep the checkers
+// registered when they started or were compiled
+func RegisterFormat(name string, checker FormatChecker) {
+	r, release := getGlobalKeywordRegistry()
+	defer release()
+
+	r.RegisterFormat(name, checker)
+}
+
+// noFormats stands in for a global registry without formats
+var noFormats = map[string]FormatChecker{}
+
+// globalFormats returns the checkers registered globally. The map is
+// never modified, allowing validations to use it without holding the
+// registry lock
+func globalFormats() map[string]FormatChecker {
+	r, release := getGlobalKeywordRegistry()
+	defer release()
+	if r.formats == nil {
+		return noFormats
+	}
+	return r.formats
+}
+
+// formatChecker looks up the checker of a format in the keyword registry
+// of the state, the global formats the state was created with and the
+// built-in formats in that order. The returned bool reports whether the
+// format is known at all
+func (vs *ValidationState) formatChecker(name string) (FormatChecker, bool) {
+	if vs.LocalKeywordRegistry != nil {
+		if checker, ok := vs.LocalKeywordRegistry.formats[name]; ok {
+			return checker, true
 		}
 	}
+
+	formats := vs.formats
+	if formats == nil {
+		// states not created by a validation look formats up as they go
+		formats = globalFormats()
+	}
+	if checker, ok := formats[name]; ok {
+		return checker, true
+	}
+
+	checker, ok := builtinFormats[name]
+	return checker, ok
 }
 
 // A string instance is valid against "date-time" if it is a valid
@@ -123,6 +260,17 @@ func isValidDate(date string) error {
 	return isValidDateTime(dateTime)
 }
 
+// A string instance is valid against "duration" if it is a valid
+// representation according to the "duration" production defined in
+// RFC 3339, appendix A [RFC3339]
+// https://tools.ietf.org/html/rfc3339#appendix-A
+func isValidDuration(duration string) error {
+	if !durationPattern.MatchString(duration) {
+		return fmt.Errorf("invalid duration string")
+	}
+	return nil
+}
+
 // A string instance is valid against "email" if it is a valid
 // representation as defined by RFC 5322, section 3.4.1 [RFC5322].
 // https://tools.ietf.org/html/rfc5322#section-3.4.1
@@ -143,9 +291,12 @@ func isValidEmail(email string) error {
 // https://tools.ietf.org/html/rfc1034#section-3.1
 // https://tools.ietf.org/html/rfc5891#section-4.4
 func isValidHostname(hostname string) error {
-	if !hostnamePattern.MatchString(hostname) || len(hostname) > 255 {
+	if !isASCII(hostname) {
 		return fmt.Errorf("invalid hostname string")
 	}
+	if _, err := idnaToASCII(hostname); err != nil {
+		return fmt.Errorf("invalid hostname: %s", err.Error())
+	}
 	return nil
 }
 
@@ -167,14 +318,8 @@ func isValidIDNEmail(idnEmail string) error {
 // https://tools.ietf.org/html/rfc5890#section-2.3.2.3
 // https://pdfs.semanticscholar.org/9275/6bcecb29d3dc407e23a997b256be6ff4149d.pdf
 func isValidIDNHostname(idnHostname string) error {
-	if len(idnHostname) > 255 {
-		return fmt.Errorf("invalid idn hostname string")
-	}
-	for _, r := range idnHostname {
-		s := string(r)
-		if disallowedIdnChars[s] {
-			return fmt.Errorf("invalid hostname: contains illegal character %#U", r)
-		}
+	if _, err := idnaToASCII(idnHostname); err != nil {
+		return fmt.Errorf("invalid hostname: %s", err.Error())
 	}
 	return nil
 }
@@ -210,14 +355,20 @@ func isValidIPv6(ipv6 string) error {
 // according to [RFC3987].
 // https://tools.ietf.org/html/rfc3987
 func isValidIriRef(iriRef string) error {
-	return isValidURIRef(iriRef)
+	if err := checkURIReference(iriRef, true, false); err != nil {
+		return fmt.Errorf("iri reference incorrectly Formatted: %s", err.Error())
+	}
+	return nil
 }
 
 // A string instance is a valid against "iri" if it is a valid IRI,
 // according to [RFC3987].
 // https://tools.ietf.org/html/rfc3987
 func isValidIri(iri string) error {
-	return isValidURI(iri)
+	if err := checkURIReference(iri, true, true); err != nil {
+		return fmt.Errorf("iri incorrectly Formatted: %s", err.Error())
+	}
+	return nil
 }
 
 // A string instance is a valid against "json-pointer" if it is a
@@ -261,16 +412,19 @@ func isValidRegex(regex string) error {
 // is a valid Relative JSON Pointer [relative-json-pointer].
 // https://tools.ietf.org/html/draft-handrews-relative-json-pointer-00
 func isValidRelJSONPointer(relJSONPointer string) error {
-	parts := strings.Split(relJSONPointer, "/")
-	if len(parts) == 1 {
-		parts = strings.Split(relJSONPointer, "#")
+	digits := 0
+	for digits < len(relJSONPointer) && relJSONPointer[digits] >= '0' && relJSONPointer[digits] <= '9' {
+		digits++
 	}
-	if i, err := strconv.Atoi(parts[0]); err != nil || i < 0 {
+	if digits == 0 {
 		return fmt.Errorf("RJP must begin with positive integer")
 	}
-	//skip over first part
-	str := relJSONPointer[len(parts[0]):]
-	if len(str) > 0 && str[0] == '#' {
+	if digits > 1 && relJSONPointer[0] == '0' {
+		return fmt.Errorf("RJP integer must not have leading zeros")
+	}
+	//skip over the integer
+	str := relJSONPointer[digits:]
+	if str == "#" {
 		return nil
 	}
 	return isValidJSONPointer(str)
@@ -281,9 +435,33 @@ func isValidRelJSONPointer(relJSONPointer string) error {
 // from RFC 3339, section 5.6 [RFC3339]
 // https://tools.ietf.org/html/rfc3339#section-5.6
 func isValidTime(time string) error {
-	arbitraryDate := "1963-06-19"
-	dateTime := fmt.Sprintf("%sT%s", arbitraryDate, time)
-	return isValidDateTime(dateTime)
+	match := fullTimePattern.FindStringSubmatch(time)
+	if match == nil {
+		return fmt.Errorf("time incorrectly Formatted")
+	}
+	hour, _ := strconv.Atoi(match[1])
+	minute, _ := strconv.Atoi(match[2])
+	second, _ := strconv.Atoi(match[3])
+	if hour > 23 || minute > 59 || second > 60 {
+		return fmt.Errorf("time out of range")
+	}
+	offsetHour, _ := strconv.Atoi(match[5])
+	offsetMinute, _ := strconv.Atoi(match[6])
+	if offsetHour > 23 || offsetMinute > 59 {
+		return fmt.Errorf("time offset out of range")
+	}
+	if second == 60 {
+		// leap seconds are only inserted at the end of a UTC day
+		offset := offsetHour*60 + offsetMinute
+		if match[4] == "-" {
+			offset = -offset
+		}
+		utc := ((hour*60+minute-offset)%(24*60) + 24*60) % (24 * 60)
+		if utc != 23*60+59 {
+			return fmt.Errorf("leap second must be at 23:59 UTC")
+		}
+	}
+	return nil
 }
 
 // A string instance is a valid against "uri-reference" if it is a
@@ -291,12 +469,9 @@ func isValidTime(time string) error {
 // according to [RFC3986].
 // https://tools.ietf.org/html/rfc3986
 func isValidURIRef(uriRef string) error {
-	if _, err := url.Parse(uriRef); err != nil {
+	if err := checkURIReference(uriRef, false, false); err != nil {
 		return fmt.Errorf("uri incorrectly Formatted: %s", err.Error())
 	}
-	if strings.Contains(uriRef, "\\") {
-		return fmt.Errorf("invalid uri")
-	}
 	return nil
 }
 
@@ -318,12 +493,9 @@ func isValidURITemplate(uriTemplate string) error {
 // according to [RFC3986].
 // https://tools.ietf.org/html/rfc3986
 func isValidURI(uri string) error {
-	if _, err := url.Parse(uri); err != nil {
+	if err := checkURIReference(uri, false, true); err != nil {
 		return fmt.Errorf("uri incorrectly Formatted: %s", err.Error())
 	}
-	if !schemePrefixPattern.MatchString(uri) {
-		return fmt.Errorf("uri missing scheme prefix")
-	}
 	return nil
 }
 
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
 	"strconv"
 	"strings"
@@ -31,16 +33,61 @@ func (c *Const) Resolve(pointer jptr.Pointer, uri string) *Schema {
 func (c Const) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Const] Validating")
 	var con interface{}
-	if err := json.Unmarshal(c, &con); err != nil {
+	if err := decodeJSON(c, &con); err != nil {
 		currentState.AddError(data, err.Error())
 		return
 	}
 
-	if !reflect.DeepEqual(con, data) {
+	if !jsonEqual(con, data) {
 		currentState.AddError(data, fmt.Sprintf(`must equal %s`, InvalidValueString(con)))
 	}
 }
 
+// jsonEqual reports whether two instances are equal as JSON values,
+// comparing numbers by value regardless of their representation
+func jsonEqual(a, b interface{}) bool {
+	if x, ok := instanceInt64(a); ok {
+		if y, ok := instanceInt64(b); ok {
+			return x == y
+		}
+	}
+	if x, ok := a.(float64); ok {
+		if y, ok := b.(float64); ok {
+			return x == y
+		}
+	}
+	if x, ok := convertNumberToRat(a); ok {
+		y, ok := convertNumberToRat(b)
+		return ok && x.Cmp(y) == 0
+	}
+
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, val := range x {
+			if other, ok := y[key]; !ok || !jsonEqual(val, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !jsonEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
 // JSONProp implements the JSONPather for Const
 func (c Const) JSONProp(name string) interface{} {
 	return nil
@@ -142,6 +189,29 @@ func DataType(data interface{}) string {
 		return "null"
 	}
 
+	switch data.(type) {
+	case json.Number, *big.Int, *big.Float, *big.Rat:
+		if _, ok := instanceInt64(data); ok {
+			return "integer"
+		}
+		num, ok := convertNumberToRat(data)
+		if n, isNum := data.(json.Number); !ok && isNum && exponentOutOfRange(string(n)) {
+			// numbers with exponents beyond maxExactExponent aren't
+			// converted, positive exponents making them integers
+			if i := strings.IndexAny(string(n), "eE"); i >= 0 && !strings.HasPrefix(string(n)[i+1:], "-") {
+				return "integer"
+			}
+			return "number"
+		}
+		if !ok {
+			return "unknown"
+		}
+		if num.IsInt() {
+			return "integer"
+		}
+		return "number"
+	}
+
 	switch reflect.TypeOf(data).Kind() {
 	case reflect.Bool:
 		return "boolean"
@@ -151,7 +221,7 @@ func DataType(data interface{}) string {
 		return "integer"
 	case reflect.Float32, reflect.Float64:
 		number := reflect.ValueOf(data).Float()
-		if float64(int(number)) == number {
+		if math.Trunc(number) == number && !math.IsInf(number, 0) {
 			return "integer"
 		}
 		return "number"
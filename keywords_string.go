@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"regexp"
 	"unicode/utf8"
 
 	jptr "github.com/qri-io/jsonpointer"
@@ -63,7 +62,9 @@ func (m MinLength) ValidateKeyword(ctx context.Context, currentState *Validation
 }
 
 // Pattern defines the pattern JSON Schema keyword
-type Pattern regexp.Regexp
+type Pattern struct {
+	re Regexp
+}
 
 // NewPattern allocates a new Pattern keyword
 func NewPattern() Keyword {
@@ -81,33 +82,40 @@ func (p *Pattern) Resolve(pointer jptr.Pointer, uri string) *Schema {
 // ValidateKeyword implements the Keyword interface for Pattern
 func (p Pattern) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Pattern] Validating")
-	re := regexp.Regexp(p)
 	if str, ok := data.(string); ok {
-		if !re.Match([]byte(str)) {
-			currentState.AddError(data, fmt.Sprintf("regexp pattern %s mismatch on string: %s", re.String(), str))
+		matched, err := matchString(p.re, str)
+		if err != nil {
+			currentState.AddError(data, fmt.Sprintf("regexp pattern %s: %s", p.re.String(), err.Error()))
+			return
+		}
+		if !matched {
+			currentState.AddError(data, fmt.Sprintf("regexp pattern %s mismatch on string: %s", p.re.String(), str))
 		}
 	}
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface for Pattern
 func (p *Pattern) UnmarshalJSON(data []byte) error {
+	return p.unmarshalJSONWithRegistry(data, nil)
+}
+
+// unmarshalJSONWithRegistry implements the registryUnmarshaler interface for Pattern
+func (p *Pattern) unmarshalJSONWithRegistry(data []byte, registry *KeywordRegistry) error {
 	var str string
 	if err := json.Unmarshal(data, &str); err != nil {
 		return err
 	}
 
-	ptn, err := regexp.Compile(str)
+	ptn, err := compilePattern(registry, str)
 	if err != nil {
 		return err
 	}
 
-	*p = Pattern(*ptn)
+	*p = Pattern{re: ptn}
 	return nil
 }
 
 // MarshalJSON implements the json.Marshaler interface for Pattern
 func (p Pattern) MarshalJSON() ([]byte, error) {
-	re := regexp.Regexp(p)
-	rep := &re
-	return json.Marshal(rep.String())
+	return json.Marshal(p.re.String())
 }
@@ -0,0 +1,153 @@
+package jsonschema
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+// LinesOptions configures how ValidateLinesWithOptions validates records
+type LinesOptions struct {
+	// Workers is the number of records validated concurrently. With more
+	// than one worker the schema is compiled upfront, see Compile, as
+	// only compiled schemas are safe for concurrent use. Zero or one
+	// validates records one after another
+	Workers int
+}
+
+// ValidateLines validates a stream of newline delimited JSON records, as
+// produced for JSON Lines or NDJSON, against the schema. fn is called
+// for every record in input order with its line number, starting at 1,
+// and its validation errors, which are empty for valid records. Records
+// that aren't valid JSON are reported with a single error describing
+// the parsing error. Blank lines are skipped. Reading stops at the first
+// read error or once ctx is done, returning the error
+func (s *Schema) ValidateLines(ctx context.Context, r io.Reader, fn func(line int, errs []KeyError)) error {
+	return s.ValidateLinesWithOptions(ctx, r, LinesOptions{}, fn)
+}
+
+// ValidateLinesWithOptions works like ValidateLines, configured by opts
+func (s *Schema) ValidateLinesWithOptions(ctx context.Context, r io.Reader, opts LinesOptions, fn func(line int, errs []KeyError)) error {
+	validate := s.Validate
+	if opts.Workers > 1 {
+		cs, err := Compile(ctx, s)
+		if err != nil {
+			return err
+		}
+		validate = cs.Validate
+	}
+
+	lv := &linesValidator{ctx: ctx, validate: validate}
+	if opts.Workers <= 1 {
+		return lv.sequential(r, fn)
+	}
+	return lv.concurrent(r, opts.Workers, fn)
+}
+
+// linesValidator validates the records of a newline delimited stream
+type linesValidator struct {
+	ctx      context.Context
+	validate func(ctx context.Context, data interface{}) *ValidationState
+}
+
+// lineRecord is a record read from a stream along with its line number
+type lineRecord struct {
+	line int
+	data []byte
+	// result receives the validation errors of the record
+	result chan []KeyError
+}
+
+// read calls fn for each record read from r until the end of the input,
+// a read error, ctx being done or fn returning false
+func (lv *linesValidator) read(r io.Reader, fn func(rec lineRecord) bool) error {
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		if err := lv.ctx.Err(); err != nil {
+			return err
+		}
+
+		data, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 {
+			if !fn(lineRecord{line: line, data: trimmed}) {
+				return lv.ctx.Err()
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// check validates a single record
+func (lv *linesValidator) check(data []byte) []KeyError {
+	var doc interface{}
+	if err := decodeJSON(data, &doc); err != nil {
+		return []KeyError{{
+			PropertyPath: "/",
+			Message:      fmt.Sprintf("error parsing JSON: %s", err.Error()),
+		}}
+	}
+	return *lv.validate(lv.ctx, doc).Errs
+}
+
+// sequential validates records one after another
+func (lv *linesValidator) sequential(r io.Reader, fn func(line int, errs []KeyError)) error {
+	return lv.read(r, func(rec lineRecord) bool {
+		fn(rec.line, lv.check(rec.data))
+		return true
+	})
+}
+
+// concurrent validates records with a pool of workers, reporting the
+// results in input order. The number of records in flight is bounded
+// by a small multiple of the number of workers
+func (lv *linesValidator) concurrent(r io.Reader, workers int, fn func(line int, errs []KeyError)) error {
+	jobs := make(chan lineRecord, workers)
+	pending := make(chan lineRecord, workers*2)
+	done := make(chan struct{})
+	defer close(done)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for rec := range jobs {
+				rec.result <- lv.check(rec.data)
+			}
+		}()
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		readErr <- lv.read(r, func(rec lineRecord) bool {
+			rec.result = make(chan []KeyError, 1)
+			select {
+			case pending <- rec:
+			case <-done:
+				return false
+			}
+			select {
+			case jobs <- rec:
+				return true
+			case <-done:
+				return false
+			}
+		})
+	}()
+
+	for rec := range pending {
+		select {
+		case errs := <-rec.result:
+			fn(rec.line, errs)
+		case <-lv.ctx.Done():
+			return lv.ctx.Err()
+		}
+	}
+	return <-readErr
+}
@@ -0,0 +1,116 @@
+package jsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidateLines(t *testing.T) {
+	ctx := context.Background()
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["id"],
+		"properties": {
+			"id": {"type": "integer"},
+			"ref": {"$ref": "#/$defs/ref"}
+		},
+		"$defs": {"ref": {"type": "string"}}
+	}`), rs); err != nil {
+		t.Fatal(err)
+	}
+
+	input := strings.Join([]string{
+		`{"id": 1}`,
+		``,
+		`{"id": "two"}`,
+		`{"id": 3, "ref": 3}`,
+		`{"id" 4}`,
+		`  {"id": 5}  `,
+	}, "\n")
+	expect := []string{
+		"1: ",
+		"3: /id: \"two\" type should be integer, got string",
+		"4: /ref: 3 type should be string, got integer",
+		"5: error parsing JSON",
+		"6: ",
+	}
+
+	for _, workers := range []int{0, 1, 4} {
+		got := []string{}
+		err := rs.ValidateLinesWithOptions(ctx, strings.NewReader(input), LinesOptions{Workers: workers}, func(line int, errs []KeyError) {
+			msgs := make([]string, len(errs))
+			for i, e := range errs {
+				msgs[i] = e.Error()
+				if strings.Contains(msgs[i], "error parsing JSON") {
+					msgs[i] = "error parsing JSON"
+				}
+			}
+			got = append(got, fmt.Sprintf("%d: %s", line, strings.Join(msgs, ", ")))
+		})
+		if err != nil {
+			t.Fatalf("%d workers: unexpected error: %s", workers, err)
+		}
+		if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+			t.Errorf("%d workers: expected:\n%s\ngot:\n%s", workers, strings.Join(expect, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestValidateLinesOrder(t *testing.T) {
+	ctx := context.Background()
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{"properties": {"n": {"multipleOf": 3}}}`), rs); err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	for i := 1; i <= 1000; i++ {
+		fmt.Fprintf(&sb, "{\"n\": %d}\n", i)
+	}
+
+	next := 1
+	err := rs.ValidateLinesWithOptions(ctx, strings.NewReader(sb.String()), LinesOptions{Workers: 8}, func(line int, errs []KeyError) {
+		if line != next {
+			t.Fatalf("expected line %d. got: %d", next, line)
+		}
+		if valid := line%3 == 0; valid != (len(errs) == 0) {
+			t.Errorf("line %d: unexpected errors: %v", line, errs)
+		}
+		next++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != 1001 {
+		t.Errorf("expected 1000 records. got: %d", next-1)
+	}
+}
+
+func TestValidateLinesCancel(t *testing.T) {
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{"type": "object"}`), rs); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 4} {
+		ctx, cancel := context.WithCancel(context.Background())
+		count := 0
+		err := rs.ValidateLinesWithOptions(ctx, strings.NewReader(strings.Repeat("{}\n", 1000)), LinesOptions{Workers: workers}, func(line int, errs []KeyError) {
+			count++
+			if line == 10 {
+				cancel()
+			}
+		})
+		if err != context.Canceled {
+			t.Errorf("%d workers: expected context.Canceled. got: %v", workers, err)
+		}
+		if count >= 1000 {
+			t.Errorf("%d workers: expected validation to stop early", workers)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,12 @@
+// Package meta bundles the meta-schemas of the JSON Schema drafts
+// supported by jsonschema, laid out like the paths of their URIs below
+// json-schema.org, such as "draft-07/schema.json" or
+// "draft/2020-12/meta/core.json"
+package meta
+
+import "embed"
+
+// FS holds the bundled meta-schema documents
+//
+//go:embed draft-04 draft-06 draft-07 draft
+var FS embed.FS
@@ -0,0 +1,153 @@
+package jsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/qri-io/jsonschema/meta"
+)
+
+// metaSchemaFiles lists the bundled documents making up the meta-schema of
+// each dialect, starting with the dialect meta-schema itself
+var metaSchemaFiles = map[string][]string{
+	normalizeDialectURI(DialectDraft4): {"draft-04/schema.json"},
+	normalizeDialectURI(DialectDraft6): {"draft-06/schema.json"},
+	normalizeDialectURI(DialectDraft7): {"draft-07/schema.json"},
+	normalizeDialectURI(DialectDraft2019_09): {
+		"draft/2019-09/schema.json",
+		"draft/2019-09/meta/core.json",
+		"draft/2019-09/meta/applicator.json",
+		"draft/2019-09/meta/validation.json",
+		"draft/2019-09/meta/meta-data.json",
+		"draft/2019-09/meta/format.json",
+		"draft/2019-09/meta/content.json",
+	},
+	normalizeDialectURI(DialectDraft2020_12): {
+		"draft/2020-12/schema.json",
+		"draft/2020-12/meta/core.json",
+		"draft/2020-12/meta/applicator.json",
+		"draft/2020-12/meta/unevaluated.json",
+		"draft/2020-12/meta/validation.json",
+		"draft/2020-12/meta/meta-data.json",
+		"draft/2020-12/meta/format-annotation.json",
+		"draft/2020-12/meta/content.json",
+	},
+}
+
+var (
+	metaSchemas     = map[string]*Schema{}
+	metaSchemasLock sync.Mutex
+)
+
+// MetaSchema returns the bundled meta-schema of the dialect identified by
+// the given URI. Loading a meta-schema registers it and the vocabulary
+// meta-schemas it references with the global SchemaRegistry
+func MetaSchema(uri string) (*Schema, error) {
+	key := normalizeDialectURI(uri)
+
+	metaSchemasLock.Lock()
+	defer metaSchemasLock.Unlock()
+
+	if sch, ok := metaSchemas[key]; ok {
+		return sch, nil
+	}
+
+	files, ok := metaSchemaFiles[key]
+	if !ok {
+		return nil, fmt.Errorf("no meta-schema bundled for dialect %q", uri)
+	}
+
+	var dialectMeta *Schema
+	for _, file := range files {
+		data, err := meta.FS.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		sch := &Schema{}
+		if err := json.Unmarshal(data, sch); err != nil {
+			return nil, fmt.Errorf("error unmarshaling meta-schema %s: %w", file, err)
+		}
+		sch.Register("", &SchemaRegistry{})
+		if dialectMeta == nil {
+			dialectMeta = sch
+		}
+	}
+
+	metaSchemas[key] = dialectMeta
+	return dialectMeta, nil
+}
+
+// InvalidSchemaError reports the meta-schema violations of a schema document
+type InvalidSchemaError struct {
+	Errs []KeyError
+}
+
+// Error implements the error interface for InvalidSchemaError
+func (e *InvalidSchemaError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid schema: %s", strings.Join(msgs, "; "))
+}
+
+// ValidateSchema checks a JSON schema document against the meta-schema of
+// its dialect, as declared by "$schema" or else the dialect loaded in the
+// global keyword registry. The property paths of the returned errors point
+// into the schema document
+func ValidateSchema(ctx context.Context, data []byte) ([]KeyError, error) {
+	var doc interface{}
+	if err := decodeJSON(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing JSON bytes: %w", err)
+	}
+
+	meta, err := metaSchemaFor(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	vs := meta.Validate(ctx, doc)
+	return *vs.Errs, nil
+}
+
+// metaSchemaFor finds the meta-schema a decoded schema document is written
+// against, resolving custom meta-schemas with the schema registry of ctx
+func metaSchemaFor(ctx context.Context, doc interface{}) (*Schema, error) {
+	if obj, ok := doc.(map[string]interface{}); ok {
+		if schemaURI, ok := obj["$schema"].(string); ok {
+			if IsKnownDialect(schemaURI) {
+				return MetaSchema(schemaURI)
+			}
+			meta, err := SchemaRegistryFromContext(ctx).root().fetch(ctx, schemaURI, "")
+			if err != nil {
+				return nil, fmt.Errorf("unknown meta-schema %q: %w", schemaURI, err)
+			}
+			return meta, nil
+		}
+	}
+
+	r, release := getGlobalKeywordRegistry()
+	dialect := r.Dialect()
+	release()
+	if dialect == "" {
+		dialect = DialectDraft2019_09
+	}
+	return MetaSchema(dialect)
+}
+
+// UnmarshalStrict parses a JSON schema document into s after checking it
+// against the meta-schema of its dialect. Meta-schema violations are
+// returned as an *InvalidSchemaError
+func UnmarshalStrict(ctx context.Context, data []byte, s *Schema) error {
+	errs, err := ValidateSchema(ctx, data)
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return &InvalidSchemaError{Errs: errs}
+	}
+	return UnmarshalSchema(ctx, data, s)
+}
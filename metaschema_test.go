@@ -0,0 +1,102 @@
+package jsonschema
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		schema string
+		paths  []string
+	}{
+		{`{"type": "string"}`, nil},
+		{`true`, nil},
+		{`{"minimum": "5"}`, []string{"/minimum"}},
+		{`{"required": {}}`, []string{"/required"}},
+		{`{"properties": {"a": {"type": "strin"}}}`, []string{"/properties/a/type"}},
+		{`{"$schema": "http://json-schema.org/draft-04/schema#", "maximum": 5, "exclusiveMaximum": true}`, nil},
+		{`{"$schema": "http://json-schema.org/draft-04/schema#", "exclusiveMaximum": 5}`, []string{"/exclusiveMaximum"}},
+		{`{"$schema": "http://json-schema.org/draft-07/schema#", "exclusiveMaximum": 5}`, nil},
+		{`{"$schema": "https://json-schema.org/draft/2020-12/schema", "prefixItems": [{"type": "string"}], "items": false}`, nil},
+		{`{"$schema": "https://json-schema.org/draft/2020-12/schema", "prefixItems": {"type": "string"}}`, []string{"/prefixItems"}},
+		{`{"$schema": "https://json-schema.org/draft/2020-12/schema", "unevaluatedItems": 5}`, []string{"/unevaluatedItems"}},
+	}
+
+	for i, c := range cases {
+		errs, err := ValidateSchema(ctx, []byte(c.schema))
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %s", i, err)
+			continue
+		}
+		if len(c.paths) == 0 && len(errs) > 0 {
+			t.Errorf("case %d: expected schema to be valid. got: %v", i, errs)
+			continue
+		}
+		for _, path := range c.paths {
+			found := false
+			for _, e := range errs {
+				if e.PropertyPath == path {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("case %d: expected an error at %s. got: %v", i, path, errs)
+			}
+		}
+	}
+
+	if _, err := ValidateSchema(ctx, []byte(`{"$schema": "urn:example:unregistered"}`)); err == nil {
+		t.Errorf("expected an unknown meta-schema to error")
+	}
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+	ctx := context.Background()
+
+	rs := &Schema{}
+	if err := UnmarshalStrict(ctx, []byte(`{"type": "string"}`), rs); err != nil {
+		t.Fatal(err)
+	}
+	if rs.TopLevelType() != "string" {
+		t.Errorf("expected schema to be unmarshaled")
+	}
+
+	err := UnmarshalStrict(ctx, []byte(`{"minLength": -1}`), &Schema{})
+	var invalid *InvalidSchemaError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected an InvalidSchemaError. got: %v", err)
+	}
+	if len(invalid.Errs) == 0 || invalid.Errs[0].PropertyPath != "/minLength" {
+		t.Errorf("expected an error pointing to /minLength. got: %v", invalid.Errs)
+	}
+}
+
+func TestStrictSchemaRegistry(t *testing.T) {
+	ctx := context.Background()
+	schemas := map[string]string{
+		"valid":   `{"type": "string"}`,
+		"invalid": `{"type": 5}`,
+		// unsupported keywords are dropped when decoding
+		"unsupported": `{"deprecated": "yes"}`,
+	}
+	GetSchemaLoaderRegistry().Register("strict", func(ctx context.Context, uri *url.URL, schema *Schema) error {
+		return UnmarshalSchema(ctx, []byte(schemas[uri.Host]), schema)
+	})
+
+	registry := &SchemaRegistry{schemaLookup: map[string]*Schema{}}
+	registry.SetStrict(true)
+
+	if registry.Get(ctx, "strict://valid") == nil {
+		t.Errorf("expected a valid schema to resolve")
+	}
+	if registry.Get(ctx, "strict://invalid") != nil {
+		t.Errorf("expected an invalid schema to be rejected")
+	}
+	if registry.Get(ctx, "strict://unsupported") != nil {
+		t.Errorf("expected the fetched document rather than the decoded schema to be checked")
+	}
+}
@@ -0,0 +1,271 @@
+package jsonschema
+
+import (
+	"context"
+	"encoding/json"
+
+	jptr "github.com/qri-io/jsonpointer"
+)
+
+// OutputFormat selects the structure of a validation result following
+// the output formats defined by the JSON Schema specification
+type OutputFormat int
+
+const (
+	// OutputFlag only reports whether the instance is valid
+	OutputFlag OutputFormat = iota
+	// OutputBasic reports all errors as a flat list
+	OutputBasic
+	// OutputDetailed reports errors as a tree following the structure
+	// of the schema, omitting valid branches
+	OutputDetailed
+	// OutputVerbose reports the result of every evaluated schema and keyword
+	OutputVerbose
+)
+
+// String returns the name of the output format as used by the specification
+func (f OutputFormat) String() string {
+	switch f {
+	case OutputFlag:
+		return "flag"
+	case OutputBasic:
+		return "basic"
+	case OutputDetailed:
+		return "detailed"
+	case OutputVerbose:
+		return "verbose"
+	}
+	return "unknown"
+}
+
+// OutputUnit is a single node of a validation result
+type OutputUnit struct {
+	// Valid reports the validity of the evaluated instance location
+	Valid bool `json:"valid"`
+	// KeywordLocation is the relative location of the validating keyword
+	// following the evaluation path, including any traversed references
+	KeywordLocation string `json:"keywordLocation"`
+	// AbsoluteKeywordLocation is the location of the validating keyword
+	// as an absolute URI of the schema resource declaring it
+	AbsoluteKeywordLocation string `json:"absoluteKeywordLocation,omitempty"`
+	// InstanceLocation is a JSON pointer to the evaluated part of the instance
+	InstanceLocation string `json:"instanceLocation"`
+	// Error is a human-readable description of the failure
+	Error string `json:"error,omitempty"`
+	// Annotation is the value produced by an annotating keyword
+	Annotation interface{} `json:"annotation,omitempty"`
+	// Errors holds the nested results of a failed evaluation
+	Errors []*OutputUnit `json:"errors,omitempty"`
+	// Annotations holds the nested results of a successful evaluation
+	Annotations []*OutputUnit `json:"annotations,omitempty"`
+
+	// flag marks a unit carrying nothing but the validity of the instance
+	flag bool
+}
+
+// MarshalJSON implements the json.Marshaler interface for OutputUnit
+func (u OutputUnit) MarshalJSON() ([]byte, error) {
+	if u.flag {
+		return json.Marshal(struct {
+			Valid bool `json:"valid"`
+		}{u.Valid})
+	}
+	type outputUnit OutputUnit
+	return json.Marshal(outputUnit(u))
+}
+
+// evaluation records the outcome of evaluating a schema or keyword
+// against a location of the instance
+type evaluation struct {
+	keywordLocation         string
+	absoluteKeywordLocation string
+	instanceLocation        string
+	valid                   bool
+	annotation              interface{}
+	errors                  []string
+	children                []*evaluation
+}
+
+// ValidateOutput checks an instance against the schema and reports
+// the result in the requested output format
+func (s *Schema) ValidateOutput(ctx context.Context, data interface{}, format OutputFormat) *OutputUnit {
+	currentState := newContextValidationState(ctx, s)
+	root := &evaluation{}
+	if format != OutputFlag {
+		// output units carry the annotations of successful evaluations
+		if currentState.Annotations == nil {
+			currentState.Annotations = &[]Annotation{}
+		}
+		currentState.evaluation = root
+	}
+	s.ValidateKeyword(ctx, currentState, data)
+
+	valid := currentState.IsValid()
+	if len(root.children) == 0 {
+		return &OutputUnit{Valid: valid, flag: true}
+	}
+	result := root.children[0]
+
+	switch format {
+	case OutputBasic:
+		unit := result.unit()
+		if !valid {
+			unit.Errors = result.errorLeaves(nil)
+			return unit
+		}
+		for _, a := range *currentState.Annotations {
+			unit.Annotations = append(unit.Annotations, &OutputUnit{
+				Valid:                   true,
+				KeywordLocation:         a.KeywordLocation,
+				AbsoluteKeywordLocation: a.AbsoluteKeywordLocation,
+				InstanceLocation:        a.InstanceLocation,
+				Annotation:              a.Value,
+			})
+		}
+		return unit
+	case OutputDetailed:
+		return result.detailed()
+	case OutputVerbose:
+		return result.verbose()
+	}
+	return &OutputUnit{Valid: valid, flag: true}
+}
+
+// enterEvaluation records the evaluation of the keyword identified by
+// the given tokens as a child of the current evaluation until the returned
+// function is called. Recording only takes place for states validating
+// with an output format requiring the evaluation tree
+func (vs *ValidationState) enterEvaluation(token ...string) func() {
+	parent := vs.evaluation
+	if parent == nil {
+		return func() {}
+	}
+
+	eval := &evaluation{
+		keywordLocation:         vs.keywordLocation(token...),
+		absoluteKeywordLocation: vs.absoluteKeywordLocation(token...),
+		instanceLocation:        vs.InstanceLocation.String(),
+	}
+	parent.children = append(parent.children, eval)
+	vs.evaluation = eval
+
+	errs := vs.Errs
+	errCount := len(*errs)
+	return func() {
+		eval.valid = len(*errs) == errCount
+		vs.evaluation = parent
+	}
+}
+
+// keywordLocation returns the location of the keyword identified by the
+// given tokens following the evaluation path
+func (vs *ValidationState) keywordLocation(token ...string) string {
+	return pointerDescendant(vs.RelativeLocation, token...).String()
+}
+
+// absoluteKeywordLocation returns the location of the keyword identified
+// by the given tokens within the schema resource it is declared in
+func (vs *ValidationState) absoluteKeywordLocation(token ...string) string {
+	if vs.BaseURI == "" {
+		return ""
+	}
+	return vs.BaseURI + "#" + pointerDescendant(vs.BaseRelativeLocation, token...).String()
+}
+
+// pointerDescendant appends tokens to a pointer without touching the
+// backing array shared with the pointers of other states
+func pointerDescendant(ptr *jptr.Pointer, token ...string) jptr.Pointer {
+	if ptr == nil {
+		return jptr.Pointer(token)
+	}
+	base := *ptr
+	return base[:len(base):len(base)].RawDescendant(token...)
+}
+
+// unit creates an output unit for the evaluation without nested results
+func (e *evaluation) unit() *OutputUnit {
+	return &OutputUnit{
+		Valid:                   e.valid,
+		Annotation:              e.annotation,
+		KeywordLocation:         e.keywordLocation,
+		AbsoluteKeywordLocation: e.absoluteKeywordLocation,
+		InstanceLocation:        e.instanceLocation,
+	}
+}
+
+// errorUnits creates one output unit per error recorded for the evaluation
+func (e *evaluation) errorUnits() []*OutputUnit {
+	units := make([]*OutputUnit, 0, len(e.errors))
+	for _, msg := range e.errors {
+		unit := e.unit()
+		unit.Valid = false
+		unit.Error = msg
+		units = append(units, unit)
+	}
+	return units
+}
+
+// errorLeaves collects the errors of all failed evaluations below e
+// ignoring those of branches which didn't affect the overall result
+func (e *evaluation) errorLeaves(units []*OutputUnit) []*OutputUnit {
+	if e.valid {
+		return units
+	}
+	units = append(units, e.errorUnits()...)
+	for _, child := range e.children {
+		units = child.errorLeaves(units)
+	}
+	return units
+}
+
+// detailed builds the failed branches of the evaluation tree, replacing
+// nested nodes holding a single result with that result
+func (e *evaluation) detailed() *OutputUnit {
+	unit := e.unit()
+	if e.valid {
+		return unit
+	}
+	unit.Errors = e.detailedErrors()
+	return unit
+}
+
+// detailedErrors returns the nested failed results of the evaluation
+func (e *evaluation) detailedErrors() []*OutputUnit {
+	errs := e.errorUnits()
+	for _, child := range e.children {
+		if child.valid {
+			continue
+		}
+		nested := child.detailedErrors()
+		if len(nested) == 1 {
+			errs = append(errs, nested[0])
+			continue
+		}
+		unit := child.unit()
+		unit.Errors = nested
+		errs = append(errs, unit)
+	}
+	return errs
+}
+
+// verbose builds the complete evaluation tree. Results nested below a
+// failed evaluation are reported as errors, those below a successful
+// evaluation as annotations
+func (e *evaluation) verbose() *OutputUnit {
+	unit := e.unit()
+	var nested []*OutputUnit
+	if len(e.errors) == 1 {
+		unit.Error = e.errors[0]
+	} else {
+		nested = e.errorUnits()
+	}
+	for _, child := range e.children {
+		nested = append(nested, child.verbose())
+	}
+	if e.valid {
+		unit.Annotations = nested
+	} else {
+		unit.Errors = nested
+	}
+	return unit
+}
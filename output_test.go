@@ -0,0 +1,186 @@
+package jsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+var outputTestSchema = `{
+	"$id": "https://example.com/polygon",
+	"$defs": {
+		"point": {
+			"type": "object",
+			"properties": {
+				"x": { "type": "number" },
+				"y": { "type": "number" }
+			},
+			"additionalProperties": false,
+			"required": [ "x", "y" ]
+		}
+	},
+	"type": "array",
+	"items": { "$ref": "#/$defs/point" },
+	"minItems": 3
+}`
+
+var outputTestInstance = []interface{}{
+	map[string]interface{}{"x": 2.5, "y": 1.3},
+	map[string]interface{}{"x": 1, "z": 6.7},
+}
+
+func TestValidateOutputFlag(t *testing.T) {
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(outputTestSchema), rs); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := json.Marshal(rs.ValidateOutput(context.Background(), outputTestInstance, OutputFlag))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"valid":false}` {
+		t.Errorf("unexpected flag output: %s", out)
+	}
+
+	out, err = json.Marshal(rs.ValidateOutput(context.Background(), []interface{}{}, OutputFlag))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"valid":false}` {
+		t.Errorf("unexpected flag output: %s", out)
+	}
+}
+
+func TestValidateOutputBasic(t *testing.T) {
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(outputTestSchema), rs); err != nil {
+		t.Fatal(err)
+	}
+
+	res := rs.ValidateOutput(context.Background(), outputTestInstance, OutputBasic)
+	if res.Valid {
+		t.Fatal("expected instance to be invalid")
+	}
+
+	expect := []OutputUnit{
+		{
+			KeywordLocation:         "/items/$ref/required",
+			AbsoluteKeywordLocation: "https://example.com/polygon#/$defs/point/required",
+			InstanceLocation:        "/1",
+		},
+		{
+			KeywordLocation:         "/items/$ref/additionalProperties",
+			AbsoluteKeywordLocation: "https://example.com/polygon#/$defs/point/additionalProperties",
+			InstanceLocation:        "/1",
+		},
+		{
+			KeywordLocation:         "/minItems",
+			AbsoluteKeywordLocation: "https://example.com/polygon#/minItems",
+			InstanceLocation:        "",
+		},
+	}
+	if len(res.Errors) != len(expect) {
+		t.Fatalf("expected %d errors. got: %d", len(expect), len(res.Errors))
+	}
+	for i, e := range expect {
+		got := res.Errors[i]
+		if got.Valid || got.Error == "" || len(got.Errors) != 0 {
+			t.Errorf("error %d: expected a failed leaf unit. got: %v", i, got)
+		}
+		if got.KeywordLocation != e.KeywordLocation {
+			t.Errorf("error %d: keywordLocation mismatch. expected: %s, got: %s", i, e.KeywordLocation, got.KeywordLocation)
+		}
+		if got.AbsoluteKeywordLocation != e.AbsoluteKeywordLocation {
+			t.Errorf("error %d: absoluteKeywordLocation mismatch. expected: %s, got: %s", i, e.AbsoluteKeywordLocation, got.AbsoluteKeywordLocation)
+		}
+		if got.InstanceLocation != e.InstanceLocation {
+			t.Errorf("error %d: instanceLocation mismatch. expected: %s, got: %s", i, e.InstanceLocation, got.InstanceLocation)
+		}
+	}
+
+	res = rs.ValidateOutput(context.Background(), []interface{}{
+		map[string]interface{}{"x": 1, "y": 1},
+		map[string]interface{}{"x": 2, "y": 2},
+		map[string]interface{}{"x": 3, "y": 3},
+	}, OutputBasic)
+	if !res.Valid || len(res.Errors) != 0 {
+		t.Errorf("expected instance to be valid. got: %v", res)
+	}
+}
+
+func TestValidateOutputDetailed(t *testing.T) {
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(outputTestSchema), rs); err != nil {
+		t.Fatal(err)
+	}
+
+	res := rs.ValidateOutput(context.Background(), outputTestInstance, OutputDetailed)
+	if res.Valid || len(res.Errors) != 2 {
+		t.Fatalf("expected two failed branches. got: %v", res)
+	}
+
+	ref := res.Errors[0]
+	if ref.KeywordLocation != "/items/$ref" || ref.AbsoluteKeywordLocation != "https://example.com/polygon#/$defs/point" || ref.InstanceLocation != "/1" {
+		t.Errorf("unexpected location of the failed reference: %v", ref)
+	}
+	if len(ref.Errors) != 2 {
+		t.Errorf("expected two nested errors of the failed reference. got: %d", len(ref.Errors))
+	}
+
+	minItems := res.Errors[1]
+	if minItems.KeywordLocation != "/minItems" || minItems.Error == "" {
+		t.Errorf("expected the minItems error to be collapsed into a leaf. got: %v", minItems)
+	}
+}
+
+func TestValidateOutputVerbose(t *testing.T) {
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{
+		"properties": {
+			"a": { "anyOf": [ { "type": "string" }, { "type": "number" } ] }
+		}
+	}`), rs); err != nil {
+		t.Fatal(err)
+	}
+
+	res := rs.ValidateOutput(context.Background(), map[string]interface{}{"a": 5}, OutputVerbose)
+	if !res.Valid || len(res.Annotations) != 1 {
+		t.Fatalf("expected a valid result for the properties keyword. got: %v", res)
+	}
+
+	anyOf := res.Annotations[0].Annotations[0].Annotations[0]
+	if anyOf.KeywordLocation != "/properties/a/anyOf" || anyOf.InstanceLocation != "/a" || !anyOf.Valid {
+		t.Fatalf("unexpected anyOf result: %v", anyOf)
+	}
+	if len(anyOf.Annotations) != 2 {
+		t.Fatalf("expected results for both anyOf branches. got: %d", len(anyOf.Annotations))
+	}
+	if branch := anyOf.Annotations[0]; branch.Valid || branch.KeywordLocation != "/properties/a/anyOf/0" || len(branch.Errors) != 1 {
+		t.Errorf("expected the failed branch to be reported. got: %v", branch)
+	}
+	if branch := anyOf.Annotations[1]; !branch.Valid || branch.KeywordLocation != "/properties/a/anyOf/1" {
+		t.Errorf("expected the passing branch to be reported. got: %v", branch)
+	}
+}
+
+func TestKeyErrorLocations(t *testing.T) {
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(outputTestSchema), rs); err != nil {
+		t.Fatal(err)
+	}
+
+	errs, err := rs.ValidateBytes(context.Background(), []byte(`[{"x": 1, "y": "2"}, {"x": 1, "y": 2}, {"x": 1, "y": 2}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("expected one error. got: %v", errs)
+	}
+	if errs[0].KeywordLocation != "/items/$ref/properties/y/type" {
+		t.Errorf("keywordLocation mismatch. got: %s", errs[0].KeywordLocation)
+	}
+	if errs[0].AbsoluteKeywordLocation != "https://example.com/polygon#/$defs/point/properties/y/type" {
+		t.Errorf("absoluteKeywordLocation mismatch. got: %s", errs[0].AbsoluteKeywordLocation)
+	}
+}
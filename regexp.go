@@ -0,0 +1,99 @@
+package jsonschema
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Regexp is a compiled regular expression as used by the pattern and
+// patternProperties keywords
+type Regexp interface {
+	// MatchString reports whether the string contains any match
+	MatchString(s string) bool
+	// String returns the source text of the expression
+	String() string
+}
+
+// ErrMatchStepLimit is the error of matching a pattern with an engine
+// giving up on inputs taking too many steps to match, see ECMAScriptEngine
+var ErrMatchStepLimit = errors.New("regular expression exceeded the step limit matching the input")
+
+// matchString reports whether re matches s, returning the error of
+// regular expressions able to fail matching such as those of
+// ECMAScriptEngine
+func matchString(re Regexp, s string) (bool, error) {
+	if em, ok := re.(interface {
+		MatchStringError(s string) (bool, error)
+	}); ok {
+		return em.MatchStringError(s)
+	}
+	return re.MatchString(s), nil
+}
+
+// RegexpEngine compiles regular expressions of a specific dialect
+type RegexpEngine interface {
+	Compile(expr string) (Regexp, error)
+}
+
+// RE2Engine compiles regular expressions with the standard library regexp
+// package. It is the default engine, guaranteeing matches in linear time
+// while deviating from the ECMA-262 dialect patterns are defined in
+var RE2Engine RegexpEngine = re2Engine{}
+
+type re2Engine struct{}
+
+// Compile implements the RegexpEngine interface for RE2Engine
+func (re2Engine) Compile(expr string) (Regexp, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return re, nil
+}
+
+// SetRegexpEngine sets the engine compiling the patterns of schemas
+// decoded with the registry and checking the "regex" format
+func (r *KeywordRegistry) SetRegexpEngine(engine RegexpEngine) {
+	r.regexpEngine = engine
+	r.RegisterFormat("regex", regexpFormat(engine))
+}
+
+// RegexpEngine returns the engine compiling patterns for the registry
+func (r *KeywordRegistry) RegexpEngine() RegexpEngine {
+	if r.regexpEngine == nil {
+		return RE2Engine
+	}
+	return r.regexpEngine
+}
+
+// SetRegexpEngine sets the engine compiling the patterns of schemas
+// decoded after the call globally
+func SetRegexpEngine(engine RegexpEngine) {
+	r, release := getGlobalKeywordRegistry()
+	defer release()
+
+	r.SetRegexpEngine(engine)
+}
+
+// compilePattern compiles a pattern with the engine of the registry
+// decoding it, or the global one for a nil registry
+func compilePattern(registry *KeywordRegistry, expr string) (Regexp, error) {
+	if registry != nil {
+		return registry.RegexpEngine().Compile(expr)
+	}
+	r, release := getGlobalKeywordRegistry()
+	engine := r.RegexpEngine()
+	release()
+	return engine.Compile(expr)
+}
+
+// regexpFormat creates a checker of the "regex" format for the engine
+func regexpFormat(engine RegexpEngine) FormatChecker {
+	return stringFormat(func(expr string) error {
+		if _, err := engine.Compile(expr); err != nil {
+			return fmt.Errorf("invalid regex expression")
+		}
+		return nil
+	})
+}
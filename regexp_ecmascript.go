@@ -0,0 +1,968 @@
+package jsonschema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultECMAScriptMaxSteps is the number of backtracking steps after
+// which ECMAScriptEngine gives up matching an input
+const DefaultECMAScriptMaxSteps = 1000000
+
+// ECMAScriptEngine compiles regular expressions following the ECMA-262
+// dialect patterns are defined in, parsed as with the unicode flag set.
+// It supports lookaround assertions, backreferences and unicode property
+// escapes using a backtracking matcher, so unlike RE2Engine matching time
+// can grow exponentially with the input for some patterns. Matches taking
+// more than DefaultECMAScriptMaxSteps steps fail with ErrMatchStepLimit
+var ECMAScriptEngine RegexpEngine = ecmaScriptEngine{maxSteps: DefaultECMAScriptMaxSteps}
+
+// NewECMAScriptEngine creates an engine like ECMAScriptEngine giving up
+// matches after maxSteps backtracking steps. Zero or less means no limit
+func NewECMAScriptEngine(maxSteps int) RegexpEngine {
+	return ecmaScriptEngine{maxSteps: maxSteps}
+}
+
+type ecmaScriptEngine struct {
+	maxSteps int
+}
+
+// Compile implements the RegexpEngine interface for ECMAScriptEngine
+func (e ecmaScriptEngine) Compile(expr string) (Regexp, error) {
+	p := &ecmaParser{src: []rune(expr), names: map[string]int{}}
+	prog, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid ECMA-262 regular expression %q: %s", expr, err.Error())
+	}
+	return &ecmaRegexp{expr: expr, prog: prog, groups: p.groups, maxSteps: e.maxSteps}, nil
+}
+
+// ecmaRegexp is a compiled ECMA-262 regular expression
+type ecmaRegexp struct {
+	expr     string
+	prog     ecmaNode
+	groups   int
+	maxSteps int
+}
+
+// String implements the Regexp interface for ecmaRegexp
+func (re *ecmaRegexp) String() string {
+	return re.expr
+}
+
+// MatchString implements the Regexp interface for ecmaRegexp. Inputs
+// exceeding the step limit don't match
+func (re *ecmaRegexp) MatchString(s string) bool {
+	matched, _ := re.MatchStringError(s)
+	return matched
+}
+
+// MatchStringError reports whether the string contains any match like
+// MatchString, returning ErrMatchStepLimit if matching takes more steps
+// than the engine allows
+func (re *ecmaRegexp) MatchStringError(s string) (bool, error) {
+	m := &ecmaMatcher{input: []rune(s), caps: make([]int, 2*(re.groups+1)), maxSteps: re.maxSteps}
+	accept := func(int) bool { return true }
+	for start := 0; start <= len(m.input); start++ {
+		for i := range m.caps {
+			m.caps[i] = -1
+		}
+		if re.prog.match(m, start, accept) {
+			return true, nil
+		}
+		if m.exhausted() {
+			return false, ErrMatchStepLimit
+		}
+	}
+	return false, nil
+}
+
+// ecmaMatcher holds the state of matching an input
+type ecmaMatcher struct {
+	input []rune
+	// caps holds the start and end positions of capturing groups
+	caps []int
+	// steps counts the nodes matched so far, failing every node
+	// once it exceeds maxSteps unless maxSteps is zero or less
+	steps, maxSteps int
+}
+
+// step counts matching a node, reporting whether the limit allows it
+func (m *ecmaMatcher) step() bool {
+	m.steps++
+	return !m.exhausted()
+}
+
+// exhausted reports whether matching ran out of steps
+func (m *ecmaMatcher) exhausted() bool {
+	return m.maxSteps > 0 && m.steps > m.maxSteps
+}
+
+// ecmaNode is a node of a compiled expression. match calls k with the
+// end position of every way the node matches at pos until k succeeds
+type ecmaNode interface {
+	match(m *ecmaMatcher, pos int, k func(int) bool) bool
+}
+
+// ecmaSeq matches its nodes one after another
+type ecmaSeq []ecmaNode
+
+func (n ecmaSeq) match(m *ecmaMatcher, pos int, k func(int) bool) bool {
+	if len(n) == 0 {
+		return k(pos)
+	}
+	return n[0].match(m, pos, func(p int) bool {
+		return n[1:].match(m, p, k)
+	})
+}
+
+// ecmaAlt matches the first of its alternatives that leads to a match
+type ecmaAlt []ecmaNode
+
+func (n ecmaAlt) match(m *ecmaMatcher, pos int, k func(int) bool) bool {
+	for _, alt := range n {
+		if alt.match(m, pos, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// ecmaChar matches a single character satisfying the predicate
+type ecmaChar func(r rune) bool
+
+func (n ecmaChar) match(m *ecmaMatcher, pos int, k func(int) bool) bool {
+	return m.step() && pos < len(m.input) && n(m.input[pos]) && k(pos+1)
+}
+
+// ecmaAssert matches the empty string where the predicate holds
+type ecmaAssert func(m *ecmaMatcher, pos int) bool
+
+func (n ecmaAssert) match(m *ecmaMatcher, pos int, k func(int) bool) bool {
+	return m.step() && n(m, pos) && k(pos)
+}
+
+// ecmaGroup records the span matched by a capturing group
+type ecmaGroup struct {
+	index int
+	node  ecmaNode
+}
+
+func (n *ecmaGroup) match(m *ecmaMatcher, pos int, k func(int) bool) bool {
+	return n.node.match(m, pos, func(p int) bool {
+		start, end := m.caps[2*n.index], m.caps[2*n.index+1]
+		m.caps[2*n.index], m.caps[2*n.index+1] = pos, p
+		if k(p) {
+			return true
+		}
+		m.caps[2*n.index], m.caps[2*n.index+1] = start, end
+		return false
+	})
+}
+
+// ecmaLookaround asserts that its node matches, or doesn't match if
+// negated, ahead of or behind the current position
+type ecmaLookaround struct {
+	node           ecmaNode
+	behind, negate bool
+}
+
+func (n *ecmaLookaround) match(m *ecmaMatcher, pos int, k func(int) bool) bool {
+	if !m.step() {
+		return false
+	}
+	saved := append([]int(nil), m.caps...)
+	matched := false
+	if n.behind {
+		for start := pos; start >= 0 && !matched; start-- {
+			matched = n.node.match(m, start, func(p int) bool { return p == pos })
+		}
+	} else {
+		matched = n.node.match(m, pos, func(int) bool { return true })
+	}
+
+	if matched == n.negate {
+		copy(m.caps, saved)
+		return false
+	}
+	if n.negate {
+		// groups within negative lookarounds never capture
+		copy(m.caps, saved)
+	}
+	if k(pos) {
+		return true
+	}
+	copy(m.caps, saved)
+	return false
+}
+
+// ecmaBackref matches the text last captured by a group
+type ecmaBackref struct {
+	index int
+	name  string
+}
+
+func (n *ecmaBackref) match(m *ecmaMatcher, pos int, k func(int) bool) bool {
+	if !m.step() {
+		return false
+	}
+	start, end := m.caps[2*n.index], m.caps[2*n.index+1]
+	if start < 0 || end < 0 {
+		// backreferences to groups that didn't participate match empty
+		return k(pos)
+	}
+	length := end - start
+	if pos+length > len(m.input) {
+		return false
+	}
+	for i := 0; i < length; i++ {
+		if m.input[start+i] != m.input[pos+i] {
+			return false
+		}
+	}
+	return k(pos + length)
+}
+
+// ecmaRepeat matches its node between min and max times, max being -1
+// for no upper bound. Groups within the node, from firstGroup up to but
+// excluding lastGroup, are reset on every iteration
+type ecmaRepeat struct {
+	node                  ecmaNode
+	min, max              int
+	greedy                bool
+	firstGroup, lastGroup int
+}
+
+func (n *ecmaRepeat) match(m *ecmaMatcher, pos int, k func(int) bool) bool {
+	return n.iterate(m, pos, 0, k)
+}
+
+func (n *ecmaRepeat) iterate(m *ecmaMatcher, pos, count int, k func(int) bool) bool {
+	if !m.step() {
+		return false
+	}
+	if n.max >= 0 && count >= n.max {
+		return k(pos)
+	}
+
+	next := func() bool {
+		groups := m.caps[2*n.firstGroup : 2*n.lastGroup]
+		saved := append([]int(nil), groups...)
+		for i := range groups {
+			groups[i] = -1
+		}
+		ok := n.node.match(m, pos, func(p int) bool {
+			if p == pos && count >= n.min {
+				// iterations matching the empty string end the repetition
+				return false
+			}
+			return n.iterate(m, p, count+1, k)
+		})
+		if !ok {
+			copy(groups, saved)
+		}
+		return ok
+	}
+
+	if count < n.min {
+		return next()
+	}
+	if n.greedy {
+		return next() || k(pos)
+	}
+	return k(pos) || next()
+}
+
+// ecmaParser parses the pattern grammar of ECMA-262, section 21.2.1,
+// with the unicode flag set
+type ecmaParser struct {
+	src []rune
+	pos int
+	// groups counts the capturing groups parsed so far
+	groups int
+	// names maps group names to their index
+	names    map[string]int
+	backrefs []*ecmaBackref
+}
+
+// parse parses the complete pattern
+func (p *ecmaParser) parse() (ecmaNode, error) {
+	node, err := p.parseDisjunction()
+	if err != nil {
+		return nil, err
+	}
+	if !p.eof() {
+		return nil, fmt.Errorf("unmatched ')' at offset %d", p.pos)
+	}
+
+	for _, ref := range p.backrefs {
+		if ref.name != "" {
+			index, ok := p.names[ref.name]
+			if !ok {
+				return nil, fmt.Errorf("reference to undefined group name %q", ref.name)
+			}
+			ref.index = index
+		} else if ref.index > p.groups {
+			return nil, fmt.Errorf("reference to undefined group %d", ref.index)
+		}
+	}
+	return node, nil
+}
+
+func (p *ecmaParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *ecmaParser) peek() rune {
+	if p.eof() {
+		return -1
+	}
+	return p.src[p.pos]
+}
+
+// lookingAt reports whether the remaining source starts with s
+func (p *ecmaParser) lookingAt(s string) bool {
+	return strings.HasPrefix(string(p.src[p.pos:]), s)
+}
+
+// parseDisjunction parses alternatives separated by '|'
+func (p *ecmaParser) parseDisjunction() (ecmaNode, error) {
+	var alts ecmaAlt
+	for {
+		alt, err := p.parseAlternative()
+		if err != nil {
+			return nil, err
+		}
+		alts = append(alts, alt)
+		if p.peek() != '|' {
+			break
+		}
+		p.pos++
+	}
+	if len(alts) == 1 {
+		return alts[0], nil
+	}
+	return alts, nil
+}
+
+// parseAlternative parses a sequence of terms
+func (p *ecmaParser) parseAlternative() (ecmaNode, error) {
+	var seq ecmaSeq
+	for !p.eof() && p.peek() != '|' && p.peek() != ')' {
+		term, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, term)
+	}
+	return seq, nil
+}
+
+// parseTerm parses an assertion or an atom followed by an optional quantifier
+func (p *ecmaParser) parseTerm() (ecmaNode, error) {
+	switch {
+	case p.peek() == '^':
+		p.pos++
+		return ecmaAssert(func(m *ecmaMatcher, pos int) bool { return pos == 0 }), nil
+	case p.peek() == '$':
+		p.pos++
+		return ecmaAssert(func(m *ecmaMatcher, pos int) bool { return pos == len(m.input) }), nil
+	case p.lookingAt(`\b`):
+		p.pos += 2
+		return ecmaAssert(isECMAWordBoundary), nil
+	case p.lookingAt(`\B`):
+		p.pos += 2
+		return ecmaAssert(func(m *ecmaMatcher, pos int) bool { return !isECMAWordBoundary(m, pos) }), nil
+	case p.lookingAt("(?="), p.lookingAt("(?!"), p.lookingAt("(?<="), p.lookingAt("(?<!"):
+		return p.parseLookaround()
+	}
+
+	firstGroup := p.groups + 1
+	atom, err := p.parseAtom()
+	if err != nil {
+		return nil, err
+	}
+	return p.parseQuantifier(atom, firstGroup)
+}
+
+// parseLookaround parses a lookahead or lookbehind assertion
+func (p *ecmaParser) parseLookaround() (ecmaNode, error) {
+	p.pos += 2
+	look := &ecmaLookaround{}
+	if p.peek() == '<' {
+		look.behind = true
+		p.pos++
+	}
+	look.negate = p.peek() == '!'
+	p.pos++
+
+	node, err := p.parseDisjunction()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ')' {
+		return nil, fmt.Errorf("missing ')' at offset %d", p.pos)
+	}
+	p.pos++
+	look.node = node
+
+	switch p.peek() {
+	case '*', '+', '?', '{':
+		return nil, fmt.Errorf("nothing to repeat at offset %d", p.pos)
+	}
+	return look, nil
+}
+
+// parseQuantifier parses an optional quantifier applying to atom
+func (p *ecmaParser) parseQuantifier(atom ecmaNode, firstGroup int) (ecmaNode, error) {
+	min, max := 0, 0
+	switch p.peek() {
+	case '*':
+		min, max = 0, -1
+		p.pos++
+	case '+':
+		min, max = 1, -1
+		p.pos++
+	case '?':
+		min, max = 0, 1
+		p.pos++
+	case '{':
+		var ok bool
+		var err error
+		if min, max, ok, err = p.parseBraceQuantifier(); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, fmt.Errorf("incomplete quantifier at offset %d", p.pos)
+		}
+	default:
+		return atom, nil
+	}
+
+	greedy := true
+	if p.peek() == '?' {
+		greedy = false
+		p.pos++
+	}
+	return &ecmaRepeat{node: atom, min: min, max: max, greedy: greedy, firstGroup: firstGroup, lastGroup: p.groups + 1}, nil
+}
+
+// parseBraceQuantifier parses {n}, {n,} and {n,m} quantifiers
+func (p *ecmaParser) parseBraceQuantifier() (min, max int, ok bool, err error) {
+	start := p.pos
+	p.pos++
+	if min, ok = p.parseDecimal(); !ok {
+		p.pos = start
+		return 0, 0, false, nil
+	}
+	max = min
+	if p.peek() == ',' {
+		p.pos++
+		max = -1
+		if p.peek() != '}' {
+			if max, ok = p.parseDecimal(); !ok {
+				p.pos = start
+				return 0, 0, false, nil
+			}
+		}
+	}
+	if p.peek() != '}' {
+		p.pos = start
+		return 0, 0, false, nil
+	}
+	p.pos++
+	if max >= 0 && max < min {
+		return 0, 0, false, fmt.Errorf("numbers out of order in quantifier at offset %d", start)
+	}
+	return min, max, true, nil
+}
+
+// parseDecimal parses a run of decimal digits
+func (p *ecmaParser) parseDecimal() (int, bool) {
+	start := p.pos
+	for !p.eof() && p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, false
+	}
+	n, err := strconv.Atoi(string(p.src[start:p.pos]))
+	if err != nil {
+		n = int(^uint(0) >> 1)
+	}
+	return n, true
+}
+
+// parseAtom parses a character, character class, escape or group
+func (p *ecmaParser) parseAtom() (ecmaNode, error) {
+	c := p.peek()
+	switch c {
+	case '.':
+		p.pos++
+		return ecmaChar(func(r rune) bool { return !isECMALineTerminator(r) }), nil
+	case '(':
+		return p.parseGroup()
+	case '[':
+		return p.parseClass()
+	case '\\':
+		return p.parseAtomEscape()
+	case '*', '+', '?', '{':
+		return nil, fmt.Errorf("nothing to repeat at offset %d", p.pos)
+	case ')', ']', '}':
+		return nil, fmt.Errorf("lone %q at offset %d", c, p.pos)
+	}
+	p.pos++
+	return ecmaLiteral(c), nil
+}
+
+// ecmaLiteral matches a single character
+func ecmaLiteral(c rune) ecmaNode {
+	return ecmaChar(func(r rune) bool { return r == c })
+}
+
+// parseGroup parses capturing, named and non-capturing groups
+func (p *ecmaParser) parseGroup() (ecmaNode, error) {
+	start := p.pos
+	p.pos++
+	capturing, name := true, ""
+	if p.lookingAt("?:") {
+		capturing = false
+		p.pos += 2
+	} else if p.lookingAt("?<") {
+		p.pos += 2
+		var err error
+		if name, err = p.parseGroupName(); err != nil {
+			return nil, err
+		}
+		if _, ok := p.names[name]; ok {
+			return nil, fmt.Errorf("duplicate group name %q", name)
+		}
+	} else if p.peek() == '?' {
+		return nil, fmt.Errorf("invalid group at offset %d", start)
+	}
+
+	index := 0
+	if capturing {
+		p.groups++
+		index = p.groups
+		if name != "" {
+			p.names[name] = index
+		}
+	}
+
+	node, err := p.parseDisjunction()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ')' {
+		return nil, fmt.Errorf("missing ')' for group at offset %d", start)
+	}
+	p.pos++
+
+	if !capturing {
+		return node, nil
+	}
+	return &ecmaGroup{index: index, node: node}, nil
+}
+
+// parseGroupName parses a group name terminated by '>'
+func (p *ecmaParser) parseGroupName() (string, error) {
+	start := p.pos
+	for !p.eof() && p.peek() != '>' {
+		r := p.peek()
+		if !(r == '$' || r == '_' || unicode.IsLetter(r) || p.pos > start && (unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r) || unicode.Is(unicode.Pc, r) || r == '\u200c' || r == '\u200d')) {
+			return "", fmt.Errorf("invalid group name at offset %d", start)
+		}
+		p.pos++
+	}
+	if p.eof() || p.pos == start {
+		return "", fmt.Errorf("invalid group name at offset %d", start)
+	}
+	name := string(p.src[start:p.pos])
+	p.pos++
+	return name, nil
+}
+
+// parseAtomEscape parses an escape outside of a character class
+func (p *ecmaParser) parseAtomEscape() (ecmaNode, error) {
+	start := p.pos
+	p.pos++
+	if p.eof() {
+		return nil, fmt.Errorf("trailing '\\' at offset %d", start)
+	}
+
+	c := p.peek()
+	switch {
+	case c >= '1' && c <= '9':
+		index, _ := p.parseDecimal()
+		ref := &ecmaBackref{index: index}
+		p.backrefs = append(p.backrefs, ref)
+		return ref, nil
+	case c == 'k':
+		p.pos++
+		if p.peek() != '<' {
+			return nil, fmt.Errorf("invalid named reference at offset %d", start)
+		}
+		p.pos++
+		name, err := p.parseGroupName()
+		if err != nil {
+			return nil, err
+		}
+		ref := &ecmaBackref{name: name}
+		p.backrefs = append(p.backrefs, ref)
+		return ref, nil
+	}
+
+	if class, ok, err := p.parseClassEscape(); err != nil {
+		return nil, err
+	} else if ok {
+		return ecmaChar(class), nil
+	}
+
+	r, err := p.parseCharacterEscape(false)
+	if err != nil {
+		return nil, err
+	}
+	return ecmaLiteral(r), nil
+}
+
+// parseClassEscape parses the character class escapes \d, \D, \s, \S, \w,
+// \W, \p{...} and \P{...} following a '\'
+func (p *ecmaParser) parseClassEscape() (func(rune) bool, bool, error) {
+	var class func(rune) bool
+	switch p.peek() {
+	case 'd', 'D':
+		class = isECMADigit
+	case 's', 'S':
+		class = isECMASpace
+	case 'w', 'W':
+		class = isECMAWordChar
+	case 'p', 'P':
+		negate := p.peek() == 'P'
+		p.pos++
+		class, err := p.parseUnicodeProperty()
+		if err != nil {
+			return nil, false, err
+		}
+		if negate {
+			return func(r rune) bool { return !class(r) }, true, nil
+		}
+		return class, true, nil
+	default:
+		return nil, false, nil
+	}
+
+	negate := unicode.IsUpper(p.peek())
+	p.pos++
+	if negate {
+		return func(r rune) bool { return !class(r) }, true, nil
+	}
+	return class, true, nil
+}
+
+// parseCharacterEscape parses an escape denoting a single character
+// following a '\'. Within classes '-' and 'b' may be escaped as well
+func (p *ecmaParser) parseCharacterEscape(inClass bool) (rune, error) {
+	start := p.pos - 1
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 't':
+		return '\t', nil
+	case 'n':
+		return '\n', nil
+	case 'v':
+		return '\v', nil
+	case 'f':
+		return '\f', nil
+	case 'r':
+		return '\r', nil
+	case 'c':
+		l := p.peek()
+		if l >= 'a' && l <= 'z' || l >= 'A' && l <= 'Z' {
+			p.pos++
+			return l % 32, nil
+		}
+		return 0, fmt.Errorf("invalid control escape at offset %d", start)
+	case '0':
+		if d := p.peek(); d >= '0' && d <= '9' {
+			return 0, fmt.Errorf("invalid decimal escape at offset %d", start)
+		}
+		return 0, nil
+	case 'x':
+		if r, ok := p.parseHex(2); ok {
+			return r, nil
+		}
+		return 0, fmt.Errorf("invalid hexadecimal escape at offset %d", start)
+	case 'u':
+		return p.parseUnicodeEscape(start)
+	case '^', '$', '\\', '.', '*', '+', '?', '(', ')', '[', ']', '{', '}', '|', '/':
+		return c, nil
+	case '-':
+		if inClass {
+			return c, nil
+		}
+	case 'b':
+		if inClass {
+			return '\b', nil
+		}
+	}
+	return 0, fmt.Errorf("invalid escape %q at offset %d", c, start)
+}
+
+// parseHex parses exactly n hexadecimal digits
+func (p *ecmaParser) parseHex(n int) (rune, bool) {
+	if p.pos+n > len(p.src) {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(string(p.src[p.pos:p.pos+n]), 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	p.pos += n
+	return rune(v), true
+}
+
+// parseUnicodeEscape parses \uXXXX, surrogate pairs of those and \u{X...}
+func (p *ecmaParser) parseUnicodeEscape(start int) (rune, error) {
+	if p.peek() == '{' {
+		p.pos++
+		end := p.pos
+		for end < len(p.src) && p.src[end] != '}' {
+			end++
+		}
+		if end == len(p.src) || end == p.pos {
+			return 0, fmt.Errorf("invalid unicode escape at offset %d", start)
+		}
+		v, err := strconv.ParseUint(string(p.src[p.pos:end]), 16, 32)
+		if err != nil || v > unicode.MaxRune {
+			return 0, fmt.Errorf("invalid unicode escape at offset %d", start)
+		}
+		p.pos = end + 1
+		return rune(v), nil
+	}
+
+	r, ok := p.parseHex(4)
+	if !ok {
+		return 0, fmt.Errorf("invalid unicode escape at offset %d", start)
+	}
+	if r >= 0xD800 && r <= 0xDBFF && p.lookingAt(`\u`) {
+		save := p.pos
+		p.pos += 2
+		if lo, ok := p.parseHex(4); ok && lo >= 0xDC00 && lo <= 0xDFFF {
+			return (r-0xD800)<<10 + (lo - 0xDC00) + 0x10000, nil
+		}
+		p.pos = save
+	}
+	return r, nil
+}
+
+// parseUnicodeProperty parses the {...} of a unicode property escape
+func (p *ecmaParser) parseUnicodeProperty() (func(rune) bool, error) {
+	start := p.pos
+	if p.peek() != '{' {
+		return nil, fmt.Errorf("invalid property escape at offset %d", start)
+	}
+	end := start
+	for end < len(p.src) && p.src[end] != '}' {
+		end++
+	}
+	if end == len(p.src) {
+		return nil, fmt.Errorf("invalid property escape at offset %d", start)
+	}
+	prop := string(p.src[start+1 : end])
+	p.pos = end + 1
+
+	class, ok := ecmaUnicodeProperty(prop)
+	if !ok {
+		return nil, fmt.Errorf("unknown unicode property %q", prop)
+	}
+	return class, nil
+}
+
+// ecmaCategoryNames maps the long names of general categories to the
+// short ones used by the unicode package
+var ecmaCategoryNames = map[string]string{
+	"Letter": "L", "Cased_Letter": "LC", "Uppercase_Letter": "Lu", "Lowercase_Letter": "Ll",
+	"Titlecase_Letter": "Lt", "Modifier_Letter": "Lm", "Other_Letter": "Lo",
+	"Mark": "M", "Combining_Mark": "M", "Nonspacing_Mark": "Mn", "Spacing_Mark": "Mc", "Enclosing_Mark": "Me",
+	"Number": "N", "Decimal_Number": "Nd", "digit": "Nd", "Letter_Number": "Nl", "Other_Number": "No",
+	"Punctuation": "P", "punct": "P", "Connector_Punctuation": "Pc", "Dash_Punctuation": "Pd",
+	"Open_Punctuation": "Ps", "Close_Punctuation": "Pe", "Initial_Punctuation": "Pi",
+	"Final_Punctuation": "Pf", "Other_Punctuation": "Po",
+	"Symbol": "S", "Math_Symbol": "Sm", "Currency_Symbol": "Sc", "Modifier_Symbol": "Sk", "Other_Symbol": "So",
+	"Separator": "Z", "Space_Separator": "Zs", "Line_Separator": "Zl", "Paragraph_Separator": "Zp",
+	"Other": "C", "Control": "Cc", "cntrl": "Cc", "Format": "Cf", "Surrogate": "Cs",
+	"Private_Use": "Co", "Unassigned": "Cn",
+}
+
+// ecmaUnicodeProperty returns the predicate of a unicode property as
+// written in \p{...}: a general category, a script or a binary property
+func ecmaUnicodeProperty(prop string) (func(rune) bool, bool) {
+	if i := strings.IndexByte(prop, '='); i >= 0 {
+		name, value := prop[:i], prop[i+1:]
+		switch name {
+		case "General_Category", "gc":
+			return ecmaGeneralCategory(value)
+		case "Script", "sc", "Script_Extensions", "scx":
+			if table, ok := unicode.Scripts[value]; ok {
+				return func(r rune) bool { return unicode.Is(table, r) }, true
+			}
+		}
+		return nil, false
+	}
+
+	if class, ok := ecmaGeneralCategory(prop); ok {
+		return class, true
+	}
+	switch prop {
+	case "Any":
+		return func(r rune) bool { return true }, true
+	case "ASCII":
+		return func(r rune) bool { return r < 0x80 }, true
+	case "Assigned":
+		return func(r rune) bool { return !isUnassigned(r) }, true
+	}
+	if table, ok := unicode.Properties[prop]; ok {
+		return func(r rune) bool { return unicode.Is(table, r) }, true
+	}
+	return nil, false
+}
+
+// ecmaGeneralCategory returns the predicate of a general category
+func ecmaGeneralCategory(name string) (func(rune) bool, bool) {
+	if short, ok := ecmaCategoryNames[name]; ok {
+		name = short
+	}
+	switch name {
+	case "LC":
+		return func(r rune) bool { return unicode.In(r, unicode.Lu, unicode.Ll, unicode.Lt) }, true
+	case "Cn":
+		return isUnassigned, true
+	case "C":
+		return func(r rune) bool { return unicode.Is(unicode.C, r) || isUnassigned(r) }, true
+	}
+	if table, ok := unicode.Categories[name]; ok {
+		return func(r rune) bool { return unicode.Is(table, r) }, true
+	}
+	return nil, false
+}
+
+// isUnassigned reports whether r isn't assigned to any general category
+func isUnassigned(r rune) bool {
+	for _, table := range unicode.Categories {
+		if unicode.Is(table, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseClass parses a character class
+func (p *ecmaParser) parseClass() (ecmaNode, error) {
+	start := p.pos
+	p.pos++
+	negate := false
+	if p.peek() == '^' {
+		negate = true
+		p.pos++
+	}
+
+	var members []func(rune) bool
+	for p.peek() != ']' {
+		if p.eof() {
+			return nil, fmt.Errorf("missing ']' for class at offset %d", start)
+		}
+		lo, loClass, err := p.parseClassAtom()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != '-' || p.pos+1 >= len(p.src) || p.src[p.pos+1] == ']' {
+			members = append(members, classMember(lo, loClass))
+			continue
+		}
+
+		p.pos++
+		hi, hiClass, err := p.parseClassAtom()
+		if err != nil {
+			return nil, err
+		}
+		if loClass != nil || hiClass != nil {
+			return nil, fmt.Errorf("invalid character class range at offset %d", start)
+		}
+		if lo > hi {
+			return nil, fmt.Errorf("range out of order in character class at offset %d", start)
+		}
+		members = append(members, func(r rune) bool { return r >= lo && r <= hi })
+	}
+	p.pos++
+
+	return ecmaChar(func(r rune) bool {
+		for _, member := range members {
+			if member(r) {
+				return !negate
+			}
+		}
+		return negate
+	}), nil
+}
+
+// parseClassAtom parses a single character or class escape of a class
+func (p *ecmaParser) parseClassAtom() (rune, func(rune) bool, error) {
+	c := p.peek()
+	p.pos++
+	if c != '\\' {
+		return c, nil, nil
+	}
+	if p.eof() {
+		return 0, nil, fmt.Errorf("trailing '\\' at offset %d", p.pos-1)
+	}
+	if class, ok, err := p.parseClassEscape(); err != nil {
+		return 0, nil, err
+	} else if ok {
+		return 0, class, nil
+	}
+	r, err := p.parseCharacterEscape(true)
+	return r, nil, err
+}
+
+// classMember returns the predicate of a single class atom
+func classMember(r rune, class func(rune) bool) func(rune) bool {
+	if class != nil {
+		return class
+	}
+	return func(c rune) bool { return c == r }
+}
+
+// isECMADigit matches the characters of \d
+func isECMADigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// isECMAWordChar matches the characters of \w
+func isECMAWordChar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_'
+}
+
+// isECMASpace matches the WhiteSpace and LineTerminator characters of \s
+func isECMASpace(r rune) bool {
+	switch r {
+	case '\t', '\n', '\v', '\f', '\r', '\ufeff':
+		return true
+	}
+	return isECMALineTerminator(r) || unicode.Is(unicode.Zs, r)
+}
+
+// isECMALineTerminator matches the characters '.' doesn't match
+func isECMALineTerminator(r rune) bool {
+	return r == '\n' || r == '\r' || r == '\u2028' || r == '\u2029'
+}
+
+// isECMAWordBoundary reports whether pos lies between a word and a
+// non-word character
+func isECMAWordBoundary(m *ecmaMatcher, pos int) bool {
+	before := pos > 0 && isECMAWordChar(m.input[pos-1])
+	after := pos < len(m.input) && isECMAWordChar(m.input[pos])
+	return before != after
+}
@@ -0,0 +1,203 @@
+package jsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestECMAScriptRegexp(t *testing.T) {
+	cases := []struct {
+		expr, input string
+		match       bool
+	}{
+		{`^\d+$`, "42", true},
+		{`^\d+$`, "٤٢", false},
+		{`^\w+$`, "héllo", false},
+		{`^\p{L}+$`, "héllo", true},
+		{`^\P{L}+$`, "héllo", false},
+		{`^\p{Script=Greek}+$`, "αβγ", true},
+		{`^\s$`, "\u00a0", true},
+		{`^\s$`, "\u2028", true},
+		{`^.$`, "\n", false},
+		{`^[^]$`, "\n", true},
+		{`^\u{1F600}$`, "😀", true},
+		{`^😀$`, "😀", true},
+		{`^\cJ$`, "\n", true},
+		{`foo(?=bar)`, "foobar", true},
+		{`foo(?=bar)`, "foobaz", false},
+		{`foo(?!bar)`, "foobar", false},
+		{`(?<=\$)\d+`, "$42", true},
+		{`(?<!\$)\b\d+`, "$42", false},
+		{`^(a+)b\1$`, "aabaa", true},
+		{`^(a+)b\1$`, "aaba", false},
+		{`^(?<q>['"]).*\k<q>$`, `"quoted"`, true},
+		{`^(?<q>['"]).*\k<q>$`, `"quoted'`, false},
+		{`^(?:a|b)*?c$`, "ababc", true},
+		{`^(a*)*$`, "aaaa", true},
+		{`^(a|ab)(c|bcd)(d*)$`, "abcd", true},
+		{`^a{2,3}$`, "aaaa", false},
+		{`^[\w-]+$`, "a-b_c", true},
+		{`\bfoo\b`, "a foo b", true},
+		{`\Bfoo`, "a foo b", false},
+	}
+	for _, c := range cases {
+		re, err := ECMAScriptEngine.Compile(c.expr)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", c.expr, err)
+			continue
+		}
+		if got := re.MatchString(c.input); got != c.match {
+			t.Errorf("%s: expected match of %q to be %t", c.expr, c.input, c.match)
+		}
+	}
+
+	invalid := []string{
+		`\Z`,
+		`\a`,
+		`(?<name>a)(?<name>b)`,
+		`\k<missing>`,
+		`(a)\2`,
+		`a{3,2}`,
+		`a**`,
+		`[z-a]`,
+		`(?i)a`,
+		`a{`,
+		`a)`,
+		`\p{Unknown}`,
+		`(?=a)*`,
+	}
+	for _, expr := range invalid {
+		if _, err := ECMAScriptEngine.Compile(expr); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
+
+func TestECMAScriptStepLimit(t *testing.T) {
+	re, err := ECMAScriptEngine.Compile(`^(?:a+)+$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := strings.Repeat("a", 26) + "!"
+	start := time.Now()
+	matched, err := re.(*ecmaRegexp).MatchStringError(input)
+	if matched || !errors.Is(err, ErrMatchStepLimit) {
+		t.Errorf("expected matching to exceed the step limit. got: %t, %v", matched, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected matching to give up early. took: %s", elapsed)
+	}
+	if re.MatchString(input) {
+		t.Errorf("expected no match once the step limit is exceeded")
+	}
+
+	unlimited, err := NewECMAScriptEngine(0).Compile(`^(?:a+)+$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matched, err := unlimited.(*ecmaRegexp).MatchStringError("aaaaaaaa!"); matched || err != nil {
+		t.Errorf("expected no match without error. got: %t, %v", matched, err)
+	}
+
+	r := NewKeywordRegistry()
+	r.SetRegexpEngine(ECMAScriptEngine)
+	rs := &Schema{}
+	if err := rs.unmarshalJSONWithRegistry([]byte(`{
+		"pattern": "^(?:a+)+$",
+		"patternProperties": {"^(?:a+)+$": true}
+	}`), r); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, doc := range []string{fmt.Sprintf("%q", input), fmt.Sprintf(`{%q: 1}`, input)} {
+		errs, err := rs.ValidateBytes(ctx, []byte(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(errs) != 1 || !strings.Contains(errs[0].Message, ErrMatchStepLimit.Error()) {
+			t.Errorf("expected a step limit error. got: %v", errs)
+		}
+	}
+}
+
+func TestSetRegexpEngine(t *testing.T) {
+	LoadDraft2019_09()
+	SetRegexpEngine(ECMAScriptEngine)
+	defer SetRegexpEngine(RE2Engine)
+
+	runJSONTests(t, []string{
+		"testdata/draft4/optional/ecmascript-regex.json",
+		"testdata/draft6/optional/ecmascript-regex.json",
+		"testdata/draft7/optional/ecmascript-regex.json",
+		"testdata/draft2019-09/optional/ecmascript-regex.json",
+	})
+
+	LoadDraft2020_12()
+	runJSONTests(t, []string{
+		"testdata/draft2020-12/optional/ecmascript-regex.json",
+	})
+	LoadDraft2019_09()
+
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{
+		"patternProperties": {"^(?<prefix>x|y)-\\k<prefix>$": {"type": "integer"}}
+	}`), rs); err != nil {
+		t.Fatal(err)
+	}
+	if errs, err := rs.ValidateBytes(context.Background(), []byte(`{"x-x": "a", "x-y": "b"}`)); err != nil {
+		t.Fatal(err)
+	} else if len(errs) != 1 {
+		t.Errorf("expected 1 error. got: %v", errs)
+	}
+}
+
+// unicodeClassEngine compiles patterns with RE2, giving \d, \D, \w and \W
+// the Unicode semantics the optional/unicode.json test suite files expect
+// where ECMA-262 and RE2 restrict them to ASCII
+type unicodeClassEngine struct{}
+
+func (unicodeClassEngine) Compile(expr string) (Regexp, error) {
+	var b strings.Builder
+	inClass := false
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		if c == '\\' && i+1 < len(expr) {
+			i++
+			switch expr[i] {
+			case 'd':
+				b.WriteString(`\p{Nd}`)
+			case 'D':
+				b.WriteString(`\P{Nd}`)
+			case 'w':
+				if inClass {
+					b.WriteString(`\p{L}\p{N}_`)
+				} else {
+					b.WriteString(`[\p{L}\p{N}_]`)
+				}
+			case 'W':
+				if inClass {
+					b.WriteString(`\W`)
+				} else {
+					b.WriteString(`[^\p{L}\p{N}_]`)
+				}
+			default:
+				b.WriteByte(c)
+				b.WriteByte(expr[i])
+			}
+			continue
+		}
+		switch c {
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		}
+		b.WriteByte(c)
+	}
+	return RE2Engine.Compile(b.String())
+}
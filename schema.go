@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"sort"
 	"strings"
+	"sync"
 
 	jptr "github.com/qri-io/jsonpointer"
 )
@@ -31,9 +32,12 @@ const (
 
 // Schema is the top-level structure defining a json schema
 type Schema struct {
-	schemaType    schemaType
-	docPath       string
-	hasRegistered bool
+	schemaType schemaType
+	docPath    string
+	// registeredWith holds the registries the schema registered with
+	registeredWith map[*SchemaRegistry]bool
+	// scopeURI is the URI the schema registered its anchors by
+	scopeURI string
 
 	id string
 
@@ -54,35 +58,83 @@ func (s *Schema) HasKeyword(key string) bool {
 	return ok
 }
 
+// dynamicAnchor returns the name of the $dynamicAnchor declared
+// by the schema or an empty string if there is none
+func (s *Schema) dynamicAnchor() string {
+	if anchor, ok := s.keywords["$dynamicAnchor"].(*DynamicAnchor); ok {
+		return string(*anchor)
+	}
+	return ""
+}
+
+// registrationLock guards the registries schemas registered with, as the
+// same schema may be validated concurrently with different registries
+var registrationLock sync.Mutex
+
+// markRegistered records the schema registering with the root of registry
+// by uri, reporting whether it did already. A schema keeps the URI it
+// registers by with the first registry, which scope returns along with
+// whether the schema is a resource registered by it
+func (s *Schema) markRegistered(uri string, registry *SchemaRegistry) (registered bool, scope string, resource bool) {
+	registrationLock.Lock()
+	defer registrationLock.Unlock()
+	root := registry.root()
+	if s.registeredWith[root] {
+		return true, "", false
+	}
+	if s.registeredWith == nil {
+		s.registeredWith = map[*SchemaRegistry]bool{}
+	}
+	s.registeredWith[root] = true
+	registry.RegisterLocal(s)
+	if len(s.registeredWith) > 1 {
+		return false, s.scopeURI, s.scopeURI != "" && s.scopeURI == s.docPath
+	}
+
+	address := s.id
+	if uri != "" && address != "" {
+		address, _ = SafeResolveURL(uri, address)
+	}
+	if s.docPath == "" && address != "" && address[0] != '#' {
+		if u, err := url.Parse(address); err != nil {
+			s.docPath, _ = SafeResolveURL("https://qri.io", address)
+		} else {
+			s.docPath = u.String()
+		}
+		uri = s.docPath
+		resource = true
+	}
+	s.scopeURI = uri
+	return false, uri, resource
+}
+
 // Register implements the Keyword interface for Schema
 func (s *Schema) Register(uri string, registry *SchemaRegistry) {
 	schemaDebug("[Schema] Register")
-	if s.hasRegistered {
+	registered, uri, resource := s.markRegistered(uri, registry)
+	if registered {
 		return
 	}
-	s.hasRegistered = true
-	registry.RegisterLocal(s)
 
 	// load default keyset if no other is present
 	globalRegistry, release := getGlobalKeywordRegistry()
 	globalRegistry.DefaultIfEmpty()
 	release()
 
-	address := s.id
-	if uri != "" && address != "" {
-		address, _ = SafeResolveURL(uri, address)
+	if resource {
+		registry.root().Register(s)
 	}
-	if s.docPath == "" && address != "" && address[0] != '#' {
-		docURI := ""
-		if u, err := url.Parse(address); err != nil {
-			docURI, _ = SafeResolveURL("https://qri.io", address)
-		} else {
-			docURI = u.String()
-		}
-		s.docPath = docURI
-		GetSchemaRegistry().Register(s)
-		uri = docURI
+	s.registerContents(uri, registry)
+}
+
+// registerContents registers the anchors of the schema, scoped to uri, and
+// the schemas of its keywords with registry
+func (s *Schema) registerContents(uri string, registry *SchemaRegistry) {
+	// anchors are scoped to the enclosing schema resource
+	if anchor, ok := s.keywords["$anchor"].(*Anchor); ok {
+		registry.root().RegisterAnchor(uri, string(*anchor), s)
 	}
+	registry.root().RegisterAnchor(uri, s.dynamicAnchor(), s)
 
 	for _, keyword := range s.keywords {
 		keyword.Register(uri, registry)
@@ -103,7 +155,10 @@ func (s *Schema) Resolve(pointer jptr.Pointer, uri string) *Schema {
 	current := pointer.Head()
 
 	if s.id != "" {
-		if u, err := url.Parse(s.id); err == nil {
+		if u, err := url.Parse(s.docPath); err == nil && u.IsAbs() {
+			// a registered schema resource knows its absolute URI
+			uri = s.docPath
+		} else if u, err := url.Parse(s.id); err == nil {
 			if u.IsAbs() {
 				uri = s.id
 			} else {
@@ -122,19 +177,18 @@ func (s *Schema) Resolve(pointer jptr.Pointer, uri string) *Schema {
 		return keywordSchema
 	}
 
-	found, err := pointer.Eval(s.extraDefinitions)
-	if err != nil {
+	// unknown keywords may still be the target of a reference,
+	// in which case they are interpreted as schemas on demand
+	raw, ok := s.extraDefinitions[*current]
+	if !ok {
 		return nil
 	}
-	if found == nil {
+	extra := &Schema{}
+	if err := json.Unmarshal(raw, extra); err != nil {
 		return nil
 	}
 
-	if foundSchema, ok := found.(*Schema); ok {
-		return foundSchema
-	}
-
-	return nil
+	return extra.Resolve(pointer.Tail(), uri)
 }
 
 // JSONProp implements the JSONPather for Schema
@@ -160,13 +214,37 @@ func (s Schema) JSONChildren() map[string]JSONPather {
 	return ch
 }
 
-// _schema is an internal struct for encoding & decoding purposes
-type _schema struct {
-	ID string `json:"$id,omitempty"`
+// UnmarshalJSON implements the json.Unmarshaler interface for Schema.
+// Custom meta-schemas named by "$schema" are resolved with the global
+// SchemaRegistry, use UnmarshalSchema to resolve them with another one
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	return s.unmarshalJSONWithRegistry(data, nil)
 }
 
-// UnmarshalJSON implements the json.Unmarshaler interface for Schema
-func (s *Schema) UnmarshalJSON(data []byte) error {
+// UnmarshalSchema parses a JSON schema document into s, resolving custom
+// meta-schemas named by "$schema" with the schema registry of ctx, see
+// WithSchemaRegistry, and fetching them if they aren't registered. A
+// "$schema" which is neither a known dialect nor resolvable is an error
+func UnmarshalSchema(ctx context.Context, data []byte, s *Schema) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	keywordRegistry := copyGlobalKeywordRegistry()
+	keywordRegistry.DefaultIfEmpty()
+	keywordRegistry.metaSchemaCtx = ctx
+	if err := s.unmarshalJSONWithRegistry(data, keywordRegistry); err != nil {
+		return err
+	}
+	if doc, ok := ctx.Value(fetchedDocCtxKey{}).(*[]byte); ok {
+		*doc = data
+	}
+	return nil
+}
+
+// unmarshalJSONWithRegistry decodes the schema with the keyword registry
+// of its parent schema, falling back to the global keyword set for top
+// level schemas with a nil registry
+func (s *Schema) unmarshalJSONWithRegistry(data []byte, keywordRegistry *KeywordRegistry) error {
 	var b bool
 	if err := json.Unmarshal(data, &b); err == nil {
 		if b {
@@ -179,22 +257,45 @@ func (s *Schema) UnmarshalJSON(data []byte) error {
 		return nil
 	}
 
-	keywordRegistry := copyGlobalKeywordRegistry()
-	keywordRegistry.DefaultIfEmpty()
+	if keywordRegistry == nil {
+		keywordRegistry = copyGlobalKeywordRegistry()
+		keywordRegistry.DefaultIfEmpty()
+	}
 
-	_s := _schema{}
-	if err := json.Unmarshal(data, &_s); err != nil {
+	valprops := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &valprops); err != nil {
 		return err
 	}
 
 	sch := &Schema{
-		id:       _s.ID,
 		keywords: map[string]Keyword{},
 	}
 
-	valprops := map[string]json.RawMessage{}
-	if err := json.Unmarshal(data, &valprops); err != nil {
-		return err
+	if rawSchemaURI, ok := valprops["$schema"]; ok {
+		var schemaURI string
+		if err := json.Unmarshal(rawSchemaURI, &schemaURI); err == nil {
+			if IsKnownDialect(schemaURI) {
+				keywordRegistry = keywordRegistry.Copy()
+				keywordRegistry.LoadDialect(schemaURI)
+			} else if !describesItself(valprops, schemaURI) {
+				meta, err := keywordRegistry.metaSchema(schemaURI)
+				if err != nil {
+					return err
+				}
+				if meta != nil {
+					keywordRegistry = keywordRegistry.Copy()
+					if err := keywordRegistry.loadMetaSchema(meta); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	if rawID, ok := valprops[keywordRegistry.IDKeyword()]; ok {
+		if err := json.Unmarshal(rawID, &sch.id); err != nil {
+			return err
+		}
 	}
 
 	for prop, rawmsg := range valprops {
@@ -212,7 +313,7 @@ func (s *Schema) UnmarshalJSON(data []byte) error {
 			continue
 		}
 		if _, ok := keyword.(*Void); !ok {
-			if err := json.Unmarshal(rawmsg, keyword); err != nil {
+			if err := unmarshalKeyword(rawmsg, keyword, keywordRegistry); err != nil {
 				return fmt.Errorf("error unmarshaling %s from json: %s", prop, err.Error())
 			}
 		}
@@ -243,6 +344,14 @@ func (s *Schema) UnmarshalJSON(data []byte) error {
 	}
 	sch.orderedkeywords = orderedKeys
 
+	if keywordRegistry.refOverridesSiblings && sch.HasKeyword("$ref") {
+		// sibling keywords are kept for resolving references into
+		// the schema but are neither validated nor allowed to change
+		// the base URI
+		sch.id = ""
+		sch.orderedkeywords = []string{"$ref"}
+	}
+
 	*s = Schema(*sch)
 	return nil
 }
@@ -253,10 +362,17 @@ type _keyOrder struct {
 	Order int
 }
 
-// Validate initiates a fresh validation state and triggers the evaluation
+// Validate initiates a fresh validation state and triggers the evaluation.
+// Validate resolves references into the schema as it goes, so a schema
+// must not be validated concurrently, use Compile instead
 func (s *Schema) Validate(ctx context.Context, data interface{}) *ValidationState {
-	currentState := NewValidationState(s)
-	s.ValidateKeyword(ctx, currentState, data)
+	currentState := newContextValidationState(ctx, s)
+	doc, err := jsonValue(data)
+	if err != nil {
+		currentState.AddError(data, fmt.Sprintf("invalid instance: %s", err.Error()))
+		return currentState
+	}
+	s.ValidateKeyword(ctx, currentState, doc)
 	return currentState
 }
 
@@ -264,6 +380,17 @@ func (s *Schema) Validate(ctx context.Context, data interface{}) *ValidationStat
 // errors in a slice
 func (s *Schema) ValidateKeyword(ctx context.Context, currentState *ValidationState, data interface{}) {
 	schemaDebug("[Schema] Validating")
+	defer currentState.enterEvaluation()()
+	keyword := currentState.keyword
+	currentState.keyword = ""
+	defer func() {
+		currentState.keyword = keyword
+	}()
+	if currentState.Annotations != nil && currentState.Errs != nil {
+		// a schema failing validation produces no annotations
+		defer currentState.discardAnnotations(len(*currentState.Annotations), len(*currentState.Errs))
+	}
+
 	if s == nil {
 		currentState.AddError(data, fmt.Sprintf("schema is nil"))
 		return
@@ -276,23 +403,39 @@ func (s *Schema) ValidateKeyword(ctx context.Context, currentState *ValidationSt
 		return
 	}
 
+	s.enterScope(currentState)
+
+	if s.docPath != "" {
+		// entering a schema resource extends the dynamic scope
+		// for the duration of its evaluation
+		dynamicScope := currentState.DynamicScope
+		currentState.DynamicScope = append(dynamicScope[:len(dynamicScope):len(dynamicScope)], currentState.BaseURI)
+		defer func() {
+			currentState.DynamicScope = dynamicScope
+		}()
+	}
+
+	s.validateSchemakeywords(ctx, currentState, data)
+}
+
+// enterScope registers the schema and points the state at it, updating
+// the base URI if the schema identifies a schema resource
+func (s *Schema) enterScope(currentState *ValidationState) {
 	s.Register("", currentState.LocalRegistry)
 	currentState.LocalRegistry.RegisterLocal(s)
 
 	currentState.Local = s
+	// properties evaluated by sibling keywords are tracked per schema
+	currentState.LocalEvaluatedPropertyNames = &map[string]bool{}
 
-	refKeyword := s.keywords["$ref"]
-
-	if refKeyword == nil {
-		if currentState.BaseURI == "" {
-			currentState.BaseURI = s.docPath
-		} else if s.docPath != "" {
-			if u, err := url.Parse(s.docPath); err == nil {
-				if u.IsAbs() {
-					currentState.BaseURI = s.docPath
-				} else {
-					currentState.BaseURI, _ = SafeResolveURL(currentState.BaseURI, s.docPath)
-				}
+	if currentState.BaseURI == "" {
+		currentState.BaseURI = s.docPath
+	} else if s.docPath != "" {
+		if u, err := url.Parse(s.docPath); err == nil {
+			if u.IsAbs() {
+				currentState.BaseURI = s.docPath
+			} else {
+				currentState.BaseURI, _ = SafeResolveURL(currentState.BaseURI, s.docPath)
 			}
 		}
 	}
@@ -301,20 +444,20 @@ func (s *Schema) ValidateKeyword(ctx context.Context, currentState *ValidationSt
 		currentState.BaseURI = strings.TrimRight(currentState.BaseURI, "#")
 	}
 
-	// TODO(arqu): only on versions bellow draft2019_09
-	// if refKeyword != nil {
-	// 	refKeyword.ValidateKeyword(currentState, errs)
-	// 	return
-	// }
-
-	s.validateSchemakeywords(ctx, currentState, data)
+	if s.id != "" && s.id[0] != '#' {
+		// locations within an embedded schema resource are relative to it
+		currentState.BaseRelativeLocation = &jptr.Pointer{}
+	}
 }
 
 // validateSchemakeywords triggers validation of sub schemas and keywords
 func (s *Schema) validateSchemakeywords(ctx context.Context, currentState *ValidationState, data interface{}) {
 	if s.keywords != nil {
 		for _, keyword := range s.orderedkeywords {
+			currentState.keyword = keyword
+			done := currentState.enterEvaluation(keyword)
 			s.keywords[keyword].ValidateKeyword(ctx, currentState, data)
+			done()
 		}
 	}
 }
@@ -323,7 +466,7 @@ func (s *Schema) validateSchemakeywords(ctx context.Context, currentState *Valid
 // byte data
 func (s *Schema) ValidateBytes(ctx context.Context, data []byte) ([]KeyError, error) {
 	var doc interface{}
-	if err := json.Unmarshal(data, &doc); err != nil {
+	if err := decodeJSON(data, &doc); err != nil {
 		return nil, fmt.Errorf("error parsing JSON bytes: %w", err)
 	}
 	vs := s.Validate(ctx, doc)
@@ -0,0 +1,270 @@
+package jsonschema
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SchemaCacheOptions configures a SchemaCache
+type SchemaCacheOptions struct {
+	// Dir is the directory fetched schemas are persisted to, sharing
+	// them between processes. Empty keeps schemas in memory only
+	Dir string
+	// TTL is how long a fetched schema is served without revalidation
+	// when its response states no freshness with Cache-Control or
+	// Expires headers. Zero revalidates on every fetch
+	TTL time.Duration
+}
+
+// SchemaCache keeps schemas fetched over http, see HTTPLoaderOptions. A
+// cached schema is served without a request while fresh, as stated by the
+// Cache-Control max-age or Expires headers of its response or else the
+// TTL of the cache. Stale schemas are revalidated with a conditional
+// request using their ETag or Last-Modified headers. Responses with
+// Cache-Control no-store aren't cached. While network access is denied,
+// see SetDenyNetwork, cached schemas are served regardless of freshness.
+// SchemaCache is safe for concurrent use
+type SchemaCache struct {
+	dir string
+	ttl time.Duration
+	// now returns the current time, replaced in tests
+	now func() time.Time
+
+	lock    sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// cacheEntry is a cached schema along with the headers needed to
+// revalidate it
+type cacheEntry struct {
+	URI          string          `json:"uri"`
+	ETag         string          `json:"etag,omitempty"`
+	LastModified string          `json:"lastModified,omitempty"`
+	Expires      time.Time       `json:"expires"`
+	Body         json.RawMessage `json:"body"`
+}
+
+// NewSchemaCache creates a SchemaCache, creating its directory if needed
+func NewSchemaCache(opts SchemaCacheOptions) (*SchemaCache, error) {
+	if opts.Dir != "" {
+		if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &SchemaCache{
+		dir:     opts.Dir,
+		ttl:     opts.TTL,
+		now:     time.Now,
+		entries: map[string]*cacheEntry{},
+	}, nil
+}
+
+// Prewarm fetches the schemas at uris into the cache, using opts to send
+// requests for schemas that aren't cached or are stale
+func (c *SchemaCache) Prewarm(ctx context.Context, opts HTTPLoaderOptions, uris ...string) error {
+	opts.Cache = c
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return err
+		}
+		body, err := fetchHTTP(ctx, u, opts)
+		if err != nil {
+			return err
+		}
+		if !json.Valid(body) {
+			return fmt.Errorf("fetching %s: invalid JSON", uri)
+		}
+	}
+	return nil
+}
+
+// Purge removes the schemas at uris from the cache, or all cached schemas
+// when no uris are given
+func (c *SchemaCache) Purge(uris ...string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(uris) > 0 {
+		for _, uri := range uris {
+			delete(c.entries, uri)
+			if c.dir == "" {
+				continue
+			}
+			if err := os.Remove(c.path(uri)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	c.entries = map[string]*cacheEntry{}
+	if c.dir == "" {
+		return nil
+	}
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if isCacheFile(f.Name()) {
+			if err := os.Remove(filepath.Join(c.dir, f.Name())); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// get returns the cached entry for uri, reading it from the cache
+// directory if needed
+func (c *SchemaCache) get(uri string) *cacheEntry {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if entry, ok := c.entries[uri]; ok {
+		return entry
+	}
+	if c.dir == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(c.path(uri))
+	if err != nil {
+		return nil
+	}
+	entry := &cacheEntry{}
+	if err := json.Unmarshal(data, entry); err != nil || entry.URI != uri {
+		schemaDebug(fmt.Sprintf("[SchemaCache] Ignoring invalid cache entry for %s", uri))
+		return nil
+	}
+	c.entries[uri] = entry
+	return entry
+}
+
+// fresh reports whether entry may be served without revalidation
+func (c *SchemaCache) fresh(entry *cacheEntry) bool {
+	return c.now().Before(entry.Expires)
+}
+
+// store caches the body of a response for uri as its headers permit
+func (c *SchemaCache) store(uri string, body []byte, header http.Header) error {
+	expires, ok := c.expires(header)
+	if !ok || !json.Valid(body) {
+		return c.Purge(uri)
+	}
+	return c.put(&cacheEntry{
+		URI:          uri,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		Expires:      expires,
+		Body:         body,
+	})
+}
+
+// revalidate refreshes a cached entry confirmed by a not modified response
+func (c *SchemaCache) revalidate(entry *cacheEntry, header http.Header) error {
+	expires, ok := c.expires(header)
+	if !ok {
+		return c.Purge(entry.URI)
+	}
+	updated := *entry
+	updated.Expires = expires
+	if etag := header.Get("ETag"); etag != "" {
+		updated.ETag = etag
+	}
+	if lastModified := header.Get("Last-Modified"); lastModified != "" {
+		updated.LastModified = lastModified
+	}
+	return c.put(&updated)
+}
+
+// put stores entry in memory and the cache directory
+func (c *SchemaCache) put(entry *cacheEntry) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.entries[entry.URI] = entry
+	if c.dir == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(c.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), c.path(entry.URI))
+}
+
+// expires returns the time a response stays fresh until, reporting false
+// when it may not be cached
+func (c *SchemaCache) expires(header http.Header) (time.Time, bool) {
+	now := c.now()
+	directives := map[string]string{}
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, val := strings.TrimSpace(directive), ""
+		if i := strings.Index(name, "="); i >= 0 {
+			name, val = name[:i], strings.Trim(name[i+1:], `"`)
+		}
+		directives[strings.ToLower(name)] = val
+	}
+
+	if _, ok := directives["no-store"]; ok {
+		return time.Time{}, false
+	}
+	if _, ok := directives["no-cache"]; ok {
+		return now, true
+	}
+	if maxAge, ok := directives["max-age"]; ok {
+		if secs, err := strconv.ParseInt(maxAge, 10, 64); err == nil && secs >= 0 {
+			return now.Add(time.Duration(secs) * time.Second), true
+		}
+		return now, true
+	}
+	if expires := header.Get("Expires"); expires != "" {
+		if t, err := http.ParseTime(expires); err == nil {
+			return t, true
+		}
+		return now, true
+	}
+	return now.Add(c.ttl), true
+}
+
+// path returns the file in the cache directory holding the entry for uri
+func (c *SchemaCache) path(uri string) string {
+	sum := sha256.Sum256([]byte(uri))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// isCacheFile reports whether name is a file written by a SchemaCache
+func isCacheFile(name string) bool {
+	if !strings.HasSuffix(name, ".json") || len(name) != sha256.Size*2+len(".json") {
+		return false
+	}
+	_, err := hex.DecodeString(strings.TrimSuffix(name, ".json"))
+	return err == nil
+}
@@ -0,0 +1,178 @@
+package jsonschema
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// cacheTestServer serves schemas counting requests and conditional
+// requests answered with not modified
+type cacheTestServer struct {
+	*httptest.Server
+	lock        sync.Mutex
+	requests    int
+	notModified int
+	version     int
+}
+
+func newCacheTestServer() *cacheTestServer {
+	s := &cacheTestServer{version: 1}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.requests++
+
+		etag := fmt.Sprintf(`"v%d"`, s.version)
+		switch r.URL.Path {
+		case "/max-age.json":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/no-store.json":
+			w.Header().Set("Cache-Control", "no-store")
+		case "/no-cache.json":
+			w.Header().Set("Cache-Control", "no-cache")
+		case "/expires.json":
+			w.Header().Set("Expires", time.Date(2020, 1, 1, 0, 1, 0, 0, time.UTC).Format(http.TimeFormat))
+		}
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			s.notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprintf(w, `{"type": "string", "description": "version %d"}`, s.version)
+	}))
+	return s
+}
+
+func (s *cacheTestServer) counts() (int, int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.requests, s.notModified
+}
+
+func TestSchemaCache(t *testing.T) {
+	ctx := context.Background()
+	ts := newCacheTestServer()
+	defer ts.Close()
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	newCache := func(opts SchemaCacheOptions) *SchemaCache {
+		cache, err := NewSchemaCache(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cache.now = func() time.Time { return now }
+		return cache
+	}
+	fetch := func(cache *SchemaCache, path string) string {
+		u, err := url.Parse(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs := &Schema{}
+		if err := NewHTTPSchemaLoader(HTTPLoaderOptions{Cache: cache})(ctx, u, rs); err != nil {
+			t.Fatal(err)
+		}
+		return string(*rs.JSONProp("description").(*Description))
+	}
+
+	cases := []struct {
+		path string
+		ttl  time.Duration
+		// requests and notModified expected after fetching once, again
+		// right away and again after two minutes
+		requests, notModified [3]int
+	}{
+		{"/max-age.json", 0, [3]int{1, 1, 2}, [3]int{0, 0, 1}},
+		{"/expires.json", 0, [3]int{1, 1, 2}, [3]int{0, 0, 1}},
+		{"/no-store.json", time.Hour, [3]int{1, 2, 3}, [3]int{0, 0, 0}},
+		{"/no-cache.json", time.Hour, [3]int{1, 2, 3}, [3]int{0, 1, 2}},
+		{"/ttl.json", 0, [3]int{1, 2, 3}, [3]int{0, 1, 2}},
+		{"/ttl.json", time.Minute, [3]int{1, 1, 2}, [3]int{0, 0, 1}},
+		{"/ttl.json", time.Hour, [3]int{1, 1, 1}, [3]int{0, 0, 0}},
+	}
+	for i, c := range cases {
+		now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		cache := newCache(SchemaCacheOptions{TTL: c.ttl})
+		reqs, notModified := ts.counts()
+		for j := 0; j < 3; j++ {
+			if j == 2 {
+				now = now.Add(2 * time.Minute)
+			}
+			if desc := fetch(cache, c.path); desc != "version 1" {
+				t.Errorf("case %d %s: unexpected schema: %s", i, c.path, desc)
+			}
+			r, n := ts.counts()
+			if r-reqs != c.requests[j] || n-notModified != c.notModified[j] {
+				t.Errorf("case %d %s fetch %d: expected %d requests and %d not modified. got: %d, %d", i, c.path, j, c.requests[j], c.notModified[j], r-reqs, n-notModified)
+			}
+		}
+	}
+
+	dir := filepath.Join(t.TempDir(), "cache")
+	now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newCache(SchemaCacheOptions{Dir: dir, TTL: time.Hour})
+	if err := cache.Prewarm(ctx, HTTPLoaderOptions{}, ts.URL+"/a.json", ts.URL+"/b.json"); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("expected 2 cached files. got: %d", len(files))
+	}
+
+	// another process reads the persisted schemas without fetching them
+	reqs, _ := ts.counts()
+	persisted := newCache(SchemaCacheOptions{Dir: dir, TTL: time.Hour})
+	if desc := fetch(persisted, "/a.json"); desc != "version 1" {
+		t.Errorf("unexpected schema: %s", desc)
+	}
+	if r, _ := ts.counts(); r != reqs {
+		t.Errorf("expected persisted schemas to be served without requests")
+	}
+
+	// stale schemas are served while network access is denied
+	now = now.Add(2 * time.Hour)
+	u, err := url.Parse(ts.URL + "/a.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	denied := context.WithValue(ctx, networkDeniedCtxKey{}, true)
+	if err := NewHTTPSchemaLoader(HTTPLoaderOptions{Cache: persisted})(denied, u, &Schema{}); err != nil {
+		t.Errorf("expected a stale schema to be served offline: %s", err)
+	}
+
+	// changed schemas replace stale ones
+	ts.lock.Lock()
+	ts.version = 2
+	ts.lock.Unlock()
+	if desc := fetch(persisted, "/a.json"); desc != "version 2" {
+		t.Errorf("expected the changed schema. got: %s", desc)
+	}
+
+	if err := persisted.Purge(ts.URL + "/a.json"); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("expected 1 cached file after purging a schema. got: %d", len(files))
+	}
+	if err := persisted.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("expected no cached files after purging. got: %d", len(files))
+	}
+	if persisted.get(ts.URL+"/b.json") != nil {
+		t.Error("expected purged schemas not to be served")
+	}
+}
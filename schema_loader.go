@@ -2,11 +2,16 @@ package jsonschema
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"path"
+	"strings"
+	"time"
 )
 
 var lr *LoaderRegistry
@@ -14,9 +19,14 @@ var lr *LoaderRegistry
 // LoaderRegistry maintains a lookup table between uri schemes and associated loader
 type LoaderRegistry struct {
 	loaderLookup map[string]SchemaLoaderFunc
+	// denyNetwork forbids loaders from fetching schemas over the network
+	denyNetwork bool
+	// policy restricts the schemas that may be loaded if set
+	policy *LoaderPolicy
 }
 
-// SchemaLoaderFunc is a function that loads a schema for a specific URI Scheme
+// SchemaLoaderFunc is a function that loads a schema for a specific URI Scheme.
+// Loaders should decode schemas with UnmarshalSchema, passing on ctx
 type SchemaLoaderFunc func(ctx context.Context, uri *url.URL, schema *Schema) error
 
 // NewLoaderRegistry allocates a new schema loader registry
@@ -43,6 +53,36 @@ func (r *LoaderRegistry) Get(scheme string) (SchemaLoaderFunc, bool) {
 	return l, exists
 }
 
+// SetDenyNetwork toggles a mode forbidding all network access while
+// fetching schemas. References to schemas that would need to be fetched
+// over the network fail with an UnresolvableReferenceError instead, while
+// loaders serving schemas locally, such as FileSchemaLoader or one created
+// with NewFSSchemaLoader, keep working. Custom loaders accessing the
+// network should check NetworkDenied
+func (r *LoaderRegistry) SetDenyNetwork(deny bool) {
+	r.denyNetwork = deny
+}
+
+type networkDeniedCtxKey struct{}
+
+// NetworkDenied reports whether the context passed to a schema loader
+// forbids network access, see SetDenyNetwork
+func NetworkDenied(ctx context.Context) bool {
+	denied, _ := ctx.Value(networkDeniedCtxKey{}).(bool)
+	return denied
+}
+
+// UnresolvableReferenceError is returned for references to schemas that
+// aren't known and may not be fetched as network access is denied
+type UnresolvableReferenceError struct {
+	URIs []string
+}
+
+// Error implements the error interface for UnresolvableReferenceError
+func (e *UnresolvableReferenceError) Error() string {
+	return fmt.Sprintf("unresolvable reference %s: network access is denied", strings.Join(e.URIs, ", "))
+}
+
 // GetSchemaLoaderRegistry provides an accessor to a globally available (schema) loader registry
 func GetSchemaLoaderRegistry() *LoaderRegistry {
 	if lr == nil {
@@ -67,30 +107,186 @@ func FetchSchema(ctx context.Context, uri string, schema *Schema) error {
 		return fmt.Errorf("URI scheme %s is not supported for uri: %s", u.Scheme, uri)
 	}
 
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if registry.policy != nil {
+		if err := registry.policy.checkFetch(ctx, u); err != nil {
+			return err
+		}
+		ctx = context.WithValue(ctx, loaderPolicyCtxKey{}, registry.policy)
+	}
+	if registry.denyNetwork {
+		ctx = context.WithValue(ctx, networkDeniedCtxKey{}, true)
+	}
 	return loader(ctx, u, schema)
 }
 
-// HTTPSchemaLoader loads a schema from a http or https URI
-func HTTPSchemaLoader(ctx context.Context, uri *url.URL, schema *Schema) error {
-	var req *http.Request
-	if ctx != nil {
-		req, _ = http.NewRequestWithContext(ctx, "GET", uri.String(), nil)
-	} else {
-		req, _ = http.NewRequest("GET", uri.String(), nil)
+// DefaultHTTPAccept is the Accept header sent when fetching schemas over
+// http, preferring schema documents over plain JSON
+const DefaultHTTPAccept = "application/schema+json, application/json;q=0.9, */*;q=0.1"
+
+const (
+	// DefaultHTTPTimeout bounds requests for schemas unless
+	// HTTPLoaderOptions states a timeout
+	DefaultHTTPTimeout = 30 * time.Second
+	// DefaultHTTPMaxBodySize is the largest schema in bytes fetched unless
+	// HTTPLoaderOptions states a limit
+	DefaultHTTPMaxBodySize = 8 << 20
+)
+
+// HTTPLoaderOptions configures a schema loader created with
+// NewHTTPSchemaLoader
+type HTTPLoaderOptions struct {
+	// Client sends requests, defaulting to a client without a timeout
+	Client *http.Client
+	// Timeout bounds each request including reading the response body.
+	// Zero means DefaultHTTPTimeout, a negative timeout none beyond the
+	// one of Client and the context
+	Timeout time.Duration
+	// MaxBodySize is the largest response body in bytes accepted as a
+	// schema. Zero means DefaultHTTPMaxBodySize, a negative size no limit
+	MaxBodySize int64
+	// Accept is the Accept header of requests, defaulting to
+	// DefaultHTTPAccept
+	Accept string
+	// Header holds additional headers sent with each request, such as
+	// credentials for authenticated schema hosts
+	Header http.Header
+	// Cache keeps fetched schemas, sparing requests while they are fresh
+	// and revalidating them once stale. Nil disables caching
+	Cache *SchemaCache
+}
+
+// NewHTTPSchemaLoader creates a loader for http and https URIs configured
+// by opts. Responses with a status outside of the 2xx range are errors.
+// Register the loader with a LoaderRegistry to use it for fetching remote
+// references:
+//
+//	loader := jsonschema.NewHTTPSchemaLoader(jsonschema.HTTPLoaderOptions{Timeout: 5 * time.Second})
+//	jsonschema.GetSchemaLoaderRegistry().Register("https", loader)
+func NewHTTPSchemaLoader(opts HTTPLoaderOptions) SchemaLoaderFunc {
+	return func(ctx context.Context, uri *url.URL, schema *Schema) error {
+		return loadHTTPSchema(ctx, uri, schema, opts)
 	}
-	client := &http.Client{}
-	res, err := client.Do(req)
-	if err != nil {
-		return err
+}
+
+// HTTPSchemaLoader loads a schema from a http or https URI with the
+// default HTTPLoaderOptions
+func HTTPSchemaLoader(ctx context.Context, uri *url.URL, schema *Schema) error {
+	return loadHTTPSchema(ctx, uri, schema, HTTPLoaderOptions{})
+}
+
+// loadHTTPSchema fetches a schema over http as configured by opts
+func loadHTTPSchema(ctx context.Context, uri *url.URL, schema *Schema, opts HTTPLoaderOptions) error {
+	if ctx == nil {
+		ctx = context.Background()
 	}
-	body, err := ioutil.ReadAll(res.Body)
+	body, err := fetchHTTP(ctx, uri, opts)
 	if err != nil {
 		return err
 	}
 	if schema == nil {
 		schema = &Schema{}
 	}
-	return json.Unmarshal(body, schema)
+	return UnmarshalSchema(ctx, body, schema)
+}
+
+// fetchHTTP returns the body of the document at uri, served from the
+// cache of opts while fresh and revalidated with a conditional request
+// once stale
+func fetchHTTP(ctx context.Context, uri *url.URL, opts HTTPLoaderOptions) ([]byte, error) {
+	var cached *cacheEntry
+	if opts.Cache != nil {
+		cached = opts.Cache.get(uri.String())
+		if cached != nil && (NetworkDenied(ctx) || opts.Cache.fresh(cached)) {
+			return cached.Body, nil
+		}
+	}
+	if NetworkDenied(ctx) {
+		return nil, &UnresolvableReferenceError{URIs: []string{uri.String()}}
+	}
+	policy := loaderPolicyFromContext(ctx)
+	if policy != nil {
+		if err := policy.checkHost(ctx, uri); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultHTTPTimeout
+	}
+	if opts.MaxBodySize == 0 {
+		opts.MaxBodySize = DefaultHTTPMaxBodySize
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", uri.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, vals := range opts.Header {
+		for _, val := range vals {
+			req.Header.Add(key, val)
+		}
+	}
+	accept := opts.Accept
+	if accept == "" {
+		accept = DefaultHTTPAccept
+	}
+	req.Header.Set("Accept", accept)
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	client := opts.Client
+	if policy != nil {
+		client = policy.client(client)
+	} else if client == nil {
+		client = &http.Client{}
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotModified && cached != nil {
+		if err := opts.Cache.revalidate(cached, res.Header); err != nil {
+			return nil, err
+		}
+		return cached.Body, nil
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", uri, res.Status)
+	}
+
+	var body []byte
+	if opts.MaxBodySize > 0 {
+		body, err = ioutil.ReadAll(io.LimitReader(res.Body, opts.MaxBodySize+1))
+		if err == nil && int64(len(body)) > opts.MaxBodySize {
+			return nil, fmt.Errorf("fetching %s: response body exceeds the maximum size of %d bytes", uri, opts.MaxBodySize)
+		}
+	} else {
+		body, err = ioutil.ReadAll(res.Body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.Cache != nil {
+		if err := opts.Cache.store(uri.String(), body, res.Header); err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
 }
 
 // FileSchemaLoader loads a schema from a file URI
@@ -102,5 +298,53 @@ func FileSchemaLoader(ctx context.Context, uri *url.URL, schema *Schema) error {
 	if schema == nil {
 		schema = &Schema{}
 	}
-	return json.Unmarshal(body, schema)
+	return UnmarshalSchema(ctx, body, schema)
+}
+
+// NewFSSchemaLoader creates a loader serving the schemas of URIs within
+// baseURI from fsys, mapping the path below baseURI onto a path of fsys.
+// A schema identified as "person" is also found in a file "person.json".
+// URIs outside of baseURI are passed to fallback, or rejected when
+// fallback is nil. Use it to resolve references offline from schemas
+// embedded in a binary:
+//
+//	//go:embed schemas
+//	var schemas embed.FS
+//
+//	sub, _ := fs.Sub(schemas, "schemas")
+//	loader, err := jsonschema.NewFSSchemaLoader(sub, "https://schemas.example.com/", jsonschema.HTTPSchemaLoader)
+//	jsonschema.GetSchemaLoaderRegistry().Register("https", loader)
+func NewFSSchemaLoader(fsys fs.FS, baseURI string, fallback SchemaLoaderFunc) (SchemaLoaderFunc, error) {
+	base, err := url.Parse(baseURI)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+
+	return func(ctx context.Context, uri *url.URL, schema *Schema) error {
+		if !strings.EqualFold(uri.Scheme, base.Scheme) || !strings.EqualFold(uri.Host, base.Host) || !strings.HasPrefix(uri.Path, base.Path) {
+			if fallback != nil {
+				return fallback(ctx, uri, schema)
+			}
+			return fmt.Errorf("%s is not within %s", uri, base)
+		}
+
+		name := strings.TrimPrefix(uri.Path, base.Path)
+		if !fs.ValidPath(name) || name == "." {
+			return fmt.Errorf("invalid schema path for uri: %s", uri)
+		}
+		body, err := fs.ReadFile(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) && path.Ext(name) == "" {
+			body, err = fs.ReadFile(fsys, name+".json")
+		}
+		if err != nil {
+			return err
+		}
+		if schema == nil {
+			schema = &Schema{}
+		}
+		return UnmarshalSchema(ctx, body, schema)
+	}, nil
 }
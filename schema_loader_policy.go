@@ -0,0 +1,384 @@
+package jsonschema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// LoaderPolicy restricts the schemas FetchSchema may load, guarding
+// against user supplied schemas referencing internal resources. Set it
+// with SetPolicy. The zero value allows everything
+type LoaderPolicy struct {
+	// Schemes lists the URI schemes schemas may be loaded from. Empty
+	// allows all schemes with a registered loader
+	Schemes []string
+	// AllowedHosts lists the hosts schemas may be loaded from, either
+	// exact host names or patterns like "*.example.com" matching
+	// subdomains. URIs using the file scheme aren't subject to it
+	AllowedHosts []string
+	// AllowedPrefixes lists URI prefixes schemas may be loaded from, such
+	// as "https://example.com/schemas/". A URI matches a prefix with the
+	// same scheme, host and port whose path holds the cleaned URI path,
+	// compared segment by segment. A URI is allowed if it matches either a
+	// host or a prefix, URIs with user info never match. Both being empty
+	// allows all URIs
+	AllowedPrefixes []string
+	// DenyPrivateIPs forbids fetching schemas over http from addresses
+	// that aren't globally reachable, such as loopback, private,
+	// link-local or reserved addresses. The http loaders
+	// check the addresses host names resolve to before sending requests
+	// as well, and connections unless they use a client with a transport
+	// of its own
+	DenyPrivateIPs bool
+	// MaxDepth caps the chain of fetched schemas referencing each other,
+	// a schema referenced by a schema that wasn't fetched having a depth
+	// of one. Zero means no limit
+	MaxDepth int
+	// MaxDocuments caps the number of schemas fetched into a schema
+	// registry. Zero means no limit
+	MaxDocuments int
+	// FileRoot confines loading file URIs to a directory, rejecting
+	// paths escaping it with ".." or symbolic links. Empty leaves file
+	// URIs unrestricted, use Schemes to forbid them
+	FileRoot string
+
+	transportOnce sync.Once
+	transport     http.RoundTripper
+}
+
+// PolicyError is returned for schemas a LoaderPolicy forbids loading
+type PolicyError struct {
+	URI    string
+	Reason string
+}
+
+// Error implements the error interface for PolicyError
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("loading %s is not allowed: %s", e.URI, e.Reason)
+}
+
+// SetPolicy sets the policy consulted by FetchSchema before loading a
+// schema. Nil removes the policy
+func (r *LoaderRegistry) SetPolicy(policy *LoaderPolicy) {
+	r.policy = policy
+}
+
+type loaderPolicyCtxKey struct{}
+
+// loaderPolicyFromContext returns the policy FetchSchema passes loaders
+func loaderPolicyFromContext(ctx context.Context) *LoaderPolicy {
+	policy, _ := ctx.Value(loaderPolicyCtxKey{}).(*LoaderPolicy)
+	return policy
+}
+
+// fetchState describes the fetch a schema registry is about to make
+type fetchState struct {
+	// depth is the length of the chain of fetched schemas leading to
+	// the schema being fetched
+	depth int
+	// documents is the number of schemas fetched into the registry
+	documents int
+}
+
+type fetchStateCtxKey struct{}
+
+// checkFetch reports whether the policy allows fetching uri, judging by
+// the fetch state and the URI without any network access
+func (p *LoaderPolicy) checkFetch(ctx context.Context, u *url.URL) error {
+	if state, ok := ctx.Value(fetchStateCtxKey{}).(fetchState); ok {
+		if p.MaxDepth > 0 && state.depth > p.MaxDepth {
+			return &PolicyError{URI: u.String(), Reason: fmt.Sprintf("references are nested deeper than %d fetches", p.MaxDepth)}
+		}
+		if p.MaxDocuments > 0 && state.documents >= p.MaxDocuments {
+			return &PolicyError{URI: u.String(), Reason: fmt.Sprintf("more than %d schemas would be fetched", p.MaxDocuments)}
+		}
+	}
+	return p.checkURL(u)
+}
+
+// checkHost reports whether the addresses the host name of u resolves to
+// are allowed. It looks the host up, so loaders only call it right before
+// connecting, leaving schemas served from a cache or refused with the
+// network denied alone
+func (p *LoaderPolicy) checkHost(ctx context.Context, u *url.URL) error {
+	if !p.DenyPrivateIPs || net.ParseIP(u.Hostname()) != nil {
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if isPrivateIP(addr.IP) {
+			return &PolicyError{URI: u.String(), Reason: fmt.Sprintf("host resolves to private address %s", addr.IP)}
+		}
+	}
+	return nil
+}
+
+// checkURL reports whether the policy allows loading u, judging by the
+// URI alone
+func (p *LoaderPolicy) checkURL(u *url.URL) error {
+	if len(p.Schemes) > 0 && !containsFold(p.Schemes, u.Scheme) {
+		return &PolicyError{URI: u.String(), Reason: fmt.Sprintf("scheme %s is not allowed", u.Scheme)}
+	}
+
+	if u.Scheme == "file" {
+		return p.checkFile(u)
+	}
+
+	if len(p.AllowedHosts) > 0 || len(p.AllowedPrefixes) > 0 {
+		// user info lets a URI read like another host
+		if u.User != nil {
+			return &PolicyError{URI: u.String(), Reason: "URIs must not carry user info"}
+		}
+		allowed := false
+		for _, prefix := range p.AllowedPrefixes {
+			if matchPrefix(prefix, u) {
+				allowed = true
+				break
+			}
+		}
+		for _, pattern := range p.AllowedHosts {
+			if matchHost(pattern, u.Hostname()) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return &PolicyError{URI: u.String(), Reason: "not an allowed host or prefix"}
+		}
+	}
+
+	if p.DenyPrivateIPs && (u.Scheme == "http" || u.Scheme == "https") {
+		if ip := net.ParseIP(u.Hostname()); ip != nil && isPrivateIP(ip) {
+			return &PolicyError{URI: u.String(), Reason: fmt.Sprintf("private address %s", ip)}
+		}
+	}
+	return nil
+}
+
+// checkFile reports whether a file URI lies within the file root
+func (p *LoaderPolicy) checkFile(u *url.URL) error {
+	if u.Host != "" && u.Host != "localhost" {
+		return &PolicyError{URI: u.String(), Reason: "file URIs must not name a host"}
+	}
+	if p.FileRoot == "" {
+		return nil
+	}
+
+	root, err := filepath.Abs(p.FileRoot)
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	path := filepath.Clean(filepath.FromSlash(u.Path))
+	if !withinDir(root, path) {
+		return &PolicyError{URI: u.String(), Reason: fmt.Sprintf("path is outside of %s", p.FileRoot)}
+	}
+	// symbolic links may point outside of the root
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	if !withinDir(root, resolved) {
+		return &PolicyError{URI: u.String(), Reason: fmt.Sprintf("path is outside of %s", p.FileRoot)}
+	}
+	return nil
+}
+
+// client returns a copy of client checking the policy when following
+// redirects. Without a client of its own, the copy refuses to connect to
+// private addresses if the policy denies them
+func (p *LoaderPolicy) client(client *http.Client) *http.Client {
+	guarded := &http.Client{}
+	if client != nil {
+		*guarded = *client
+	}
+	if client == nil || client.Transport == nil {
+		if p.DenyPrivateIPs {
+			guarded.Transport = p.guardedTransport()
+		}
+	}
+
+	checkRedirect := guarded.CheckRedirect
+	guarded.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := p.checkURL(req.URL); err != nil {
+			return err
+		}
+		if checkRedirect != nil {
+			return checkRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return guarded
+}
+
+// guardedTransport returns a transport refusing to connect to private
+// addresses, shared by all requests under the policy
+func (p *LoaderPolicy) guardedTransport() http.RoundTripper {
+	p.transportOnce.Do(func() {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control: func(network, address string, c syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+					return &PolicyError{URI: address, Reason: "connecting to private addresses is not allowed"}
+				}
+				return nil
+			},
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = dialer.DialContext
+		p.transport = transport
+	})
+	return p.transport
+}
+
+// privateNetworks lists the address ranges of the IANA special-purpose
+// address registries that aren't globally reachable, along with the
+// documentation, multicast and reserved ranges
+var privateNetworks = func() []*net.IPNet {
+	cidrs := []string{
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.0.0.0/24",
+		"192.0.2.0/24",
+		"192.88.99.0/24",
+		"192.168.0.0/16",
+		"198.18.0.0/15",
+		"198.51.100.0/24",
+		"203.0.113.0/24",
+		"224.0.0.0/4",
+		"240.0.0.0/4",
+		"::/128",
+		"::1/128",
+		"64:ff9b:1::/48",
+		"100::/64",
+		"2001::/23",
+		"2001:db8::/32",
+		"3fff::/20",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	}
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, _ := net.ParseCIDR(cidr)
+		networks[i] = network
+	}
+	return networks
+}()
+
+// nat64Network and sixToFourNetwork hold IPv6 addresses embedding an IPv4
+// address, reaching the IPv4 address through a gateway
+var (
+	_, nat64Network, _     = net.ParseCIDR("64:ff9b::/96")
+	_, sixToFourNetwork, _ = net.ParseCIDR("2002::/16")
+)
+
+// isPrivateIP reports whether ip isn't reachable from the internet, lying
+// in one of privateNetworks or embedding an IPv4 address that does.
+// IPv4-mapped IPv6 addresses count as their IPv4 address
+func isPrivateIP(ip net.IP) bool {
+	if ip.To4() == nil {
+		switch {
+		case nat64Network.Contains(ip):
+			return isPrivateIP(ip[12:16])
+		case sixToFourNetwork.Contains(ip):
+			return isPrivateIP(ip[2:6])
+		}
+	}
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost reports whether host matches a host name or a pattern like
+// "*.example.com"
+func matchHost(pattern, host string) bool {
+	pattern, host = strings.ToLower(pattern), strings.ToLower(host)
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return pattern == host
+}
+
+// matchPrefix reports whether u lies under the URI prefix, comparing the
+// scheme, host and port exactly and the cleaned path segment by segment
+func matchPrefix(prefix string, u *url.URL) bool {
+	p, err := url.Parse(prefix)
+	if err != nil || p.User != nil {
+		return false
+	}
+	if !strings.EqualFold(p.Scheme, u.Scheme) || !strings.EqualFold(p.Hostname(), u.Hostname()) ||
+		portOrDefault(p) != portOrDefault(u) {
+		return false
+	}
+
+	prefixPath := path.Clean("/" + p.Path)
+	uriPath := path.Clean("/" + u.Path)
+	if prefixPath == "/" {
+		return true
+	}
+	return uriPath == prefixPath || strings.HasPrefix(uriPath, prefixPath+"/")
+}
+
+// portOrDefault returns the port of u, or the default port of its scheme
+func portOrDefault(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// withinDir reports whether path lies within dir
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// containsFold reports whether strs holds s, ignoring case
+func containsFold(strs []string, s string) bool {
+	for _, str := range strs {
+		if strings.EqualFold(str, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,295 @@
+package jsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoaderPolicyCheckURL(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "schemas"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"schemas/a.json", "outside.json"} {
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(`{}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(root, "outside.json"), filepath.Join(root, "schemas", "link.json")); err != nil {
+		t.Fatal(err)
+	}
+	fileURI := func(path string) string {
+		return "file://" + filepath.ToSlash(filepath.Join(root, path))
+	}
+
+	cases := []struct {
+		policy  *LoaderPolicy
+		uri     string
+		message string
+	}{
+		{&LoaderPolicy{}, "http://127.0.0.1/schema.json", ""},
+		{&LoaderPolicy{Schemes: []string{"https"}}, "https://example.com/schema.json", ""},
+		{&LoaderPolicy{Schemes: []string{"https"}}, "http://example.com/schema.json", "scheme http is not allowed"},
+		{&LoaderPolicy{Schemes: []string{"https"}}, fileURI("schemas/a.json"), "scheme file is not allowed"},
+		{&LoaderPolicy{AllowedHosts: []string{"example.com"}}, "https://EXAMPLE.com:8443/a.json", ""},
+		{&LoaderPolicy{AllowedHosts: []string{"example.com"}}, "https://api.example.com/a.json", "not an allowed host or prefix"},
+		{&LoaderPolicy{AllowedHosts: []string{"*.example.com"}}, "https://api.example.com/a.json", ""},
+		{&LoaderPolicy{AllowedHosts: []string{"*.example.com"}}, "https://example.com.evil.org/a.json", "not an allowed host or prefix"},
+		{&LoaderPolicy{AllowedPrefixes: []string{"https://example.com/schemas/"}}, "https://example.com/schemas/a.json", ""},
+		{&LoaderPolicy{AllowedPrefixes: []string{"https://example.com/schemas/"}}, "https://example.com/private/a.json", "not an allowed host or prefix"},
+		{&LoaderPolicy{AllowedPrefixes: []string{"https://example.com/schemas/"}}, "https://example.com/schemas/../private/x.json", "not an allowed host or prefix"},
+		{&LoaderPolicy{AllowedPrefixes: []string{"https://example.com/schemas/"}}, "https://example.com/schemas", ""},
+		{&LoaderPolicy{AllowedPrefixes: []string{"https://example.com/schemas/"}}, "https://example.com/schemas.json", "not an allowed host or prefix"},
+		{&LoaderPolicy{AllowedPrefixes: []string{"https://example.com"}}, "https://example.com@evil.net/x.json", "must not carry user info"},
+		{&LoaderPolicy{AllowedPrefixes: []string{"https://example.com"}}, "https://example.com.evil.net/x.json", "not an allowed host or prefix"},
+		{&LoaderPolicy{AllowedPrefixes: []string{"https://example.com"}}, "https://example.com:443/x.json", ""},
+		{&LoaderPolicy{AllowedPrefixes: []string{"https://example.com"}}, "https://example.com:8443/x.json", "not an allowed host or prefix"},
+		{&LoaderPolicy{AllowedHosts: []string{"example.com"}}, fileURI("schemas/a.json"), ""},
+		{&LoaderPolicy{DenyPrivateIPs: true}, "http://127.0.0.1/schema.json", "private address 127.0.0.1"},
+		{&LoaderPolicy{DenyPrivateIPs: true}, "http://10.1.2.3/schema.json", "private address"},
+		{&LoaderPolicy{DenyPrivateIPs: true}, "http://169.254.169.254/latest", "private address"},
+		{&LoaderPolicy{DenyPrivateIPs: true}, "http://[::1]/schema.json", "private address"},
+		{&LoaderPolicy{DenyPrivateIPs: true}, "http://[fd00::1]/schema.json", "private address"},
+		{&LoaderPolicy{DenyPrivateIPs: true}, "http://0.0.0.0/schema.json", "private address"},
+		{&LoaderPolicy{DenyPrivateIPs: true}, "http://8.8.8.8/schema.json", ""},
+		{&LoaderPolicy{FileRoot: filepath.Join(root, "schemas")}, fileURI("schemas/a.json"), ""},
+		{&LoaderPolicy{FileRoot: filepath.Join(root, "schemas")}, fileURI("outside.json"), "path is outside of"},
+		{&LoaderPolicy{FileRoot: filepath.Join(root, "schemas")}, "file://" + filepath.ToSlash(root) + "/schemas/../outside.json", "path is outside of"},
+		{&LoaderPolicy{FileRoot: filepath.Join(root, "schemas")}, fileURI("schemas/link.json"), "path is outside of"},
+		{&LoaderPolicy{FileRoot: filepath.Join(root, "schemas")}, fileURI("schemas/missing.json"), "no such file or directory"},
+		{&LoaderPolicy{}, "file://remote.example.com/etc/passwd", "must not name a host"},
+	}
+
+	for i, c := range cases {
+		u, err := url.Parse(c.uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.policy.checkURL(u)
+		if c.message == "" {
+			if err != nil {
+				t.Errorf("case %d %s: unexpected error: %s", i, c.uri, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.message) {
+			t.Errorf("case %d %s: expected error to include %q. got: %v", i, c.uri, c.message, err)
+		}
+	}
+}
+
+func TestIsPrivateIP(t *testing.T) {
+	cases := []struct {
+		ip      string
+		private bool
+	}{
+		{"0.0.0.0", true},
+		{"0.1.2.3", true},
+		{"9.255.255.255", false},
+		{"10.0.0.1", true},
+		{"100.63.255.255", false},
+		{"100.64.0.1", true},
+		{"100.127.255.255", true},
+		{"100.128.0.0", false},
+		{"127.0.0.1", true},
+		{"127.255.255.254", true},
+		{"169.254.169.254", true},
+		{"172.15.255.255", false},
+		{"172.16.0.1", true},
+		{"172.31.255.255", true},
+		{"172.32.0.0", false},
+		{"192.0.0.8", true},
+		{"192.0.2.1", true},
+		{"192.88.99.1", true},
+		{"192.168.1.1", true},
+		{"198.17.255.255", false},
+		{"198.18.0.1", true},
+		{"198.19.255.255", true},
+		{"198.51.100.1", true},
+		{"203.0.113.1", true},
+		{"224.0.0.1", true},
+		{"239.255.255.255", true},
+		{"240.0.0.1", true},
+		{"255.255.255.255", true},
+		{"8.8.8.8", false},
+		{"1.1.1.1", false},
+		{"::", true},
+		{"::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:10.1.2.3", true},
+		{"::ffff:8.8.8.8", false},
+		{"64:ff9b::7f00:1", true},
+		{"64:ff9b::808:808", false},
+		{"64:ff9b:1::1", true},
+		{"100::1", true},
+		{"2001::1", true},
+		{"2001:db8::1", true},
+		{"2002:7f00:1::", true},
+		{"2002:808:808::", false},
+		{"3fff::1", true},
+		{"fc00::1", true},
+		{"fd12:3456::1", true},
+		{"fe80::1", true},
+		{"ff02::1", true},
+		{"2606:4700:4700::1111", false},
+		{"2001:4860:4860::8888", false},
+	}
+
+	for _, c := range cases {
+		ip := net.ParseIP(c.ip)
+		if ip == nil {
+			t.Fatalf("invalid address %s", c.ip)
+		}
+		if got := isPrivateIP(ip); got != c.private {
+			t.Errorf("%s: expected private %t. got: %t", c.ip, c.private, got)
+		}
+	}
+}
+
+func TestLoaderPolicy(t *testing.T) {
+	ctx := context.Background()
+	requests := 0
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "http://evil.example.com/schema.json", http.StatusFound)
+		case "/1", "/2", "/3":
+			// each schema references the next one
+			next := int(r.URL.Path[1]-'0') + 1
+			fmt.Fprintf(w, `{"$id": "%s%s", "properties": {"next": {"$ref": "%s/%d"}}}`, ts.URL, r.URL.Path, ts.URL, next)
+		default:
+			fmt.Fprint(w, `{"type": "string"}`)
+		}
+	}))
+	defer ts.Close()
+
+	lr := GetSchemaLoaderRegistry()
+	defer lr.SetPolicy(nil)
+
+	validate := func(ref string, data interface{}) []KeyError {
+		rs := &Schema{}
+		if err := json.Unmarshal([]byte(fmt.Sprintf(`{"$ref": %q}`, ref)), rs); err != nil {
+			t.Fatal(err)
+		}
+		return *rs.Validate(WithSchemaRegistry(ctx, NewSchemaRegistry()), data).Errs
+	}
+	hasError := func(errs []KeyError, message string) bool {
+		for _, err := range errs {
+			if strings.Contains(err.Message, message) {
+				return true
+			}
+		}
+		return false
+	}
+
+	lr.SetPolicy(&LoaderPolicy{DenyPrivateIPs: true})
+	if errs := validate(ts.URL+"/schema.json", "a"); !hasError(errs, "private address 127.0.0.1") {
+		t.Errorf("expected a private address error. got: %v", errs)
+	}
+	if requests != 0 {
+		t.Errorf("expected no requests to private addresses. got: %d", requests)
+	}
+
+	// connections are checked as well, covering host names resolving to
+	// private addresses after the policy was consulted
+	policy := &LoaderPolicy{DenyPrivateIPs: true}
+	if _, err := policy.client(nil).Get(ts.URL); err == nil || !strings.Contains(err.Error(), "connecting to private addresses is not allowed") {
+		t.Errorf("expected connecting to a private address to fail. got: %v", err)
+	}
+
+	lr.SetPolicy(&LoaderPolicy{AllowedPrefixes: []string{ts.URL + "/"}})
+	if errs := validate(ts.URL+"/schema.json", 1); len(errs) != 1 || errs[0].Message != "type should be string, got integer" {
+		t.Errorf("expected an allowed schema to be fetched. got: %v", errs)
+	}
+	if errs := validate(ts.URL+"/redirect", "a"); len(errs) == 0 {
+		t.Error("expected a redirect to a host that isn't allowed to fail")
+	}
+
+	lr.SetPolicy(&LoaderPolicy{MaxDepth: 2})
+	errs := validate(ts.URL+"/1", map[string]interface{}{"next": map[string]interface{}{"next": "a"}})
+	if !hasError(errs, "loading "+ts.URL+"/3 is not allowed: references are nested deeper than 2 fetches") {
+		t.Errorf("expected a depth error. got: %v", errs)
+	}
+
+	lr.SetPolicy(&LoaderPolicy{MaxDocuments: 1})
+	errs = validate(ts.URL+"/1", map[string]interface{}{"next": "a"})
+	if !hasError(errs, "more than 1 schemas would be fetched") {
+		t.Errorf("expected a document limit error. got: %v", errs)
+	}
+
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(fmt.Sprintf(`{"$ref": "%s/1"}`, ts.URL)), rs); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Compile(WithSchemaRegistry(ctx, NewSchemaRegistry()), rs)
+	if err == nil || !strings.Contains(err.Error(), "more than 1 schemas would be fetched") {
+		t.Errorf("expected compiling to report the policy. got: %v", err)
+	}
+
+	lr.SetPolicy(&LoaderPolicy{Schemes: []string{"https"}})
+	err = FetchSchema(ctx, ts.URL+"/schema.json", &Schema{})
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) || policyErr.URI != ts.URL+"/schema.json" {
+		t.Errorf("expected a PolicyError. got: %v", err)
+	}
+}
+
+func TestLoaderPolicyOffline(t *testing.T) {
+	ctx := context.Background()
+	cache, err := NewSchemaCache(SchemaCacheOptions{TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// .invalid host names never resolve, so looking them up fails
+	if err := cache.store("https://schemas.invalid/cached.json", []byte(`{"type": "string"}`), http.Header{}); err != nil {
+		t.Fatal(err)
+	}
+
+	lr := GetSchemaLoaderRegistry()
+	lr.Register("https", NewHTTPSchemaLoader(HTTPLoaderOptions{Cache: cache}))
+	defer lr.Register("https", HTTPSchemaLoader)
+	lr.SetPolicy(&LoaderPolicy{DenyPrivateIPs: true})
+	defer lr.SetPolicy(nil)
+	defer lr.SetDenyNetwork(false)
+
+	unmarshal := func(ref string) *Schema {
+		rs := &Schema{}
+		if err := json.Unmarshal([]byte(fmt.Sprintf(`{"$ref": %q}`, ref)), rs); err != nil {
+			t.Fatal(err)
+		}
+		return rs
+	}
+
+	for _, deny := range []bool{false, true} {
+		lr.SetDenyNetwork(deny)
+		errs := *unmarshal("https://schemas.invalid/cached.json").Validate(WithSchemaRegistry(ctx, NewSchemaRegistry()), 1).Errs
+		if len(errs) != 1 || errs[0].Message != "type should be string, got integer" {
+			t.Errorf("deny network %t: expected the cached schema to be served. got: %v", deny, errs)
+		}
+	}
+
+	missing := "https://schemas.invalid/missing.json"
+	errs := *unmarshal(missing).Validate(WithSchemaRegistry(ctx, NewSchemaRegistry()), 1).Errs
+	if len(errs) == 0 || !strings.Contains(errs[0].Message, "network access is denied") {
+		t.Errorf("expected an unresolvable reference error. got: %v", errs)
+	}
+	_, err = Compile(WithSchemaRegistry(ctx, NewSchemaRegistry()), unmarshal(missing))
+	var unresolvable *UnresolvableReferenceError
+	if !errors.As(err, &unresolvable) || len(unresolvable.URIs) != 1 || unresolvable.URIs[0] != missing {
+		t.Errorf("expected compiling to report an UnresolvableReferenceError. got: %v", err)
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/qri-io/jsonschema"
 )
@@ -47,7 +49,7 @@ func TestFetchSchema(t *testing.T) {
 	}{
 		{fmt.Sprintf("%s/valid_schema.json", ts.URL), false, ""},
 		{fmt.Sprintf("%s/invalid_schema.json", ts.URL), true, "invalid character"},
-		{fmt.Sprintf("file://%s/testdata/draft-07_schema.json", wd), false, ""},
+		{fmt.Sprintf("file://%s/meta/draft-07/schema.json", wd), false, ""},
 		{fmt.Sprintf("file://%s/testdata/missing_file.json", wd), true, "no such file or directory"},
 		{"unknownscheme://resource.json#definitions/property", true, "unknownscheme is not supported for uri"},
 	}
@@ -114,3 +116,181 @@ func TestCustomSchemaLoader(t *testing.T) {
 	}
 
 }
+
+func TestHTTPSchemaLoaderOptions(t *testing.T) {
+	validSchema := `{"type": "string"}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/schema.json":
+			fmt.Fprint(w, validSchema)
+		case "/private.json":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, validSchema)
+		case "/accept.json":
+			if r.Header.Get("Accept") != "application/schema+json" {
+				w.WriteHeader(http.StatusNotAcceptable)
+				return
+			}
+			fmt.Fprint(w, validSchema)
+		case "/large.json":
+			fmt.Fprintf(w, `{"description": %q}`, strings.Repeat("a", 1024))
+		case "/huge.json":
+			fmt.Fprintf(w, `{"description": %q}`, strings.Repeat("a", jsonschema.DefaultHTTPMaxBodySize))
+		case "/slow.json":
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+			fmt.Fprint(w, validSchema)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	requests := 0
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+
+	cases := []struct {
+		path    string
+		opts    jsonschema.HTTPLoaderOptions
+		message string
+	}{
+		{"/schema.json", jsonschema.HTTPLoaderOptions{}, ""},
+		{"/missing.json", jsonschema.HTTPLoaderOptions{}, "unexpected status 404 Not Found"},
+		{"/private.json", jsonschema.HTTPLoaderOptions{}, "unexpected status 401 Unauthorized"},
+		{"/private.json", jsonschema.HTTPLoaderOptions{Header: http.Header{"Authorization": {"Bearer token"}}}, ""},
+		{"/accept.json", jsonschema.HTTPLoaderOptions{}, "unexpected status 406 Not Acceptable"},
+		{"/accept.json", jsonschema.HTTPLoaderOptions{Accept: "application/schema+json"}, ""},
+		{"/large.json", jsonschema.HTTPLoaderOptions{MaxBodySize: 1024}, "exceeds the maximum size of 1024 bytes"},
+		{"/large.json", jsonschema.HTTPLoaderOptions{MaxBodySize: 2048}, ""},
+		{"/huge.json", jsonschema.HTTPLoaderOptions{}, "exceeds the maximum size of"},
+		{"/huge.json", jsonschema.HTTPLoaderOptions{MaxBodySize: -1}, ""},
+		{"/slow.json", jsonschema.HTTPLoaderOptions{Timeout: 50 * time.Millisecond}, "context deadline exceeded"},
+		{"/schema.json", jsonschema.HTTPLoaderOptions{Client: client}, ""},
+	}
+
+	for i, c := range cases {
+		u, err := url.Parse(ts.URL + c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs := &jsonschema.Schema{}
+		err = jsonschema.NewHTTPSchemaLoader(c.opts)(context.Background(), u, rs)
+		if c.message == "" {
+			if err != nil {
+				t.Errorf("case %d %s: unexpected error: %s", i, c.path, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.message) {
+			t.Errorf("case %d %s: expected error to include %q. got: %v", i, c.path, c.message, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected the custom client to send 1 request. got: %d", requests)
+	}
+
+	lr := jsonschema.GetSchemaLoaderRegistry()
+	lr.Register("http", jsonschema.NewHTTPSchemaLoader(jsonschema.HTTPLoaderOptions{
+		Header: http.Header{"Authorization": {"Bearer token"}},
+	}))
+	defer lr.Register("http", jsonschema.HTTPSchemaLoader)
+
+	rs := &jsonschema.Schema{}
+	if err := json.Unmarshal([]byte(fmt.Sprintf(`{"$ref": "%s/private.json"}`, ts.URL)), rs); err != nil {
+		t.Fatal(err)
+	}
+	errs, err := rs.ValidateBytes(context.Background(), []byte(`1`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 {
+		t.Errorf("expected the registered loader to resolve the reference. got: %v", errs)
+	}
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestFSSchemaLoader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"person.json":          {Data: []byte(`{"type": "object", "required": ["name"], "properties": {"address": {"$ref": "defs/address"}}}`)},
+		"defs/address.json":    {Data: []byte(`{"type": "object", "properties": {"zip": {"$ref": "types.json#/$defs/zip"}}}`)},
+		"defs/types.json":      {Data: []byte(`{"$defs": {"zip": {"type": "string", "pattern": "^[0-9]{5}$"}}}`)},
+		"invalid/schema.json":  {Data: []byte(`invalid`)},
+		"private/secrets.json": {Data: []byte(`{}`)},
+	}
+
+	fallbackCalls := 0
+	fallback := func(ctx context.Context, uri *url.URL, schema *jsonschema.Schema) error {
+		fallbackCalls++
+		return fmt.Errorf("offline")
+	}
+	loader, err := jsonschema.NewFSSchemaLoader(fsys, "https://schemas.example.com/v1", fallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		uri     string
+		message string
+	}{
+		{"https://schemas.example.com/v1/person.json", ""},
+		{"https://schemas.example.com/v1/person", ""},
+		{"https://SCHEMAS.example.com/v1/defs/types.json", ""},
+		{"https://schemas.example.com/v1/missing.json", "file does not exist"},
+		{"https://schemas.example.com/v1/invalid/schema.json", "invalid character"},
+		{"https://schemas.example.com/v1/../v1/person.json", "invalid schema path"},
+		{"https://schemas.example.com/v2/person.json", "offline"},
+		{"https://other.example.com/v1/person.json", "offline"},
+	}
+	for i, c := range cases {
+		u, err := url.Parse(c.uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = loader(context.Background(), u, &jsonschema.Schema{})
+		if c.message == "" {
+			if err != nil {
+				t.Errorf("case %d %s: unexpected error: %s", i, c.uri, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.message) {
+			t.Errorf("case %d %s: expected error to include %q. got: %v", i, c.uri, c.message, err)
+		}
+	}
+	if fallbackCalls != 2 {
+		t.Errorf("expected 2 calls to the fallback loader. got: %d", fallbackCalls)
+	}
+
+	lr := jsonschema.GetSchemaLoaderRegistry()
+	lr.Register("https", loader)
+	defer lr.Register("https", jsonschema.HTTPSchemaLoader)
+
+	rs := &jsonschema.Schema{}
+	if err := json.Unmarshal([]byte(`{"$ref": "https://schemas.example.com/v1/person"}`), rs); err != nil {
+		t.Fatal(err)
+	}
+	errs, err := rs.ValidateBytes(context.Background(), []byte(`{"address": {"zip": "abc"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, e := range errs {
+		got = append(got, e.PropertyPath)
+	}
+	if strings.Join(got, ",") != "/,/address/zip" && strings.Join(got, ",") != "/address/zip,/" {
+		t.Errorf("expected errors at / and /address/zip. got: %v", errs)
+	}
+}
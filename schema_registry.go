@@ -1,22 +1,58 @@
 package jsonschema
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
 	"strings"
+	"sync"
 )
 
-var sr *SchemaRegistry
+var (
+	sr     *SchemaRegistry
+	srLock sync.Mutex
+)
 
 // SchemaRegistry maintains a lookup table between schema string references
 // and actual schemas
 type SchemaRegistry struct {
 	schemaLookup  map[string]*Schema
 	contextLookup map[string]*Schema
+	// strict enables meta-schema validation of fetched schemas
+	strict bool
+	// parent is the registry schemas are registered with by URI when
+	// this registry serves as the local registry of a validation.
+	// The global registry is used if nil
+	parent *SchemaRegistry
+	// isolated marks registries created with NewSchemaRegistry which
+	// keep the schemas registered with them to themselves
+	isolated bool
+	// fetchDepths holds the depth of the chain of fetches leading to each
+	// fetched schema, see LoaderPolicy
+	fetchDepths map[string]int
+	// lock guards schemaLookup which may be shared between validations
+	lock sync.RWMutex
+}
+
+// NewSchemaRegistry creates an empty SchemaRegistry isolated from the
+// global registry. Schemas passed it with Register are registered with
+// it by URI. Use WithSchemaRegistry to validate with it
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{
+		schemaLookup:  map[string]*Schema{},
+		contextLookup: map[string]*Schema{},
+		isolated:      true,
+	}
 }
 
 // GetSchemaRegistry provides an accessor to a globally available schema registry
 func GetSchemaRegistry() *SchemaRegistry {
+	srLock.Lock()
+	defer srLock.Unlock()
 	if sr == nil {
 		sr = &SchemaRegistry{
 			schemaLookup:  map[string]*Schema{},
@@ -28,34 +64,215 @@ func GetSchemaRegistry() *SchemaRegistry {
 
 // ResetSchemaRegistry resets the main SchemaRegistry
 func ResetSchemaRegistry() {
+	srLock.Lock()
+	defer srLock.Unlock()
 	sr = nil
 }
 
+type schemaRegistryCtxKey struct{}
+
+// WithSchemaRegistry returns a context registering and resolving schemas
+// by URI with the given registry instead of the global one when passed
+// to Validate or Compile. References are resolved separately for each
+// registry, so the same schema may be validated with several registries
+func WithSchemaRegistry(ctx context.Context, registry *SchemaRegistry) context.Context {
+	return context.WithValue(ctx, schemaRegistryCtxKey{}, registry)
+}
+
+// SchemaRegistryFromContext returns the registry set by WithSchemaRegistry
+// or the global registry
+func SchemaRegistryFromContext(ctx context.Context) *SchemaRegistry {
+	if registry, ok := ctx.Value(schemaRegistryCtxKey{}).(*SchemaRegistry); ok && registry != nil {
+		return registry
+	}
+	return GetSchemaRegistry()
+}
+
+// root returns the registry schemas are registered with by URI
+func (sr *SchemaRegistry) root() *SchemaRegistry {
+	if sr != nil {
+		if sr.parent != nil {
+			return sr.parent
+		}
+		if sr.isolated {
+			return sr
+		}
+	}
+	return GetSchemaRegistry()
+}
+
 // Get fetches a schema from the top level context registry or fetches it from a remote
 func (sr *SchemaRegistry) Get(ctx context.Context, uri string) *Schema {
+	schema, _ := sr.fetch(ctx, uri, "")
+	return schema
+}
+
+// fetch works like Get, returning the error of fetching the schema.
+// referrer is the URI of the document referencing the schema
+func (sr *SchemaRegistry) fetch(ctx context.Context, uri, referrer string) (*Schema, error) {
 	uri = strings.TrimRight(uri, "#")
-	schema := sr.schemaLookup[uri]
+	schema := sr.GetKnown(uri)
+	if schema == nil && IsKnownDialect(uri) {
+		// meta-schemas of the supported dialects are bundled
+		return MetaSchema(uri)
+	}
 	if schema == nil {
+		sr.lock.RLock()
+		state := fetchState{
+			depth:     sr.fetchDepths[strings.Split(referrer, "#")[0]] + 1,
+			documents: len(sr.fetchDepths),
+		}
+		sr.lock.RUnlock()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx = context.WithValue(ctx, fetchStateCtxKey{}, state)
+		// meta-schemas of the fetched schema resolve with this registry
+		ctx = WithSchemaRegistry(ctx, sr.root())
+
+		var doc []byte
+		ctx = context.WithValue(ctx, fetchedDocCtxKey{}, &doc)
+
 		fetchedSchema := &Schema{}
 		err := FetchSchema(ctx, uri, fetchedSchema)
 		if err != nil {
 			schemaDebug(fmt.Sprintf("[SchemaRegistry] Fetch error: %s", err.Error()))
-			return nil
+			return nil, err
 		}
-		if fetchedSchema == nil {
-			return nil
+		if sr.strict {
+			if err := metaValidateSchema(ctx, fetchedSchema, doc); err != nil {
+				schemaDebug(fmt.Sprintf("[SchemaRegistry] Invalid schema %s: %s", uri, err.Error()))
+				return nil, err
+			}
 		}
 		fetchedSchema.docPath = uri
-		// TODO(arqu): meta validate schema
 		schema = fetchedSchema
-		sr.schemaLookup[uri] = schema
+		sr.lock.Lock()
+		sr.lookup()[uri] = schema
+		if sr.fetchDepths == nil {
+			sr.fetchDepths = map[string]int{}
+		}
+		sr.fetchDepths[uri] = state.depth
+		sr.lock.Unlock()
 	}
-	return schema
+	return schema, nil
+}
+
+// PreloadFS registers the schemas of all JSON files in fsys by their
+// $id, so references to them resolve without fetching. Each file holds a
+// schema with an absolute $id, or an array of them as PreloadBundle
+// accepts. Use os.DirFS to preload a directory
+func (sr *SchemaRegistry) PreloadFS(fsys fs.FS) error {
+	seen := map[string]string{}
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(name) != ".json" {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if err := sr.preload(data, name, seen); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// PreloadBundle registers the schemas of a bundle by their $id, so
+// references to them resolve without fetching. The bundle is either a
+// schema with an absolute $id or an array of them. Schemas embedded in
+// a bundled schema with an $id of their own are registered as well
+func (sr *SchemaRegistry) PreloadBundle(data []byte) error {
+	return sr.preload(data, "bundle", map[string]string{})
+}
+
+// preload registers the schemas of a bundle read from source, reporting
+// an $id seen before in another source
+func (sr *SchemaRegistry) preload(data []byte, source string, seen map[string]string) error {
+	ctx := WithSchemaRegistry(context.Background(), sr.root())
+	var schemas []*Schema
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		raws := []json.RawMessage{}
+		if err := json.Unmarshal(data, &raws); err != nil {
+			return err
+		}
+		for _, raw := range raws {
+			var sch *Schema
+			if !bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+				sch = &Schema{}
+				if err := UnmarshalSchema(ctx, raw, sch); err != nil {
+					return err
+				}
+			}
+			schemas = append(schemas, sch)
+		}
+	} else {
+		sch := &Schema{}
+		if err := UnmarshalSchema(ctx, data, sch); err != nil {
+			return err
+		}
+		schemas = append(schemas, sch)
+	}
+
+	for i, sch := range schemas {
+		if sch == nil || sch.id == "" {
+			return fmt.Errorf("schema %d has no $id", i)
+		}
+		u, err := url.Parse(sch.id)
+		if err != nil || !u.IsAbs() {
+			return fmt.Errorf("schema %d has a relative $id %q", i, sch.id)
+		}
+		id := strings.TrimRight(u.String(), "#")
+		if other, ok := seen[id]; ok {
+			return fmt.Errorf("$id %s is already declared in %s", id, other)
+		}
+		seen[id] = source
+		sch.Register("", sr)
+	}
+	return nil
+}
+
+// SetStrict toggles validation of fetched schemas against the meta-schema
+// of their dialect. Schemas failing validation are not resolved
+func (sr *SchemaRegistry) SetStrict(strict bool) {
+	sr.strict = strict
+}
+
+// fetchedDocCtxKey is the context key of the document a fetched schema is
+// decoded from, recorded by UnmarshalSchema
+type fetchedDocCtxKey struct{}
+
+// metaValidateSchema checks a fetched schema against its meta-schema.
+// doc is the document the schema was decoded from, or nil if the loader
+// didn't decode it with UnmarshalSchema in which case the schema is
+// checked as it encodes
+func metaValidateSchema(ctx context.Context, sch *Schema, doc []byte) error {
+	if doc == nil {
+		data, err := json.Marshal(sch)
+		if err != nil {
+			return err
+		}
+		doc = data
+	}
+	errs, err := ValidateSchema(ctx, doc)
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return &InvalidSchemaError{Errs: errs}
+	}
+	return nil
 }
 
 // GetKnown fetches a schema from the top level context registry
 func (sr *SchemaRegistry) GetKnown(uri string) *Schema {
 	uri = strings.TrimRight(uri, "#")
+	sr.lock.RLock()
+	defer sr.lock.RUnlock()
 	return sr.schemaLookup[uri]
 }
 
@@ -70,11 +287,17 @@ func (sr *SchemaRegistry) Register(sch *Schema) {
 	if sch.docPath == "" {
 		return
 	}
-	sr.schemaLookup[sch.docPath] = sch
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	sr.lookup()[strings.TrimRight(sch.docPath, "#")] = sch
 }
 
 // RegisterLocal registers a schema to a local context
 func (sr *SchemaRegistry) RegisterLocal(sch *Schema) {
+	if sr.contextLookup == nil {
+		sr.contextLookup = map[string]*Schema{}
+	}
+
 	if sch.id != "" && IsLocalSchemaID(sch.id) {
 		sr.contextLookup[sch.id] = sch
 	}
@@ -82,9 +305,30 @@ func (sr *SchemaRegistry) RegisterLocal(sch *Schema) {
 	if sch.HasKeyword("$anchor") {
 		anchorKeyword := sch.keywords["$anchor"].(*Anchor)
 		anchorURI := sch.docPath + "#" + string(*anchorKeyword)
-		if sr.contextLookup == nil {
-			sr.contextLookup = map[string]*Schema{}
-		}
 		sr.contextLookup[anchorURI] = sch
 	}
+
+	if anchor := sch.dynamicAnchor(); anchor != "" {
+		sr.contextLookup[sch.docPath+"#"+anchor] = sch
+	}
+}
+
+// RegisterAnchor registers a schema to the top level context by
+// the URI of its schema resource and an anchor name
+func (sr *SchemaRegistry) RegisterAnchor(uri, anchor string, sch *Schema) {
+	if uri == "" || anchor == "" {
+		return
+	}
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	sr.lookup()[strings.TrimRight(uri, "#")+"#"+anchor] = sch
+}
+
+// lookup returns the top level lookup table, allocating it if needed.
+// Callers must hold the write lock
+func (sr *SchemaRegistry) lookup() map[string]*Schema {
+	if sr.schemaLookup == nil {
+		sr.schemaLookup = map[string]*Schema{}
+	}
+	return sr.schemaLookup
 }
@@ -0,0 +1,250 @@
+package jsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestScopedSchemaRegistry(t *testing.T) {
+	ctx := context.Background()
+	tenants := map[string]struct {
+		shared string
+		valid  interface{}
+	}{
+		"a": {`{"$id": "https://example.com/scoped/shared", "type": "string"}`, "text"},
+		"b": {`{"$id": "https://example.com/scoped/shared", "type": "number"}`, 5.0},
+	}
+
+	for name, tenant := range tenants {
+		registry := NewSchemaRegistry()
+		shared := &Schema{}
+		if err := json.Unmarshal([]byte(tenant.shared), shared); err != nil {
+			t.Fatal(err)
+		}
+		shared.Register("", registry)
+
+		tctx := WithSchemaRegistry(ctx, registry)
+		if SchemaRegistryFromContext(tctx) != registry {
+			t.Fatalf("tenant %s: expected the registry to be available from the context", name)
+		}
+
+		rs := &Schema{}
+		if err := json.Unmarshal([]byte(`{"properties": {"value": {"$ref": "https://example.com/scoped/shared"}}}`), rs); err != nil {
+			t.Fatal(err)
+		}
+		if state := rs.Validate(tctx, map[string]interface{}{"value": tenant.valid}); !state.IsValid() {
+			t.Errorf("tenant %s: expected instance to be valid. got: %v", name, *state.Errs)
+		}
+		if state := rs.Validate(tctx, map[string]interface{}{"value": true}); state.IsValid() {
+			t.Errorf("tenant %s: expected instance to be invalid", name)
+		}
+
+		compiled := &Schema{}
+		if err := json.Unmarshal([]byte(`{"$ref": "https://example.com/scoped/shared"}`), compiled); err != nil {
+			t.Fatal(err)
+		}
+		cs, err := Compile(tctx, compiled)
+		if err != nil {
+			t.Fatalf("tenant %s: %s", name, err)
+		}
+		if state := cs.Validate(ctx, tenant.valid); !state.IsValid() {
+			t.Errorf("tenant %s: expected compiled schema to resolve with the tenant registry. got: %v", name, *state.Errs)
+		}
+	}
+
+	if GetSchemaRegistry().GetKnown("https://example.com/scoped/shared") != nil {
+		t.Error("expected schemas of scoped registries to stay out of the global registry")
+	}
+	if SchemaRegistryFromContext(ctx) != GetSchemaRegistry() {
+		t.Error("expected the global registry to be the default")
+	}
+}
+
+func TestSchemaRegistriesShareSchema(t *testing.T) {
+	ctx := context.Background()
+	targets := []struct {
+		target  string
+		valid   interface{}
+		invalid interface{}
+	}{
+		{`{"$id": "https://example.com/shared/target.json", "type": "string"}`, "text", 5.0},
+		{`{"$id": "https://example.com/shared/target.json", "type": "integer"}`, 5.0, "text"},
+	}
+	registries := make([]*SchemaRegistry, len(targets))
+	for i, target := range targets {
+		registries[i] = NewSchemaRegistry()
+		sch := &Schema{}
+		if err := json.Unmarshal([]byte(target.target), sch); err != nil {
+			t.Fatal(err)
+		}
+		sch.Register("", registries[i])
+	}
+
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{
+		"properties": {
+			"value": {"$ref": "https://example.com/shared/target.json"},
+			"self": {"$ref": "https://example.com/shared/embedded.json"}
+		},
+		"$defs": {
+			"embedded": {"$id": "https://example.com/shared/embedded.json", "type": "boolean"}
+		}
+	}`), rs); err != nil {
+		t.Fatal(err)
+	}
+	// validate twice so the second round resolves with known registries
+	for round := 0; round < 2; round++ {
+		for i, target := range targets {
+			rctx := WithSchemaRegistry(ctx, registries[i])
+			if state := rs.Validate(rctx, map[string]interface{}{"value": target.valid, "self": true}); !state.IsValid() {
+				t.Errorf("round %d registry %d: expected instance to be valid. got: %v", round, i, *state.Errs)
+			}
+			if state := rs.Validate(rctx, map[string]interface{}{"value": target.invalid}); state.IsValid() {
+				t.Errorf("round %d registry %d: expected the target of the registry to reject %v", round, i, target.invalid)
+			}
+			if state := rs.Validate(rctx, map[string]interface{}{"self": "text"}); state.IsValid() {
+				t.Errorf("round %d registry %d: expected the embedded schema to reject a string", round, i)
+			}
+		}
+	}
+
+	compiled := &Schema{}
+	if err := json.Unmarshal([]byte(`{"$ref": "https://example.com/shared/target.json"}`), compiled); err != nil {
+		t.Fatal(err)
+	}
+	cs, err := Compile(WithSchemaRegistry(ctx, registries[0]), compiled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := cs.Validate(WithSchemaRegistry(ctx, registries[1]), targets[1].valid); state.IsValid() {
+		t.Error("expected the compiled schema to keep resolving with the registry it was compiled with")
+	}
+	if state := compiled.Validate(WithSchemaRegistry(ctx, registries[1]), targets[1].valid); !state.IsValid() {
+		t.Errorf("expected the schema to resolve with the registry of the context. got: %v", *state.Errs)
+	}
+}
+
+func TestPreloadSchemas(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"person.json": {Data: []byte(`{
+			"$id": "https://example.com/preload/person",
+			"type": "object",
+			"properties": {"address": {"$ref": "address"}}
+		}`)},
+		"nested/address.json": {Data: []byte(`{
+			"$id": "https://example.com/preload/address",
+			"properties": {"zip": {"$ref": "types#/$defs/zip"}}
+		}`)},
+		"nested/types.json": {Data: []byte(`[
+			{"$id": "https://example.com/preload/types", "$defs": {"zip": {"type": "string"}}}
+		]`)},
+		"README.md": {Data: []byte(`not a schema`)},
+	}
+
+	registry := NewSchemaRegistry()
+	if err := registry.PreloadFS(fsys); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"person", "address", "types"} {
+		if registry.GetKnown("https://example.com/preload/"+id) == nil {
+			t.Errorf("expected %s to be preloaded", id)
+		}
+	}
+
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{"$ref": "https://example.com/preload/person"}`), rs); err != nil {
+		t.Fatal(err)
+	}
+	state := rs.Validate(WithSchemaRegistry(ctx, registry), map[string]interface{}{
+		"address": map[string]interface{}{"zip": 12345},
+	})
+	if len(*state.Errs) != 1 || (*state.Errs)[0].PropertyPath != "/address/zip" {
+		t.Errorf("expected an error at /address/zip. got: %v", *state.Errs)
+	}
+
+	bundle := []byte(`{
+		"$id": "https://example.com/bundle/root",
+		"$defs": {
+			"embedded": {"$id": "https://example.com/bundle/embedded", "type": "integer"}
+		}
+	}`)
+	if err := registry.PreloadBundle(bundle); err != nil {
+		t.Fatal(err)
+	}
+	if registry.GetKnown("https://example.com/bundle/embedded") == nil {
+		t.Error("expected embedded schema resources of a bundle to be registered")
+	}
+
+	invalid := map[string]string{
+		`{"type": "string"}`:                    "has no $id",
+		`{"$id": "relative", "type": "string"}`: "has a relative $id",
+		`[{"$id": "https://example.com/dup"}, {"$id": "https://example.com/dup"}]`: "already declared",
+		`{`: "unexpected end of JSON input",
+	}
+	for data, message := range invalid {
+		if err := NewSchemaRegistry().PreloadBundle([]byte(data)); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%s: expected error to include %q. got: %v", data, message, err)
+		}
+	}
+}
+
+func TestDenyNetwork(t *testing.T) {
+	ctx := context.Background()
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"type": "string"}`))
+	}))
+	defer ts.Close()
+
+	lr := GetSchemaLoaderRegistry()
+	lr.SetDenyNetwork(true)
+	defer lr.SetDenyNetwork(false)
+
+	registry := NewSchemaRegistry()
+	if err := registry.PreloadBundle([]byte(`{"$id": "http://example.com/deny/known", "type": "string"}`)); err != nil {
+		t.Fatal(err)
+	}
+	ctx = WithSchemaRegistry(ctx, registry)
+
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{"properties": {
+		"known": {"$ref": "http://example.com/deny/known"},
+		"remote": {"$ref": "`+ts.URL+`/remote.json"}
+	}}`), rs); err != nil {
+		t.Fatal(err)
+	}
+
+	state := rs.Validate(ctx, map[string]interface{}{"known": 1, "remote": "a"})
+	expect := "unresolvable reference " + ts.URL + "/remote.json: network access is denied"
+	found := false
+	for _, err := range *state.Errs {
+		if err.Message == expect {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected an unresolvable reference error. got: %v", *state.Errs)
+	}
+
+	_, err := Compile(ctx, rs)
+	var unresolvable *UnresolvableReferenceError
+	if !errors.As(err, &unresolvable) || len(unresolvable.URIs) != 1 || unresolvable.URIs[0] != ts.URL+"/remote.json" {
+		t.Errorf("expected compiling to fail with an UnresolvableReferenceError. got: %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("expected no requests. got: %d", requests)
+	}
+
+	lr.SetDenyNetwork(false)
+	if _, err := Compile(ctx, rs); err != nil {
+		t.Errorf("expected the reference to resolve with network access: %s", err)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/big"
+	"os"
 	"path/filepath"
 	"reflect"
 	"strconv"
@@ -15,7 +17,7 @@ import (
 	"github.com/sergi/go-diff/diffmatchpatch"
 )
 
-func ExampleBasic() {
+func Example_basic() {
 	ctx := context.Background()
 	var schemaData = []byte(`{
 	"title": "Person",
@@ -266,50 +268,55 @@ func TestDraft3(t *testing.T) {
 }
 
 func TestDraft4(t *testing.T) {
+	LoadDraft4()
+	defer LoadDraft2019_09()
+
+	registerMetaSchemas(t, "meta/draft-04/schema.json")
 	runJSONTests(t, []string{
 		"testdata/draft4/additionalItems.json",
 		"testdata/draft4/allOf.json",
 		"testdata/draft4/anyOf.json",
 		"testdata/draft4/default.json",
+		"testdata/draft4/definitions.json",
+		"testdata/draft4/dependencies.json",
 		"testdata/draft4/enum.json",
 		"testdata/draft4/format.json",
+		"testdata/draft4/items.json",
+		"testdata/draft4/maximum.json",
 		"testdata/draft4/maxItems.json",
 		"testdata/draft4/maxLength.json",
 		"testdata/draft4/maxProperties.json",
+		"testdata/draft4/minimum.json",
 		"testdata/draft4/minItems.json",
 		"testdata/draft4/minLength.json",
 		"testdata/draft4/minProperties.json",
 		"testdata/draft4/multipleOf.json",
 		"testdata/draft4/not.json",
 		"testdata/draft4/oneOf.json",
+		"testdata/draft4/optional/bignum.json",
 		"testdata/draft4/optional/format.json",
 		"testdata/draft4/pattern.json",
 		"testdata/draft4/patternProperties.json",
 		"testdata/draft4/properties.json",
+		"testdata/draft4/ref.json",
 		"testdata/draft4/required.json",
 		"testdata/draft4/type.json",
 		"testdata/draft4/uniqueItems.json",
 
 		// disabled due to changes in spec
-		// "testdata/draft4/maximum.json",
-		// "testdata/draft4/minimum.json",
-		// "testdata/draft4/ref.json",
 		// "testdata/draft4/refRemote.json",
 		// "testdata/draft4/optional/zeroTerminatedFloats.json",
 
-		// TODO(arqu): implement this
-		// "testdata/draft4/definitions.json",
-		// "testdata/draft4/dependencies.json",
-		// "testdata/draft4/items.json",
-
 		// wont fix
 		// "testdata/draft4/additionalProperties.json",
-		// "testdata/draft4/optional/bignum.json",
-		// "testdata/draft4/optional/ecmascript-regex.json",
 	})
 }
 
 func TestDraft6(t *testing.T) {
+	LoadDraft6()
+	defer LoadDraft2019_09()
+
+	registerMetaSchemas(t, "meta/draft-06/schema.json")
 	runJSONTests(t, []string{
 		"testdata/draft6/additionalItems.json",
 		"testdata/draft6/allOf.json",
@@ -318,10 +325,13 @@ func TestDraft6(t *testing.T) {
 		"testdata/draft6/const.json",
 		"testdata/draft6/contains.json",
 		"testdata/draft6/default.json",
+		"testdata/draft6/definitions.json",
+		"testdata/draft6/dependencies.json",
 		"testdata/draft6/enum.json",
 		"testdata/draft6/exclusiveMaximum.json",
 		"testdata/draft6/exclusiveMinimum.json",
 		"testdata/draft6/format.json",
+		"testdata/draft6/items.json",
 		"testdata/draft6/maximum.json",
 		"testdata/draft6/maxItems.json",
 		"testdata/draft6/maxLength.json",
@@ -337,40 +347,26 @@ func TestDraft6(t *testing.T) {
 		"testdata/draft6/patternProperties.json",
 		"testdata/draft6/properties.json",
 		"testdata/draft6/propertyNames.json",
+		"testdata/draft6/ref.json",
 		"testdata/draft6/required.json",
 		"testdata/draft6/type.json",
 		"testdata/draft6/uniqueItems.json",
 
 		"testdata/draft6/optional/format.json",
+		"testdata/draft6/optional/bignum.json",
 		"testdata/draft6/optional/zeroTerminatedFloats.json",
 
-		// TODO(arqu): implement this
-		// "testdata/draft6/definitions.json",
-		// "testdata/draft6/dependencies.json",
-		// "testdata/draft6/items.json",
-		// "testdata/draft6/ref.json",
-
 		// wont fix
 		// "testdata/draft6/additionalProperties.json",
 		// "testdata/draft6/refRemote.json",
-		// "testdata/draft6/optional/bignum.json",
-		// "testdata/draft6/optional/ecmascript-regex.json",
 	})
 }
 
 func TestDraft7(t *testing.T) {
-	path := "testdata/draft-07_schema.json"
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		t.Errorf("error reading %s: %s", path, err.Error())
-		return
-	}
+	LoadDraft7()
+	defer LoadDraft2019_09()
 
-	rsch := &Schema{}
-	if err := json.Unmarshal(data, rsch); err != nil {
-		t.Errorf("error unmarshaling schema: %s", err.Error())
-		return
-	}
+	registerMetaSchemas(t, "meta/draft-07/schema.json")
 
 	runJSONTests(t, []string{
 		"testdata/draft7/additionalItems.json",
@@ -404,6 +400,7 @@ func TestDraft7(t *testing.T) {
 		"testdata/draft7/type.json",
 		"testdata/draft7/uniqueItems.json",
 
+		"testdata/draft7/optional/bignum.json",
 		"testdata/draft7/optional/zeroTerminatedFloats.json",
 		"testdata/draft7/optional/format/date-time.json",
 		"testdata/draft7/optional/format/date.json",
@@ -414,6 +411,7 @@ func TestDraft7(t *testing.T) {
 		"testdata/draft7/optional/format/ipv4.json",
 		"testdata/draft7/optional/format/ipv6.json",
 		"testdata/draft7/optional/format/iri-reference.json",
+		"testdata/draft7/optional/format/iri.json",
 		"testdata/draft7/optional/format/json-pointer.json",
 		"testdata/draft7/optional/format/regex.json",
 		"testdata/draft7/optional/format/relative-json-pointer.json",
@@ -422,35 +420,27 @@ func TestDraft7(t *testing.T) {
 		"testdata/draft7/optional/format/uri-template.json",
 		"testdata/draft7/optional/format/uri.json",
 
-		// TODO(arqu): implement this
-		// "testdata/draft7/definitions.json",
-		// "testdata/draft7/dependencies.json",
-		// "testdata/draft7/items.json",
-		// "testdata/draft7/ref.json",
+		"testdata/draft7/definitions.json",
+		"testdata/draft7/dependencies.json",
+		"testdata/draft7/items.json",
+		"testdata/draft7/ref.json",
 
 		// wont fix
 		// "testdata/draft7/additionalProperties.json",
 		// "testdata/draft7/refRemote.json",
-		// "testdata/draft7/optional/bignum.json",
-		// "testdata/draft7/optional/content.json",
-		// "testdata/draft7/optional/ecmascript-regex.json",
-		// "testdata/draft7/optional/format/iri.json",
 	})
 }
 
 func TestDraft2019_09(t *testing.T) {
-	path := "testdata/draft2019-09_schema.json"
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		t.Errorf("error reading %s: %s", path, err.Error())
-		return
-	}
-
-	rsch := &Schema{}
-	if err := json.Unmarshal(data, rsch); err != nil {
-		t.Errorf("error unmarshaling schema: %s", err.Error())
-		return
-	}
+	registerMetaSchemas(t,
+		"meta/draft/2019-09/schema.json",
+		"meta/draft/2019-09/meta/core.json",
+		"meta/draft/2019-09/meta/applicator.json",
+		"meta/draft/2019-09/meta/validation.json",
+		"meta/draft/2019-09/meta/meta-data.json",
+		"meta/draft/2019-09/meta/format.json",
+		"meta/draft/2019-09/meta/content.json",
+	)
 
 	runJSONTests(t, []string{
 		"testdata/draft2019-09/additionalItems.json",
@@ -493,9 +483,11 @@ func TestDraft2019_09(t *testing.T) {
 		// "testdata/draft2019-09/unevaluatedItems.json",
 		"testdata/draft2019-09/uniqueItems.json",
 
+		"testdata/draft2019-09/optional/bignum.json",
 		"testdata/draft2019-09/optional/zeroTerminatedFloats.json",
 		"testdata/draft2019-09/optional/format/date-time.json",
 		"testdata/draft2019-09/optional/format/date.json",
+		"testdata/draft2019-09/optional/format/duration.json",
 		"testdata/draft2019-09/optional/format/email.json",
 		"testdata/draft2019-09/optional/format/hostname.json",
 		"testdata/draft2019-09/optional/format/idn-email.json",
@@ -503,6 +495,7 @@ func TestDraft2019_09(t *testing.T) {
 		"testdata/draft2019-09/optional/format/ipv4.json",
 		"testdata/draft2019-09/optional/format/ipv6.json",
 		"testdata/draft2019-09/optional/format/iri-reference.json",
+		"testdata/draft2019-09/optional/format/iri.json",
 		"testdata/draft2019-09/optional/format/json-pointer.json",
 		"testdata/draft2019-09/optional/format/regex.json",
 		"testdata/draft2019-09/optional/format/relative-json-pointer.json",
@@ -524,20 +517,138 @@ func TestDraft2019_09(t *testing.T) {
 
 		// wont fix
 		// "testdata/draft2019-09/refRemote.json",
-		// "testdata/draft2019-09/optional/bignum.json",
-		// "testdata/draft2019-09/optional/content.json",
-		// "testdata/draft2019-09/optional/ecmascript-regex.json",
 		// "testdata/draft2019-09/optional/refOfUnknownKeyword.json",
+	})
+}
+
+func TestDraft2020_12(t *testing.T) {
+	LoadDraft2020_12()
+	defer LoadDraft2019_09()
+	serveRemotes(t)
+
+	runJSONTests(t, []string{
+		"testdata/draft2020-12/additionalProperties.json",
+		"testdata/draft2020-12/allOf.json",
+		"testdata/draft2020-12/anchor.json",
+		"testdata/draft2020-12/anyOf.json",
+		"testdata/draft2020-12/boolean_schema.json",
+		"testdata/draft2020-12/const.json",
+		"testdata/draft2020-12/contains.json",
+		"testdata/draft2020-12/content.json",
+		"testdata/draft2020-12/default.json",
+		"testdata/draft2020-12/defs.json",
+		"testdata/draft2020-12/dependentRequired.json",
+		"testdata/draft2020-12/dependentSchemas.json",
+		"testdata/draft2020-12/dynamicRef.json",
+		"testdata/draft2020-12/enum.json",
+		"testdata/draft2020-12/exclusiveMaximum.json",
+		"testdata/draft2020-12/exclusiveMinimum.json",
+		"testdata/draft2020-12/format.json",
+		"testdata/draft2020-12/id.json",
+		"testdata/draft2020-12/if-then-else.json",
+		"testdata/draft2020-12/infinite-loop-detection.json",
+		"testdata/draft2020-12/items.json",
+		"testdata/draft2020-12/maxContains.json",
+		"testdata/draft2020-12/maxItems.json",
+		"testdata/draft2020-12/maxLength.json",
+		"testdata/draft2020-12/maxProperties.json",
+		"testdata/draft2020-12/maximum.json",
+		"testdata/draft2020-12/minContains.json",
+		"testdata/draft2020-12/minItems.json",
+		"testdata/draft2020-12/minLength.json",
+		"testdata/draft2020-12/minProperties.json",
+		"testdata/draft2020-12/minimum.json",
+		"testdata/draft2020-12/multipleOf.json",
+		"testdata/draft2020-12/not.json",
+		"testdata/draft2020-12/oneOf.json",
+		"testdata/draft2020-12/pattern.json",
+		"testdata/draft2020-12/patternProperties.json",
+		"testdata/draft2020-12/prefixItems.json",
+		"testdata/draft2020-12/properties.json",
+		"testdata/draft2020-12/propertyNames.json",
+		"testdata/draft2020-12/ref.json",
+		"testdata/draft2020-12/refRemote.json",
+		"testdata/draft2020-12/required.json",
+		"testdata/draft2020-12/type.json",
+		"testdata/draft2020-12/unevaluatedItems.json",
+		"testdata/draft2020-12/unevaluatedProperties.json",
+		"testdata/draft2020-12/uniqueItems.json",
+		"testdata/draft2020-12/unknownKeyword.json",
+
+		"testdata/draft2020-12/optional/bignum.json",
+		"testdata/draft2020-12/optional/float-overflow.json",
+		"testdata/draft2020-12/optional/non-bmp-regex.json",
+		"testdata/draft2020-12/optional/refOfUnknownKeyword.json",
+
+		// run with the ECMA-262 engine in TestSetRegexpEngine
+		// "testdata/draft2020-12/optional/ecmascript-regex.json",
+	})
 
-		// TODO(arqu): iri fails on IPV6 not having [] around the address
-		// which was a legal format in draft7
-		// introduced: https://github.com/json-schema-org/JSON-Schema-Test-Suite/commit/2146b02555b163da40ae98e60bf36b2c2f8d4bd0#diff-b2ca98716e146559819bc49635a149a9
-		// relevant RFC: https://tools.ietf.org/html/rfc3986#section-3.2.2
-		// relevant 'net/url' package discussion: https://github.com/golang/go/issues/31024
-		// "testdata/draft2019-09/optional/format/iri.json",
+	// unicode.json expects \w and \d to match non-ASCII letters and
+	// digits, which neither RE2Engine nor ECMAScriptEngine do following
+	// ecmascript-regex.json, so it runs with an engine of its own
+	SetRegexpEngine(unicodeClassEngine{})
+	runJSONTests(t, []string{
+		"testdata/draft2020-12/optional/unicode.json",
+	})
+	SetRegexpEngine(RE2Engine)
+
+	// formats are only annotations in draft 2020-12 unless asserted
+	runJSONTestsContext(WithFormatMode(context.Background(), FormatAssertKnown), t, []string{
+		"testdata/draft2020-12/optional/format/date-time.json",
+		"testdata/draft2020-12/optional/format/date.json",
+		"testdata/draft2020-12/optional/format/duration.json",
+		"testdata/draft2020-12/optional/format/email.json",
+		"testdata/draft2020-12/optional/format/hostname.json",
+		"testdata/draft2020-12/optional/format/idn-email.json",
+		"testdata/draft2020-12/optional/format/idn-hostname.json",
+		"testdata/draft2020-12/optional/format/ipv4.json",
+		"testdata/draft2020-12/optional/format/ipv6.json",
+		"testdata/draft2020-12/optional/format/iri-reference.json",
+		"testdata/draft2020-12/optional/format/iri.json",
+		"testdata/draft2020-12/optional/format/json-pointer.json",
+		"testdata/draft2020-12/optional/format/regex.json",
+		"testdata/draft2020-12/optional/format/relative-json-pointer.json",
+		"testdata/draft2020-12/optional/format/time.json",
+		"testdata/draft2020-12/optional/format/uri-reference.json",
+		"testdata/draft2020-12/optional/format/uri-template.json",
+		"testdata/draft2020-12/optional/format/uri.json",
+		"testdata/draft2020-12/optional/format/uuid.json",
+	})
+}
+
+// serveRemotes serves the remote schemas of the test suite, which tests
+// reference at http://localhost:1234/, from testdata/remotes
+func serveRemotes(t *testing.T) {
+	lr := GetSchemaLoaderRegistry()
+	fallback, _ := lr.Get("http")
+	loader, err := NewFSSchemaLoader(os.DirFS("testdata/remotes"), "http://localhost:1234/", fallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lr.Register("http", loader)
+	t.Cleanup(func() {
+		lr.Register("http", fallback)
 	})
 }
 
+// registerMetaSchemas loads meta-schemas from disk and registers them
+// by $id so references to them resolve without network access
+func registerMetaSchemas(t *testing.T, paths ...string) {
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("error reading %s: %s", path, err.Error())
+		}
+
+		rsch := &Schema{}
+		if err := json.Unmarshal(data, rsch); err != nil {
+			t.Fatalf("error unmarshaling schema %s: %s", path, err.Error())
+		}
+		rsch.Register("", &SchemaRegistry{})
+	}
+}
+
 // TestSet is a json-based set of tests
 // JSON-Schema comes with a lovely JSON-based test suite:
 // https://github.com/json-schema-org/JSON-Schema-Test-Suite
@@ -554,9 +665,13 @@ type TestCase struct {
 }
 
 func runJSONTests(t *testing.T, testFilepaths []string) {
+	runJSONTestsContext(context.Background(), t, testFilepaths)
+}
+
+// runJSONTestsContext runs test suite files validating with the given context
+func runJSONTestsContext(ctx context.Context, t *testing.T, testFilepaths []string) {
 	tests := 0
 	passed := 0
-	ctx := context.Background()
 	for _, path := range testFilepaths {
 		t.Run(path, func(t *testing.T) {
 			base := filepath.Base(path)
@@ -567,7 +682,7 @@ func runJSONTests(t *testing.T, testFilepaths []string) {
 				return
 			}
 
-			if err := json.Unmarshal(data, &testSets); err != nil {
+			if err := decodeJSON(data, &testSets); err != nil {
 				t.Errorf("error unmarshaling test set %s from JSON: %s", base, err.Error())
 				return
 			}
@@ -624,6 +739,14 @@ func TestDataType(t *testing.T) {
 		{float32(42), "integer"},
 		{float32(42.0), "integer"},
 		{float32(42.5), "number"},
+		{float64(1e52), "integer"},
+		{json.Number("42"), "integer"},
+		{json.Number("42.0"), "integer"},
+		{json.Number("42.5"), "number"},
+		{json.Number("12345678910111213141516171819202122232425262728293031"), "integer"},
+		{big.NewInt(42), "integer"},
+		{big.NewFloat(42.5), "number"},
+		{big.NewRat(1, 3), "number"},
 		// special cases which should pass with type hints
 		{"true", "boolean"},
 		{4.0, "number"},
@@ -637,6 +760,79 @@ func TestDataType(t *testing.T) {
 	}
 }
 
+func TestNumericPrecision(t *testing.T) {
+	ctx := context.Background()
+	huge, _ := new(big.Int).SetString("18446744073709551617", 10)
+	cases := []struct {
+		schema string
+		data   interface{}
+		valid  bool
+	}{
+		{`{"multipleOf": 0.01}`, 19.99, true},
+		{`{"multipleOf": 0.01}`, json.Number("19.99"), true},
+		{`{"multipleOf": 0.01}`, json.Number("19.991"), false},
+		{`{"multipleOf": 0.1}`, float32(0.3), true},
+		{`{"multipleOf": 3}`, huge, false},
+		{`{"multipleOf": 0.25}`, big.NewFloat(2.5), true},
+		{`{"maximum": 18446744073709551616}`, huge, false},
+		{`{"maximum": 18446744073709551616}`, uint64(18446744073709551615), true},
+		{`{"exclusiveMinimum": 9007199254740992}`, json.Number("9007199254740993"), true},
+		{`{"exclusiveMinimum": 9007199254740992}`, int64(9007199254740992), false},
+		{`{"minimum": 1e-400}`, json.Number("0"), false},
+		{`{"maximum": 1e400}`, json.Number("1e401"), false},
+		{`{"maximum": 3}`, json.Number("5e10001"), false},
+		{`{"minimum": 3}`, json.Number("5e-10001"), false},
+		{`{"minimum": -3}`, json.Number("-5e10001"), false},
+		{`{"minimum": 3}`, json.Number("0e10001"), false},
+		{`{"maximum": 3}`, json.Number("0e10001"), true},
+		{`{"multipleOf": 3}`, json.Number("3e10001"), false},
+		{`{"type": "integer"}`, json.Number("1e10001"), true},
+		{`{"type": "integer"}`, json.Number("1e-10001"), false},
+		{`{"maximum": 3}`, 2.5, true},
+		{`{"maximum": 3}`, 3.0, true},
+		{`{"exclusiveMaximum": 3}`, 3.0, false},
+		{`{"exclusiveMaximum": 3}`, json.Number("3.0"), false},
+		{`{"exclusiveMaximum": 0.5}`, json.Number("0.49999999999999999999999"), true},
+		{`{"minimum": 0.1}`, 0.1, true},
+		{`{"exclusiveMinimum": 0.1}`, json.Number("0.1"), false},
+		{`{"multipleOf": 2}`, 42.0, true},
+		{`{"multipleOf": 2}`, int8(-3), false},
+		{`{"maximum": 1152921504606846976}`, float64(1 << 60), false},
+		{`{"const": 1}`, json.Number("1.0"), true},
+		{`{"const": 18446744073709551617}`, huge, true},
+		{`{"enum": [[0.1]]}`, []interface{}{big.NewRat(1, 10)}, true},
+		{`{"uniqueItems": true}`, []interface{}{json.Number("1"), 1.0}, false},
+	}
+	for i, c := range cases {
+		rs := &Schema{}
+		if err := json.Unmarshal([]byte(c.schema), rs); err != nil {
+			t.Fatal(err)
+		}
+		if state := rs.Validate(ctx, c.data); state.IsValid() != c.valid {
+			t.Errorf("case %d: %s %v: expected valid to be %t. got: %v", i, c.schema, c.data, c.valid, *state.Errs)
+		}
+	}
+
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{"maximum": 18446744073709551615, "multipleOf": 0.01}`), rs); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"maximum":18446744073709551615,"multipleOf":0.01}` {
+		t.Errorf("unexpected encoding: %s", data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"maximum": "5"}`), rs); err == nil {
+		t.Error("expected an error for a string maximum")
+	}
+	if err := json.Unmarshal([]byte(`{"maximum": 1e10001}`), rs); err == nil || !strings.Contains(err.Error(), "exponent is out of range") {
+		t.Errorf("expected an error for a maximum that can't be compared exactly. got: %v", err)
+	}
+}
+
 func TestJSONCoding(t *testing.T) {
 	cases := []string{
 		"testdata/coding/false.json",
@@ -742,6 +938,106 @@ func TestValidateBytes(t *testing.T) {
 	}
 }
 
+func TestDialectSelection(t *testing.T) {
+	ctx := context.Background()
+	embeddedDialectSchema := `{
+		"$schema": "https://json-schema.org/draft/2019-09/schema",
+		"properties": {
+			"a": {
+				"$id": "https://example.com/embedded-draft7",
+				"$schema": "http://json-schema.org/draft-07/schema#",
+				"properties": {
+					"x": {"$ref": "#/definitions/any", "type": "string"}
+				},
+				"definitions": {"any": {}}
+			},
+			"b": {"$ref": "#/$defs/any", "type": "string"}
+		},
+		"$defs": {"any": {}}
+	}`
+	cases := []struct {
+		description string
+		schema      string
+		input       string
+		valid       bool
+	}{
+		{"draft4 boolean exclusiveMaximum",
+			`{"$schema": "http://json-schema.org/draft-04/schema#", "maximum": 3, "exclusiveMaximum": true}`,
+			`3`, false},
+		{"draft2019-09 numeric exclusiveMaximum",
+			`{"$schema": "https://json-schema.org/draft/2019-09/schema", "maximum": 3, "exclusiveMaximum": 4}`,
+			`3`, true},
+		{"https scheme variant of draft7",
+			`{"$schema": "https://json-schema.org/draft-07/schema", "$ref": "#/definitions/a", "definitions": {"a": {}}, "type": "string"}`,
+			`1`, true},
+		{"subschemas inherit the dialect of their parent",
+			`{"$schema": "http://json-schema.org/draft-04/schema#", "properties": {"a": {"items": [{"maximum": 3, "exclusiveMaximum": true}]}}}`,
+			`{"a": [3]}`, false},
+		{"dependencies inherit the dialect of their parent",
+			`{"$schema": "http://json-schema.org/draft-04/schema#", "dependencies": {"a": {"properties": {"b": {"maximum": 3, "exclusiveMaximum": true}}}}}`,
+			`{"a": 1, "b": 3}`, false},
+		{"dependentSchemas inherit the dialect of their parent",
+			`{"$schema": "https://json-schema.org/draft/2019-09/schema", "dependentSchemas": {"a": {"properties": {"b": {"$ref": "#/$defs/any", "type": "string"}}}}, "$defs": {"any": {}}}`,
+			`{"a": 1, "b": 3}`, false},
+		{"embedded resources switch dialect",
+			embeddedDialectSchema, `{"a": {"x": 1}}`, true},
+		{"siblings of $ref apply outside of the embedded resource",
+			embeddedDialectSchema, `{"b": 1}`, false},
+		{"2020-12 prefixItems",
+			`{"$schema": "https://json-schema.org/draft/2020-12/schema", "prefixItems": [{"type": "string"}], "items": false}`,
+			`["a", "b"]`, false},
+		{"2020-12 contains evaluates items",
+			`{"$schema": "https://json-schema.org/draft/2020-12/schema", "contains": {"type": "string"}, "unevaluatedItems": {"type": "integer"}}`,
+			`[1, "a", 2]`, true},
+		{"2020-12 formats are annotations",
+			`{"$schema": "https://json-schema.org/draft/2020-12/schema", "format": "email"}`,
+			`"not an email"`, true},
+	}
+
+	for _, c := range cases {
+		rs := &Schema{}
+		if err := json.Unmarshal([]byte(c.schema), rs); err != nil {
+			t.Errorf("%s: error parsing schema: %s", c.description, err)
+			continue
+		}
+		errs, err := rs.ValidateBytes(ctx, []byte(c.input))
+		if err != nil {
+			t.Errorf("%s: error validating: %s", c.description, err)
+			continue
+		}
+		if got := len(errs) == 0; got != c.valid {
+			t.Errorf("%s: expected valid to be %t. errors: %v", c.description, c.valid, errs)
+		}
+	}
+
+	// prefixItems replaced the array form of items
+	rs := &Schema{}
+	err := json.Unmarshal([]byte(`{"$schema": "https://json-schema.org/draft/2020-12/schema", "items": [{"type": "string"}]}`), rs)
+	if err == nil || !strings.Contains(err.Error(), "use prefixItems") {
+		t.Errorf("expected the array form of items to be rejected in 2020-12. got: %v", err)
+	}
+}
+
+func TestFallbackDialect(t *testing.T) {
+	if err := LoadDialect("https://example.com/unknown"); err == nil {
+		t.Errorf("expected loading an unknown dialect to fail")
+	}
+
+	if err := LoadDialect(DialectDraft4); err != nil {
+		t.Fatal(err)
+	}
+	defer LoadDraft2019_09()
+
+	rs := Must(`{"maximum": 3, "exclusiveMaximum": true}`)
+	errs, err := rs.ValidateBytes(context.Background(), []byte(`3`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 {
+		t.Errorf("expected the draft4 fallback dialect to apply. got errors: %v", errs)
+	}
+}
+
 func BenchmarkAdditionalItems(b *testing.B) {
 	runBenchmark(b,
 		func(sampleSize int) (string, interface{}) {
@@ -1067,3 +1363,292 @@ func runBenchmark(b *testing.B, dataFn func(sampleSize int) (string, interface{}
 		})
 	}
 }
+
+func TestAnnotations(t *testing.T) {
+	ctx := WithAnnotations(context.Background(), true)
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{
+		"title": "user",
+		"properties": {
+			"id": { "title": "identifier", "readOnly": true, "format": "hostname", "$comment": "not an annotation" },
+			"name": {
+				"anyOf": [
+					{ "type": "number", "description": "numeric name" },
+					{ "type": "string", "description": "text name" }
+				]
+			},
+			"role": {
+				"if": { "const": "admin", "$comment": "admins" },
+				"then": { "default": "admin" },
+				"else": { "default": "user", "examples": [ "guest" ] }
+			}
+		}
+	}`), rs); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := map[string]interface{}{
+		"id":   "example.com",
+		"name": "ann",
+		"role": "editor",
+	}
+	if state := rs.Validate(context.Background(), doc); state.Annotations != nil {
+		t.Errorf("expected no annotations to be collected by default. got: %v", *state.Annotations)
+	}
+	state := rs.Validate(ctx, doc)
+	if !state.IsValid() {
+		t.Fatalf("expected instance to be valid. got: %v", *state.Errs)
+	}
+
+	cases := []struct {
+		location string
+		expect   map[string]interface{}
+	}{
+		{"", map[string]interface{}{"title": "user"}},
+		{"/id", map[string]interface{}{"title": "identifier", "readOnly": true, "format": "hostname"}},
+		{"/name", map[string]interface{}{"description": "text name"}},
+		{"/role", map[string]interface{}{"default": "user", "examples": []interface{}{"guest"}}},
+	}
+	for _, c := range cases {
+		got := map[string]interface{}{}
+		for _, a := range state.AnnotationsAt(c.location) {
+			got[a.Keyword] = a.Value
+		}
+		if !reflect.DeepEqual(c.expect, got) {
+			t.Errorf("annotations at %q mismatch. expected: %v, got: %v", c.location, c.expect, got)
+		}
+	}
+
+	for _, a := range state.AnnotationsAt("/id") {
+		if a.Keyword == "readOnly" && a.KeywordLocation != "/properties/id/readOnly" {
+			t.Errorf("keywordLocation mismatch. got: %s", a.KeywordLocation)
+		}
+	}
+
+	state = rs.Validate(ctx, map[string]interface{}{"name": true})
+	if state.IsValid() {
+		t.Fatal("expected instance to be invalid")
+	}
+	if got := state.AnnotationsAt("/name"); len(got) != 0 {
+		t.Errorf("expected annotations of failed branches to be dropped. got: %v", got)
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	ctx := context.Background()
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{
+		"$defs": {
+			"port": { "type": "integer", "default": 8080 },
+			"tls": {
+				"type": "object",
+				"properties": {
+					"enabled": { "default": false },
+					"ciphers": { "default": [ "aes" ] }
+				}
+			}
+		},
+		"type": "object",
+		"properties": {
+			"host": { "type": "string", "default": "localhost" },
+			"port": { "$ref": "#/$defs/port" },
+			"tls": { "$ref": "#/$defs/tls" },
+			"mode": { "enum": [ "dev", "prod" ] },
+			"upstreams": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": { "weight": { "default": 1 } }
+				}
+			}
+		},
+		"allOf": [
+			{ "properties": { "timeout": { "default": 30 } } }
+		],
+		"if": { "properties": { "mode": { "const": "prod" } }, "required": [ "mode" ] },
+		"then": { "properties": { "debug": { "default": false } } },
+		"else": { "properties": { "debug": { "default": true } } }
+	}`), rs); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		opts   DefaultsOptions
+		input  string
+		expect string
+	}{
+		{DefaultsOptions{}, `{}`, `{"debug":true,"host":"localhost","port":8080,"timeout":30}`},
+		{DefaultsOptions{}, `{"mode":"prod","host":"example.com"}`, `{"debug":false,"host":"example.com","mode":"prod","port":8080,"timeout":30}`},
+		{DefaultsOptions{}, `{"tls":{},"upstreams":[{},{"weight":5}]}`, `{"debug":true,"host":"localhost","port":8080,"timeout":30,"tls":{"ciphers":["aes"],"enabled":false},"upstreams":[{"weight":1},{"weight":5}]}`},
+		{DefaultsOptions{CreateIntermediate: true}, `{}`, `{"debug":true,"host":"localhost","port":8080,"timeout":30,"tls":{"ciphers":["aes"],"enabled":false}}`},
+		{DefaultsOptions{}, `"not an object"`, `"not an object"`},
+	}
+
+	for i, c := range cases {
+		var input interface{}
+		if err := json.Unmarshal([]byte(c.input), &input); err != nil {
+			t.Fatal(err)
+		}
+		inputCopy := deepCopy(input)
+
+		got, err := rs.ApplyDefaultsWithOptions(ctx, input, c.opts)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %s", i, err)
+			continue
+		}
+		gotBytes, err := json.Marshal(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(gotBytes) != c.expect {
+			t.Errorf("case %d: result mismatch.\nexpected: %s\ngot:      %s", i, c.expect, gotBytes)
+		}
+		if !reflect.DeepEqual(input, inputCopy) {
+			t.Errorf("case %d: input was modified", i)
+		}
+	}
+
+	// defaults are copied into each location they are applied to
+	got, err := rs.ApplyDefaults(ctx, map[string]interface{}{"tls": map[string]interface{}{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.(map[string]interface{})["tls"].(map[string]interface{})["ciphers"].([]interface{})[0] = "changed"
+	again, err := rs.ApplyDefaults(ctx, map[string]interface{}{"tls": map[string]interface{}{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cipher := again.(map[string]interface{})["tls"].(map[string]interface{})["ciphers"].([]interface{})[0]; cipher != "aes" {
+		t.Errorf("expected default values to be copied. got: %v", cipher)
+	}
+
+	recursive := &Schema{}
+	if err := json.Unmarshal([]byte(`{
+		"properties": {
+			"name": { "default": "node" },
+			"child": { "$ref": "#" }
+		}
+	}`), recursive); err != nil {
+		t.Fatal(err)
+	}
+	got, err = recursive.ApplyDefaultsWithOptions(ctx, map[string]interface{}{}, DefaultsOptions{CreateIntermediate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, map[string]interface{}{"name": "node"}) {
+		t.Errorf("unexpected result for a recursive schema: %v", got)
+	}
+
+	// draft 7 ignores the siblings of $ref
+	draft7 := &Schema{}
+	if err := json.Unmarshal([]byte(`{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"definitions": {
+			"port": { "default": 8080 },
+			"config": { "properties": { "host": { "default": "localhost" } } }
+		},
+		"$ref": "#/definitions/config",
+		"properties": {
+			"ignored": { "default": true },
+			"port": { "$ref": "#/definitions/port", "default": 1 }
+		}
+	}`), draft7); err != nil {
+		t.Fatal(err)
+	}
+	got, err = draft7.ApplyDefaults(ctx, map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, map[string]interface{}{"host": "localhost"}) {
+		t.Errorf("expected the siblings of $ref to be ignored. got: %v", got)
+	}
+
+	type tlsConfig struct {
+		Enabled *bool    `json:"enabled,omitempty"`
+		Ciphers []string `json:"ciphers,omitempty"`
+	}
+	type config struct {
+		Host string     `json:"host,omitempty"`
+		TLS  *tlsConfig `json:"tls,omitempty"`
+	}
+	got, err = rs.ApplyDefaults(ctx, config{Host: "example.com", TLS: &tlsConfig{Ciphers: []string{"chacha"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotBytes, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `{"debug":true,"host":"example.com","port":8080,"timeout":30,"tls":{"ciphers":["chacha"],"enabled":false}}`; string(gotBytes) != expect {
+		t.Errorf("expected defaults to be filled into a struct. expected: %s\ngot: %s", expect, gotBytes)
+	}
+	if _, err := rs.ApplyDefaults(ctx, map[string]interface{}{"ch": make(chan int)}); err == nil || !strings.Contains(err.Error(), "invalid instance") {
+		t.Errorf("expected an error for a value without JSON form. got: %v", err)
+	}
+}
+
+func TestContent(t *testing.T) {
+	ctx := WithContentMode(context.Background(), ContentAssert)
+	runJSONTestsContext(ctx, t, []string{
+		"testdata/draft7/optional/content.json",
+		"testdata/draft2019-09/optional/content.json",
+	})
+
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{
+		"properties": {
+			"payload": {
+				"contentEncoding": "base64",
+				"contentMediaType": "application/json",
+				"contentSchema": {
+					"required": ["id"],
+					"properties": {"id": {"type": "integer"}}
+				}
+			}
+		}
+	}`), rs); err != nil {
+		t.Fatal(err)
+	}
+
+	// {"id": "abc"}
+	doc := map[string]interface{}{"payload": "eyJpZCI6ICJhYmMifQ=="}
+	if state := rs.Validate(WithAnnotations(context.Background(), true), doc); !state.IsValid() {
+		t.Errorf("expected content keywords to only annotate by default. got: %v", *state.Errs)
+	} else if got := len(state.AnnotationsAt("/payload")); got != 3 {
+		t.Errorf("expected 3 content annotations. got: %d", got)
+	}
+
+	state := rs.Validate(ctx, doc)
+	if len(*state.Errs) != 1 {
+		t.Fatalf("expected 1 error. got: %v", *state.Errs)
+	}
+	err := (*state.Errs)[0]
+	if err.PropertyPath != "/payload/id" {
+		t.Errorf("expected error at /payload/id. got: %s", err.PropertyPath)
+	}
+	if err.KeywordLocation != "/properties/payload/contentSchema/properties/id/type" {
+		t.Errorf("unexpected keyword location: %s", err.KeywordLocation)
+	}
+
+	cases := []struct {
+		encoding, data string
+		valid          bool
+	}{
+		{"base32", "MZXW6===", true},
+		{"base32", "MZXW6", false},
+		{"quoted-printable", "caf=C3=A9", true},
+		{"quoted-printable", "caf=C", false},
+		{"base16", "666F6F", true},
+		{"base16", "666F6", false},
+		{"rot13", "sbb", false},
+	}
+	for _, c := range cases {
+		rs := &Schema{}
+		if err := json.Unmarshal([]byte(fmt.Sprintf(`{"contentEncoding": %q}`, c.encoding)), rs); err != nil {
+			t.Fatal(err)
+		}
+		if state := rs.Validate(ctx, c.data); state.IsValid() != c.valid {
+			t.Errorf("%s %q: expected valid to be %t. got: %v", c.encoding, c.data, c.valid, *state.Errs)
+		}
+	}
+}
@@ -0,0 +1,495 @@
+package jsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	jptr "github.com/qri-io/jsonpointer"
+)
+
+// ValidateReader validates the JSON document read from r without decoding
+// it into memory first. Objects and arrays are validated as their tokens
+// are read, as long as their schemas only use keywords which can be
+// evaluated that way, like properties, items, required or type. $ref and
+// allOf are followed while streaming if the schemas they apply qualify as
+// well. Subtrees that need keywords such as uniqueItems, enum, const,
+// anyOf, $dynamicRef or unevaluatedProperties, or references that don't
+// resolve, are decoded to be validated, so an array of objects is held in
+// memory one element at a time at most. Errors of objects and arrays
+// validated while streaming carry a placeholder rather than the invalid
+// value. Annotations are not collected
+func (s *Schema) ValidateReader(ctx context.Context, r io.Reader) ([]KeyError, error) {
+	return validateReader(ctx, newContextValidationState(ctx, s), s, r)
+}
+
+// ValidateReader validates the JSON document read from r with the compiled
+// schema, streaming it as described for Schema.ValidateReader
+func (cs *CompiledSchema) ValidateReader(ctx context.Context, r io.Reader) ([]KeyError, error) {
+	return validateReader(ctx, cs.validationState(ctx), cs.schema, r)
+}
+
+// validateReader streams the document read from r through the schema
+func validateReader(ctx context.Context, currentState *ValidationState, s *Schema, r io.Reader) ([]KeyError, error) {
+	currentState.Annotations = nil
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	sv := &streamValidator{ctx: ctx, dec: dec}
+	if err := sv.value([]streamApplication{{schema: s, state: currentState}}); err != nil {
+		return nil, fmt.Errorf("error parsing JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("unexpected data after the document")
+		}
+		return nil, fmt.Errorf("error parsing JSON: %w", err)
+	}
+	return *currentState.Errs, nil
+}
+
+// streamApplication is a schema applied to the value being read, along with
+// the state it is validated in
+type streamApplication struct {
+	schema *Schema
+	state  *ValidationState
+}
+
+// streamValidator validates values as they are read from a token stream
+type streamValidator struct {
+	ctx context.Context
+	dec *json.Decoder
+}
+
+// keyword categories of the stream validator
+const (
+	// streamUnsupported keywords need the decoded value
+	streamUnsupported = iota
+	// streamBefore keywords have no effect on objects and arrays beyond
+	// their side effects on the state, and are evaluated upfront
+	streamBefore
+	// streamApplicator keywords apply schemas to the children of objects
+	// and arrays, and are evaluated as children are read
+	streamApplicator
+	// streamAfter keywords check properties of objects and arrays once
+	// all of their children have been read
+	streamAfter
+	// streamInPlace keywords apply schemas to the value itself, which are
+	// validated as applications of their own
+	streamInPlace
+)
+
+// streamCategory returns how the stream validator evaluates a keyword
+func streamCategory(keyword Keyword) int {
+	switch keyword.(type) {
+	case *Ref, *AllOf:
+		return streamInPlace
+	case *Properties, *PatternProperties, *AdditionalProperties, *Items, *PrefixItems, *AdditionalItems, *Contains:
+		return streamApplicator
+	case *Type, *Required, *MinProperties, *MaxProperties, *PropertyNames, *DependentRequired,
+		*MinItems, *MaxItems, *MinContains, *MaxContains:
+		return streamAfter
+	case *SchemaURI, *ID, *Vocabularies, *Description, *Title, *Comment, *Default, *Examples,
+		*ReadOnly, *WriteOnly, *Anchor, *DynamicAnchor, *RecursiveAnchor, *Defs, *Definitions, *Void,
+		*MultipleOf, *Maximum, *ExclusiveMaximum, *Minimum, *ExclusiveMinimum,
+		*Draft4ExclusiveMaximum, *Draft4ExclusiveMinimum, *MaxLength, *MinLength, *Pattern,
+		*ContentEncoding, *ContentMediaType, *ContentSchema:
+		return streamBefore
+	}
+	return streamUnsupported
+}
+
+// streamable reports whether an object or array can be validated against
+// the schema while it is read
+func streamable(s *Schema) bool {
+	if s == nil || s.schemaType == schemaTypeFalse {
+		return false
+	}
+	for _, keyword := range s.orderedkeywords {
+		if streamCategory(s.keywords[keyword]) == streamUnsupported {
+			return false
+		}
+	}
+	return true
+}
+
+// token reads the next token, treating the end of the input as unexpected
+func (sv *streamValidator) token() (json.Token, error) {
+	tok, err := sv.dec.Token()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return tok, err
+}
+
+// value reads the next value, validating it against all applications
+func (sv *streamValidator) value(apps []streamApplication) error {
+	tok, err := sv.token()
+	if err != nil {
+		return err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		for _, app := range apps {
+			app.schema.ValidateKeyword(sv.ctx, app.state, tok)
+		}
+		return nil
+	}
+
+	live := apps[:0:0]
+	for _, app := range apps {
+		if app.schema != nil && app.schema.schemaType == schemaTypeTrue {
+			continue
+		}
+		live = append(live, app)
+	}
+	if len(live) == 0 {
+		return sv.skip()
+	}
+
+	// placeholder stands in for the object or array in errors, objects
+	// keep track of their keys in it
+	var placeholder interface{} = []interface{}{}
+	if delim == '{' {
+		placeholder = map[string]interface{}{}
+	}
+	expanded := make([]streamApplication, 0, len(live))
+	var restore []func()
+	leave := func() {
+		for _, f := range restore {
+			f()
+		}
+		restore = nil
+	}
+	defer leave()
+	seen := map[*Schema]bool{}
+	for _, app := range live {
+		if !sv.expand(app, placeholder, seen, &expanded, &restore) {
+			leave()
+			data, err := sv.decode(delim)
+			if err != nil {
+				return err
+			}
+			for _, app := range live {
+				app.schema.ValidateKeyword(sv.ctx, app.state, data)
+			}
+			return nil
+		}
+	}
+
+	if delim == '{' {
+		return sv.object(expanded, placeholder.(map[string]interface{}))
+	}
+	return sv.array(expanded, placeholder.([]interface{}))
+}
+
+// expand enters an application, adding it and the applications of the
+// schemas it applies to the value through $ref and allOf to expanded,
+// and reports false if any of them can't be streamed. seen holds the
+// schemas being expanded, guarding against references looping back
+func (sv *streamValidator) expand(app streamApplication, placeholder interface{}, seen map[*Schema]bool, expanded *[]streamApplication, restore *[]func()) bool {
+	s, currentState := app.schema, app.state
+	if s != nil && s.schemaType == schemaTypeTrue {
+		return true
+	}
+	if !streamable(s) || seen[s] {
+		return false
+	}
+	seen[s] = true
+	defer delete(seen, s)
+	*expanded = append(*expanded, app)
+	*restore = append(*restore, sv.enter(app, placeholder))
+
+	for _, keyword := range s.orderedkeywords {
+		switch kw := s.keywords[keyword].(type) {
+		case *Ref:
+			res := kw.resolve(sv.ctx, currentState)
+			if res.resolved == nil {
+				return false
+			}
+			subState := inPlace(currentState, "$ref")
+			if res.resolvedRoot != nil {
+				subState.BaseURI = res.resolvedRoot.docPath
+				subState.Root = res.resolvedRoot
+			}
+			subState.BaseRelativeLocation = descendPointer(*res.baseRelativeLocation())
+			if !sv.expand(streamApplication{res.resolved, subState}, placeholder, seen, expanded, restore) {
+				return false
+			}
+		case *AllOf:
+			for i, sch := range *kw {
+				subState := inPlace(currentState, "allOf", strconv.Itoa(i))
+				if !sv.expand(streamApplication{sch, subState}, placeholder, seen, expanded, restore) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// decode reads the remainder of an object or array after its opening delimiter
+func (sv *streamValidator) decode(delim json.Delim) (interface{}, error) {
+	if delim == '{' {
+		obj := map[string]interface{}{}
+		for sv.dec.More() {
+			key, err := sv.token()
+			if err != nil {
+				return nil, err
+			}
+			var val interface{}
+			if err := sv.dec.Decode(&val); err != nil {
+				return nil, err
+			}
+			obj[key.(string)] = val
+		}
+		_, err := sv.token()
+		return obj, err
+	}
+
+	arr := []interface{}{}
+	for sv.dec.More() {
+		var val interface{}
+		if err := sv.dec.Decode(&val); err != nil {
+			return nil, err
+		}
+		arr = append(arr, val)
+	}
+	_, err := sv.token()
+	return arr, err
+}
+
+// skip reads the remainder of an object or array without validating it
+func (sv *streamValidator) skip() error {
+	for depth := 1; depth > 0; {
+		tok, err := sv.token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// enter prepares the state to validate an object or array against the
+// schema as Schema.ValidateKeyword does, evaluating the keywords of
+// the streamBefore category. The returned function restores the state
+func (sv *streamValidator) enter(app streamApplication, data interface{}) func() {
+	s, currentState := app.schema, app.state
+	s.enterScope(currentState)
+
+	dynamicScope := currentState.DynamicScope
+	if s.docPath != "" {
+		currentState.DynamicScope = append(dynamicScope[:len(dynamicScope):len(dynamicScope)], currentState.BaseURI)
+	}
+
+	for _, keyword := range s.orderedkeywords {
+		if streamCategory(s.keywords[keyword]) == streamBefore {
+			currentState.keyword = keyword
+			s.keywords[keyword].ValidateKeyword(sv.ctx, currentState, data)
+		}
+	}
+	currentState.keyword = ""
+
+	return func() {
+		currentState.DynamicScope = dynamicScope
+	}
+}
+
+// child creates the state validating a child of an object or array
+// against a schema found at the given location of the parent schema.
+// Unlike the states keywords descend into one after another, the states
+// of a child are alive at the same time, so they get their own pointers
+func child(currentState *ValidationState, instanceToken string, schemaLocation ...string) *ValidationState {
+	subState := inPlace(currentState, schemaLocation...)
+	subState.InstanceLocation = descendPointer(*currentState.InstanceLocation, instanceToken)
+	return subState
+}
+
+// inPlace creates the state validating the value itself against a schema
+// found at the given location of the current schema, with pointers of
+// its own like the states of children
+func inPlace(currentState *ValidationState, schemaLocation ...string) *ValidationState {
+	subState := currentState.NewSubState()
+	subState.ClearState()
+	if currentState.BaseRelativeLocation != nil {
+		subState.BaseRelativeLocation = descendPointer(*currentState.BaseRelativeLocation, schemaLocation...)
+	}
+	subState.RelativeLocation = descendPointer(*currentState.RelativeLocation, schemaLocation...)
+	return subState
+}
+
+// descendPointer creates a descendant of a pointer not sharing its storage
+func descendPointer(ptr jptr.Pointer, tokens ...string) *jptr.Pointer {
+	descendant := make(jptr.Pointer, 0, len(ptr)+len(tokens))
+	descendant = append(append(descendant, ptr...), tokens...)
+	return &descendant
+}
+
+// object validates the properties of an object as they are read. Only
+// the keys of the object are kept, serving as placeholder for the object
+func (sv *streamValidator) object(apps []streamApplication, keys map[string]interface{}) error {
+	additionalReported := make([]bool, len(apps))
+
+	for sv.dec.More() {
+		tok, err := sv.token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+		keys[key] = nil
+
+		var children []streamApplication
+		for i, app := range apps {
+			s, currentState := app.schema, app.state
+			matched := false
+			if props, ok := s.keywords["properties"].(*Properties); ok {
+				if sch, ok := (*props)[key]; ok {
+					matched = true
+					children = append(children, streamApplication{sch, child(currentState, key, "properties", key)})
+				}
+			}
+			if patterns, ok := s.keywords["patternProperties"].(*PatternProperties); ok {
+				for _, ptn := range *patterns {
+					ok, err := matchString(ptn.re, key)
+					if err != nil {
+						currentState.AddError(keys, fmt.Sprintf("regexp pattern %s on property %s: %s", ptn.re.String(), key, err.Error()))
+						continue
+					}
+					if ok {
+						matched = true
+						children = append(children, streamApplication{ptn.schema, child(currentState, key, "patternProperties", ptn.key)})
+					}
+				}
+			}
+			if additional, ok := s.keywords["additionalProperties"].(*AdditionalProperties); ok && !matched && !additionalReported[i] {
+				subState := child(currentState, key, "additionalProperties")
+				if additional.schemaType == schemaTypeFalse {
+					subState.AddError(keys, "additional properties are not allowed")
+					additionalReported[i] = true
+					continue
+				}
+				children = append(children, streamApplication{(*Schema)(additional), subState})
+			}
+		}
+
+		if err := sv.value(children); err != nil {
+			return err
+		}
+	}
+	if _, err := sv.token(); err != nil {
+		return err
+	}
+
+	for _, app := range apps {
+		sv.after(app, keys, len(keys), 0)
+	}
+	return nil
+}
+
+// array validates the elements of an array as they are read, keeping no
+// more than the element being read in memory
+func (sv *streamValidator) array(apps []streamApplication, placeholder []interface{}) error {
+	matches := make([]int, len(apps))
+	additionalReported := make([]bool, len(apps))
+
+	length := 0
+	for ; sv.dec.More(); length++ {
+		index := strconv.Itoa(length)
+		var children []streamApplication
+		containsStates := make([]*ValidationState, len(apps))
+		for i, app := range apps {
+			s, currentState := app.schema, app.state
+			prefixCount := 0
+			if prefix, ok := s.keywords["prefixItems"].(*PrefixItems); ok {
+				prefixCount = len(*prefix)
+				if length < prefixCount {
+					children = append(children, streamApplication{(*prefix)[length], child(currentState, index, "prefixItems", index)})
+				}
+			}
+			if items, ok := s.keywords["items"].(*Items); ok {
+				if items.single && length >= prefixCount {
+					children = append(children, streamApplication{items.Schemas[0], child(currentState, index, "items")})
+				} else if !items.single && length < len(items.Schemas) {
+					children = append(children, streamApplication{items.Schemas[length], child(currentState, index, "items", index)})
+				} else if additional, ok := s.keywords["additionalItems"].(*AdditionalItems); ok && !items.single && len(items.Schemas) > 0 && !additionalReported[i] {
+					if additional.schemaType == schemaTypeFalse {
+						currentState.keyword = "additionalItems"
+						currentState.AddError(placeholder, "additional items are not allowed")
+						currentState.keyword = ""
+						additionalReported[i] = true
+					} else {
+						children = append(children, streamApplication{(*Schema)(additional), child(currentState, index, "additionalItems")})
+					}
+				}
+			}
+			if contains, ok := s.keywords["contains"].(*Contains); ok {
+				subState := child(currentState, index, "contains")
+				subState.Errs = &[]KeyError{}
+				containsStates[i] = subState
+				children = append(children, streamApplication{(*Schema)(contains), subState})
+			}
+		}
+
+		if err := sv.value(children); err != nil {
+			return err
+		}
+		for i, subState := range containsStates {
+			if subState != nil && subState.IsValid() {
+				matches[i]++
+			}
+		}
+	}
+	if _, err := sv.token(); err != nil {
+		return err
+	}
+
+	for i, app := range apps {
+		sv.after(app, placeholder, length, matches[i])
+	}
+	return nil
+}
+
+// after evaluates the keywords of the streamAfter category once an object
+// or array of the given length has been read
+func (sv *streamValidator) after(app streamApplication, data interface{}, length, matches int) {
+	s, currentState := app.schema, app.state
+	_, isArray := data.([]interface{})
+	for _, keyword := range s.orderedkeywords {
+		currentState.keyword = keyword
+		switch kw := s.keywords[keyword].(type) {
+		case *MinItems:
+			if isArray {
+				kw.validateLength(currentState, data, length)
+			}
+		case *MaxItems:
+			if isArray {
+				kw.validateLength(currentState, data, length)
+			}
+		case *Contains:
+			if isArray {
+				kw.validateCount(currentState, data, matches)
+			}
+		case *MinContains:
+			if isArray {
+				kw.validateLength(currentState, data, length)
+			}
+		case *MaxContains:
+			if isArray {
+				kw.validateLength(currentState, data, length)
+			}
+		default:
+			if streamCategory(kw) == streamAfter {
+				kw.ValidateKeyword(sv.ctx, currentState, data)
+			}
+		}
+	}
+	currentState.keyword = ""
+}
@@ -0,0 +1,212 @@
+package jsonschema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestValidateReader(t *testing.T) {
+	t.Run("draft2019-09", func(t *testing.T) {
+		LoadDraft2019_09()
+		testValidateReader(t, "testdata/draft2019-09/*.json")
+	})
+	t.Run("draft2020-12", func(t *testing.T) {
+		LoadDraft2020_12()
+		defer LoadDraft2019_09()
+		testValidateReader(t, "testdata/draft2020-12/*.json")
+	})
+}
+
+// testValidateReader checks ValidateReader reports the errors ValidateBytes
+// does for the test suite files matching pattern
+func testValidateReader(t *testing.T, pattern string) {
+	ctx := context.Background()
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		if strings.HasSuffix(path, "refRemote.json") {
+			// requires a server for remote references
+			continue
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testSets := []*TestSet{}
+		if err := decodeJSON(data, &testSets); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, ts := range testSets {
+			for i, c := range ts.Tests {
+				doc, err := json.Marshal(c.Data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				expect, err := ts.Schema.ValidateBytes(ctx, doc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := ts.Schema.ValidateReader(ctx, bytes.NewReader(doc))
+				if err != nil {
+					t.Errorf("%s: %s test case %d: unexpected error: %s", filepath.Base(path), ts.Description, i, err)
+					continue
+				}
+				if e, g := errorLocations(expect), errorLocations(got); e != g {
+					t.Errorf("%s: %s test case %d: %s\nexpected errors: %s\ngot: %s", filepath.Base(path), ts.Description, i, c.Description, e, g)
+				}
+			}
+		}
+	}
+}
+
+// errorLocations lists the keyword locations and messages of errors in a
+// stable order
+func errorLocations(errs []KeyError) string {
+	locations := make([]string, len(errs))
+	for i, err := range errs {
+		locations[i] = err.KeywordLocation + ": " + err.Message
+	}
+	sort.Strings(locations)
+	return strings.Join(locations, ", ")
+}
+
+// arrayReader generates a JSON array of n objects without holding it in memory
+type arrayReader struct {
+	n, i int
+	buf  bytes.Buffer
+}
+
+func (r *arrayReader) Read(p []byte) (int, error) {
+	for r.buf.Len() < len(p) && r.i <= r.n {
+		switch {
+		case r.i == 0:
+			r.buf.WriteString("[")
+		case r.i == r.n:
+			r.buf.WriteString(`{"id": "last"}]`)
+		default:
+			fmt.Fprintf(&r.buf, `{"id": %d, "tags": ["a", "b"]},`, r.i)
+		}
+		r.i++
+	}
+	if r.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return r.buf.Read(p)
+}
+
+func TestValidateReaderStreaming(t *testing.T) {
+	ctx := context.Background()
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{
+		"type": "array",
+		"minItems": 2,
+		"items": {
+			"type": "object",
+			"required": ["id"],
+			"properties": {
+				"id": {"type": "integer"},
+				"tags": {"type": "array", "uniqueItems": true}
+			}
+		}
+	}`), rs); err != nil {
+		t.Fatal(err)
+	}
+
+	errs, err := rs.ValidateReader(ctx, &arrayReader{n: 100000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error. got: %v", errs)
+	}
+	if errs[0].PropertyPath != "/99999/id" || errs[0].KeywordLocation != "/items/properties/id/type" {
+		t.Errorf("unexpected error: %#v", errs[0])
+	}
+
+	cases := []string{
+		`[{"id": 1}`,
+		`[{"id": 1}] []`,
+		`{"id" 1}`,
+		``,
+	}
+	for _, c := range cases {
+		if _, err := rs.ValidateReader(ctx, strings.NewReader(c)); err == nil {
+			t.Errorf("%q: expected a parsing error", c)
+		}
+	}
+}
+
+// heapReader samples the live heap every 256KB read from r
+type heapReader struct {
+	r          io.Reader
+	read, peak uint64
+}
+
+func (r *heapReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if r.read/(256<<10) != (r.read+uint64(n))/(256<<10) {
+		var stats runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+		if stats.HeapAlloc > r.peak {
+			r.peak = stats.HeapAlloc
+		}
+	}
+	r.read += uint64(n)
+	return n, err
+}
+
+func TestValidateReaderStreamingRefs(t *testing.T) {
+	ctx := context.Background()
+	items := `{
+		"type": "object",
+		"required": ["id"],
+		"properties": {
+			"id": {"type": "integer"},
+			"tags": {"type": "array", "uniqueItems": true}
+		}
+	}`
+	cases := []struct {
+		schema   string
+		path     string
+		location string
+		streamed bool
+	}{
+		{`{"$ref": "#/$defs/root", "$defs": {"root": {"type": "array", "items": ` + items + `}}}`, "/49999/id", "/$ref/items/properties/id/type", true},
+		{`{"type": "array", "items": {"$ref": "#/$defs/item"}, "$defs": {"item": ` + items + `}}`, "/49999/id", "/items/$ref/properties/id/type", true},
+		{`{"allOf": [{"type": "array"}, {"items": ` + items + `}]}`, "/49999/id", "/allOf/1/items/properties/id/type", true},
+		// anyOf needs the decoded array
+		{`{"anyOf": [{"type": "array", "items": ` + items + `}]}`, "/", "/anyOf", false},
+	}
+
+	for i, c := range cases {
+		rs := &Schema{}
+		if err := json.Unmarshal([]byte(c.schema), rs); err != nil {
+			t.Fatal(err)
+		}
+		r := &heapReader{r: &arrayReader{n: 50000}}
+		errs, err := rs.ValidateReader(ctx, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(errs) != 1 || errs[0].PropertyPath != c.path || errs[0].KeywordLocation != c.location {
+			t.Errorf("case %d: expected an error at %s. got: %v", i, c.location, errs)
+		}
+		// decoding the array takes about 20MB
+		if streamed := r.peak < 4<<20; streamed != c.streamed {
+			t.Errorf("case %d: expected streaming to be %t. heap peaked at %d bytes", i, c.streamed, r.peak)
+		}
+	}
+}
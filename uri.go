@@ -0,0 +1,223 @@
+package jsonschema
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"unicode/utf8"
+)
+
+// checkURIReference validates s against the URI-reference production of
+// RFC 3986, section 4.1, or the IRI-reference production of RFC 3987,
+// section 2.2 when iri is set. absolute requires a scheme
+// https://tools.ietf.org/html/rfc3986#section-4.1
+// https://tools.ietf.org/html/rfc3987#section-2.2
+func checkURIReference(s string, iri, absolute bool) error {
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		if err := checkURIChars(s[i+1:], "/?", iri, false); err != nil {
+			return fmt.Errorf("invalid fragment: %s", err.Error())
+		}
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '?'); i >= 0 {
+		if err := checkURIChars(s[i+1:], "/?", iri, true); err != nil {
+			return fmt.Errorf("invalid query: %s", err.Error())
+		}
+		s = s[:i]
+	}
+
+	// a colon ahead of the first slash ends the scheme, relative
+	// references must not contain one in their first path segment
+	if i := strings.IndexAny(s, ":/"); i >= 0 && s[i] == ':' {
+		if err := checkScheme(s[:i]); err != nil {
+			return err
+		}
+		s = s[i+1:]
+	} else if absolute {
+		return fmt.Errorf("missing scheme")
+	}
+
+	if strings.HasPrefix(s, "//") {
+		s = s[2:]
+		authority := s
+		if i := strings.IndexByte(s, '/'); i >= 0 {
+			authority, s = s[:i], s[i:]
+		} else {
+			s = ""
+		}
+		if err := checkAuthority(authority, iri); err != nil {
+			return err
+		}
+	}
+
+	if err := checkURIChars(s, "/", iri, false); err != nil {
+		return fmt.Errorf("invalid path: %s", err.Error())
+	}
+	return nil
+}
+
+// checkScheme validates a scheme as defined by RFC 3986, section 3.1
+func checkScheme(scheme string) error {
+	if scheme == "" || !isAlpha(scheme[0]) {
+		return fmt.Errorf("scheme must begin with a letter")
+	}
+	for i := 1; i < len(scheme); i++ {
+		c := scheme[i]
+		if !isAlpha(c) && !isDigit(c) && c != '+' && c != '-' && c != '.' {
+			return fmt.Errorf("invalid character %q in scheme", c)
+		}
+	}
+	return nil
+}
+
+// checkAuthority validates an authority as defined by RFC 3986, section 3.2
+func checkAuthority(authority string, iri bool) error {
+	if i := strings.LastIndexByte(authority, '@'); i >= 0 {
+		if err := checkURIChars(authority[:i], ":", iri, false); err != nil {
+			return fmt.Errorf("invalid userinfo: %s", err.Error())
+		}
+		authority = authority[i+1:]
+	}
+
+	host, port := authority, ""
+	if strings.HasPrefix(authority, "[") {
+		end := strings.IndexByte(authority, ']')
+		if end < 0 {
+			return fmt.Errorf("unterminated IP literal")
+		}
+		if err := checkIPLiteral(authority[1:end]); err != nil {
+			return err
+		}
+		host, port = "", authority[end+1:]
+		if port != "" {
+			if port[0] != ':' {
+				return fmt.Errorf("unexpected %q after IP literal", port)
+			}
+			port = port[1:]
+		}
+	} else if i := strings.IndexByte(authority, ':'); i >= 0 {
+		host, port = authority[:i], authority[i+1:]
+	}
+
+	for i := 0; i < len(port); i++ {
+		if !isDigit(port[i]) {
+			return fmt.Errorf("invalid port %q", port)
+		}
+	}
+	if err := checkURIChars(host, "", iri, false); err != nil {
+		return fmt.Errorf("invalid host: %s", err.Error())
+	}
+	if iri && !isASCII(host) && !strings.Contains(host, "%") {
+		// internationalized host names map to URIs using their ASCII form
+		for _, label := range splitIDNLabels(host) {
+			if label == "" || isASCII(label) {
+				continue
+			}
+			encoded, err := punycodeEncode(label)
+			if err != nil {
+				return fmt.Errorf("invalid host: %s", err.Error())
+			}
+			if len(acePrefix+encoded) > 63 {
+				return fmt.Errorf("invalid host: label %q exceeds 63 characters", label)
+			}
+		}
+	}
+	return nil
+}
+
+// checkIPLiteral validates the address of an IP-literal, either an IPv6
+// address or an IPvFuture as defined by RFC 3986, section 3.2.2
+func checkIPLiteral(literal string) error {
+	if strings.HasPrefix(literal, "v") || strings.HasPrefix(literal, "V") {
+		dot := strings.IndexByte(literal, '.')
+		if dot < 2 || dot == len(literal)-1 {
+			return fmt.Errorf("invalid IPvFuture %q", literal)
+		}
+		for i := 1; i < dot; i++ {
+			if !isHexDigit(literal[i]) {
+				return fmt.Errorf("invalid IPvFuture %q", literal)
+			}
+		}
+		for i := dot + 1; i < len(literal); i++ {
+			c := literal[i]
+			if !isUnreserved(c) && !isSubDelim(c) && c != ':' {
+				return fmt.Errorf("invalid IPvFuture %q", literal)
+			}
+		}
+		return nil
+	}
+	if !strings.Contains(literal, ":") || net.ParseIP(literal) == nil {
+		return fmt.Errorf("invalid IPv6 address %q", literal)
+	}
+	return nil
+}
+
+// checkURIChars validates that s only holds unreserved characters,
+// percent-encodings, sub-delims and the given extra characters. IRIs may
+// also hold the ucschar code points of RFC 3987, and private use code
+// points where private is set
+func checkURIChars(s, extra string, iri, private bool) error {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '%':
+			if i+2 >= len(s) || !isHexDigit(s[i+1]) || !isHexDigit(s[i+2]) {
+				return fmt.Errorf("invalid percent-encoding")
+			}
+			i += 3
+			continue
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if !iri || !(isUCSChar(r) || private && isIPrivate(r)) {
+				return fmt.Errorf("invalid character %q", r)
+			}
+			i += size
+			continue
+		case isUnreserved(c), isSubDelim(c), strings.IndexByte(extra, c) >= 0:
+		case (c == ':' || c == '@') && extra != "":
+			// pchar also allows ':' and '@' in paths, queries and fragments
+		default:
+			return fmt.Errorf("invalid character %q", c)
+		}
+		i++
+	}
+	return nil
+}
+
+// isUCSChar reports whether r is a ucschar as defined by RFC 3987, section 2.2
+func isUCSChar(r rune) bool {
+	switch {
+	case r >= 0xA0 && r <= 0xD7FF, r >= 0xF900 && r <= 0xFDCF, r >= 0xFDF0 && r <= 0xFFEF:
+		return true
+	case r >= 0x10000 && r <= 0xEFFFD:
+		// all planes except for their last two code points
+		return r&0xFFFF <= 0xFFFD
+	}
+	return false
+}
+
+// isIPrivate reports whether r is an iprivate code point as defined by
+// RFC 3987, section 2.2
+func isIPrivate(r rune) bool {
+	return r >= 0xE000 && r <= 0xF8FF || r >= 0xF0000 && r <= 0xFFFFD || r >= 0x100000 && r <= 0x10FFFD
+}
+
+func isAlpha(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func isUnreserved(c byte) bool {
+	return isAlpha(c) || isDigit(c) || c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isSubDelim(c byte) bool {
+	return strings.IndexByte("!$&'()*+,;=", c) >= 0
+}
@@ -1,7 +1,10 @@
 package jsonschema
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"strings"
@@ -47,3 +50,17 @@ func IsLocalSchemaID(id string) bool {
 	}
 	return id != "#" && !strings.HasPrefix(id, "#/") && strings.Contains(id, "#")
 }
+
+// decodeJSON decodes a single JSON document, keeping numbers as json.Number
+// so no precision is lost before validation
+func decodeJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("invalid character after top-level value")
+	}
+	return nil
+}
@@ -1,17 +1,24 @@
 package jsonschema
 
 import (
+	"context"
+	"strings"
+
 	jptr "github.com/qri-io/jsonpointer"
 )
 
 // ValidationState holds the schema validation state
 // The aim is to have one global validation state
-// and use local sub states when evaluating parallel branches
-// TODO(arqu): make sure this is safe for concurrent use
+// and use local sub states when evaluating parallel branches.
+// A ValidationState belongs to a single validation and isn't safe for
+// concurrent use. Validating with a CompiledSchema is safe for concurrent
+// use, Schema.Validate is not as it caches resolved references into the
+// schema
 type ValidationState struct {
 	Local                *Schema
 	Root                 *Schema
 	RecursiveAnchor      *Schema
+	DynamicScope         []string
 	BaseURI              string
 	InstanceLocation     *jptr.Pointer
 	RelativeLocation     *jptr.Pointer
@@ -27,6 +34,34 @@ type ValidationState struct {
 	Misc                        map[string]interface{}
 
 	Errs *[]KeyError
+	// Annotations collects annotations when enabled, see WithAnnotations,
+	// and is nil otherwise
+	Annotations *[]Annotation
+
+	// keyword is the name of the keyword currently validated
+	// against the state, used to locate errors
+	keyword string
+	// evaluation records the evaluation tree when validating
+	// with a hierarchical output format
+	evaluation *evaluation
+	// known holds the schemas collected when compiling, used in place
+	// of the global registry to look up schemas by URI
+	known map[string]*Schema
+	// recursion holds the recursive references currently evaluated
+	// per instance location to detect endless recursion
+	recursion map[recursionKey]bool
+	// formats holds the globally registered format checkers, taken
+	// once per validation to spare locking the global registry
+	formats map[string]FormatChecker
+	// evaluatedIndexes holds the indexes of array items evaluated at
+	// the instance location, shared like EvaluatedPropertyNames
+	evaluatedIndexes *map[int]bool
+}
+
+// recursionKey identifies a keyword evaluated at an instance location
+type recursionKey struct {
+	keyword  Keyword
+	location string
 }
 
 // NewValidationState creates a new ValidationState with the provided location pointers and data instance
@@ -46,7 +81,46 @@ func NewValidationState(s *Schema) *ValidationState {
 		LocalEvaluatedPropertyNames: &map[string]bool{},
 		Misc:                        map[string]interface{}{},
 		Errs:                        &[]KeyError{},
+		recursion:                   map[recursionKey]bool{},
+		evaluatedIndexes:            &map[int]bool{},
+	}
+}
+
+// annotationsCtxKey is the context key enabling annotation collection
+type annotationsCtxKey struct{}
+
+// WithAnnotations returns a context collecting annotations into the
+// ValidationState returned by Validate when collect is true. Annotations
+// aren't collected by default, sparing their cost
+func WithAnnotations(ctx context.Context, collect bool) context.Context {
+	return context.WithValue(ctx, annotationsCtxKey{}, collect)
+}
+
+// AnnotationsFromContext reports whether annotations are collected as set
+// by WithAnnotations
+func AnnotationsFromContext(ctx context.Context) bool {
+	collect, _ := ctx.Value(annotationsCtxKey{}).(bool)
+	return collect
+}
+
+// newContextValidationState creates a new ValidationState registering and
+// resolving schemas with the registries of the context, checking formats
+// with the checkers currently registered globally
+func newContextValidationState(ctx context.Context, s *Schema) *ValidationState {
+	return newFormatsValidationState(ctx, s, globalFormats())
+}
+
+// newFormatsValidationState creates a new ValidationState like
+// newContextValidationState, checking formats with the given global checkers
+func newFormatsValidationState(ctx context.Context, s *Schema, formats map[string]FormatChecker) *ValidationState {
+	vs := NewValidationState(s)
+	vs.LocalRegistry.parent = SchemaRegistryFromContext(ctx)
+	vs.LocalKeywordRegistry = KeywordRegistryFromContext(ctx)
+	vs.formats = formats
+	if AnnotationsFromContext(ctx) {
+		vs.Annotations = &[]Annotation{}
 	}
+	return vs
 }
 
 // NewSubState creates a new ValidationState from an existing ValidationState
@@ -55,17 +129,25 @@ func (vs *ValidationState) NewSubState() *ValidationState {
 		Local:                       vs.Local,
 		Root:                        vs.Root,
 		RecursiveAnchor:             vs.RecursiveAnchor,
+		DynamicScope:                vs.DynamicScope,
 		LastEvaluatedIndex:          vs.LastEvaluatedIndex,
 		LocalLastEvaluatedIndex:     vs.LocalLastEvaluatedIndex,
 		BaseURI:                     vs.BaseURI,
 		InstanceLocation:            vs.InstanceLocation,
 		RelativeLocation:            vs.RelativeLocation,
-		BaseRelativeLocation:        vs.RelativeLocation,
+		BaseRelativeLocation:        vs.BaseRelativeLocation,
 		LocalRegistry:               vs.LocalRegistry,
+		LocalKeywordRegistry:        vs.LocalKeywordRegistry,
 		EvaluatedPropertyNames:      vs.EvaluatedPropertyNames,
 		LocalEvaluatedPropertyNames: vs.LocalEvaluatedPropertyNames,
 		Misc:                        map[string]interface{}{},
 		Errs:                        vs.Errs,
+		Annotations:                 vs.Annotations,
+		evaluation:                  vs.evaluation,
+		known:                       vs.known,
+		recursion:                   vs.recursion,
+		formats:                     vs.formats,
+		evaluatedIndexes:            vs.evaluatedIndexes,
 	}
 }
 
@@ -73,6 +155,9 @@ func (vs *ValidationState) NewSubState() *ValidationState {
 func (vs *ValidationState) ClearState() {
 	vs.EvaluatedPropertyNames = &map[string]bool{}
 	vs.LocalEvaluatedPropertyNames = &map[string]bool{}
+	vs.evaluatedIndexes = &map[int]bool{}
+	vs.LastEvaluatedIndex = -1
+	vs.LocalLastEvaluatedIndex = -1
 	if len(vs.Misc) > 0 {
 		vs.Misc = map[string]interface{}{}
 	}
@@ -96,23 +181,46 @@ func (vs *ValidationState) IsLocallyEvaluatedKey(key string) bool {
 	return ok
 }
 
-// SetEvaluatedIndex sets the evaluation index for the current state
+// SetEvaluatedIndex marks the array item at index i as evaluated
 func (vs *ValidationState) SetEvaluatedIndex(i int) {
-	vs.LastEvaluatedIndex = i
-	vs.LocalLastEvaluatedIndex = i
+	if vs.evaluatedIndexes == nil {
+		vs.evaluatedIndexes = &map[int]bool{}
+	}
+	(*vs.evaluatedIndexes)[i] = true
+	if i > vs.LastEvaluatedIndex {
+		vs.LastEvaluatedIndex = i
+	}
+	if i > vs.LocalLastEvaluatedIndex {
+		vs.LocalLastEvaluatedIndex = i
+	}
 }
 
-// UpdateEvaluatedPropsAndItems is a utility function to join evaluated properties and set the
-// current evaluation position index
+// IsEvaluatedIndex checks if the array item at index i is evaluated
+// against the state context
+func (vs *ValidationState) IsEvaluatedIndex(i int) bool {
+	if vs.evaluatedIndexes == nil {
+		return false
+	}
+	return (*vs.evaluatedIndexes)[i]
+}
+
+// UpdateEvaluatedPropsAndItems joins the properties and items evaluated by
+// a sub state applying a subschema in place, which callers only do for
+// valid subschemas. Keywords of the subschema don't count as siblings of
+// the keywords of the current schema
 func (vs *ValidationState) UpdateEvaluatedPropsAndItems(subState *ValidationState) {
 	joinSets(vs.EvaluatedPropertyNames, *subState.EvaluatedPropertyNames)
-	joinSets(vs.LocalEvaluatedPropertyNames, *subState.LocalEvaluatedPropertyNames)
+	if subState.evaluatedIndexes != nil && subState.evaluatedIndexes != vs.evaluatedIndexes {
+		if vs.evaluatedIndexes == nil {
+			vs.evaluatedIndexes = &map[int]bool{}
+		}
+		for i := range *subState.evaluatedIndexes {
+			(*vs.evaluatedIndexes)[i] = true
+		}
+	}
 	if subState.LastEvaluatedIndex > vs.LastEvaluatedIndex {
 		vs.LastEvaluatedIndex = subState.LastEvaluatedIndex
 	}
-	if subState.LocalLastEvaluatedIndex > vs.LastEvaluatedIndex {
-		vs.LastEvaluatedIndex = subState.LocalLastEvaluatedIndex
-	}
 }
 
 func copySet(input map[string]bool) map[string]bool {
@@ -129,6 +237,48 @@ func joinSets(consumer *map[string]bool, supplier map[string]bool) {
 	}
 }
 
+// registry returns the registry schemas are resolved with by URI
+func (vs *ValidationState) registry() *SchemaRegistry {
+	return vs.LocalRegistry.root()
+}
+
+// rootURI returns the URI of the document being evaluated
+func (vs *ValidationState) rootURI() string {
+	if vs.Root != nil && vs.Root.docPath != "" {
+		return vs.Root.docPath
+	}
+	return vs.BaseURI
+}
+
+// knownSchema looks up a schema by URI among the schemas of a compiled
+// schema, falling back to the global registry for uncompiled schemas
+func (vs *ValidationState) knownSchema(uri string) *Schema {
+	if vs.known != nil {
+		return vs.known[strings.TrimRight(uri, "#")]
+	}
+	return vs.registry().GetKnown(uri)
+}
+
+// enterRecursion marks the keyword as being evaluated at the current
+// instance location, reporting false if it already is
+func (vs *ValidationState) enterRecursion(keyword Keyword) bool {
+	if vs.recursion == nil {
+		vs.recursion = map[recursionKey]bool{}
+	}
+	key := recursionKey{keyword: keyword, location: vs.InstanceLocation.String()}
+	if vs.recursion[key] {
+		return false
+	}
+	vs.recursion[key] = true
+	return true
+}
+
+// exitRecursion marks the end of the evaluation of the keyword
+// at the current instance location
+func (vs *ValidationState) exitRecursion(keyword Keyword) {
+	delete(vs.recursion, recursionKey{keyword: keyword, location: vs.InstanceLocation.String()})
+}
+
 // AddError creates and appends a KeyError to errs of the current state
 func (vs *ValidationState) AddError(data interface{}, msg string) {
 	schemaDebug("[AddError] Error: %s", msg)
@@ -136,11 +286,86 @@ func (vs *ValidationState) AddError(data interface{}, msg string) {
 	if len(instancePath) == 0 {
 		instancePath = "/"
 	}
-	*vs.Errs = append(*vs.Errs, KeyError{
+	keyError := KeyError{
 		PropertyPath: instancePath,
 		InvalidValue: data,
 		Message:      msg,
+	}
+	if vs.keyword != "" {
+		keyError.KeywordLocation = vs.keywordLocation(vs.keyword)
+		keyError.AbsoluteKeywordLocation = vs.absoluteKeywordLocation(vs.keyword)
+	} else {
+		keyError.KeywordLocation = vs.keywordLocation()
+		keyError.AbsoluteKeywordLocation = vs.absoluteKeywordLocation()
+	}
+	if vs.evaluation != nil {
+		vs.evaluation.errors = append(vs.evaluation.errors, msg)
+	}
+	*vs.Errs = append(*vs.Errs, keyError)
+}
+
+// Annotation is a value attached to a location of the instance by a keyword
+// of a schema the location validated against
+type Annotation struct {
+	// InstanceLocation is a JSON pointer to the annotated part of the instance
+	InstanceLocation string `json:"instanceLocation"`
+	// KeywordLocation is a JSON pointer to the annotating keyword
+	// following the evaluation path
+	KeywordLocation string `json:"keywordLocation"`
+	// AbsoluteKeywordLocation is the absolute URI of the annotating
+	// keyword within its schema resource
+	AbsoluteKeywordLocation string `json:"absoluteKeywordLocation,omitempty"`
+	// Keyword is the name of the annotating keyword
+	Keyword string `json:"keyword"`
+	// Value is the annotation value produced by the keyword
+	Value interface{} `json:"value"`
+}
+
+// AddAnnotation attaches a value produced by the current keyword to the
+// current instance location. Annotations of schemas failing validation
+// are discarded once the schema has been evaluated. Nothing is collected
+// unless annotations are enabled
+func (vs *ValidationState) AddAnnotation(value interface{}) {
+	if vs.Annotations == nil {
+		return
+	}
+	schemaDebug("[AddAnnotation] %s: %v", vs.keyword, value)
+	*vs.Annotations = append(*vs.Annotations, Annotation{
+		InstanceLocation:        vs.InstanceLocation.String(),
+		KeywordLocation:         vs.keywordLocation(vs.keyword),
+		AbsoluteKeywordLocation: vs.absoluteKeywordLocation(vs.keyword),
+		Keyword:                 vs.keyword,
+		Value:                   value,
 	})
+	if vs.evaluation != nil {
+		vs.evaluation.annotation = value
+	}
+}
+
+// AnnotationsAt returns the annotations collected for the given
+// instance location, with "" addressing the instance root
+func (vs *ValidationState) AnnotationsAt(instanceLocation string) []Annotation {
+	if vs.Annotations == nil {
+		return nil
+	}
+	var annotations []Annotation
+	for _, a := range *vs.Annotations {
+		if a.InstanceLocation == instanceLocation {
+			annotations = append(annotations, a)
+		}
+	}
+	return annotations
+}
+
+// discardAnnotations drops annotations collected after the given
+// count if validation errors occured after the given error count
+func (vs *ValidationState) discardAnnotations(annotationCount, errCount int) {
+	if vs.Annotations == nil || vs.Errs == nil {
+		return
+	}
+	if len(*vs.Errs) > errCount && len(*vs.Annotations) > annotationCount {
+		*vs.Annotations = (*vs.Annotations)[:annotationCount]
+	}
 }
 
 // AddSubErrors appends a list of KeyError to the current state
@@ -179,7 +404,7 @@ func (vs *ValidationState) DescendRelative(token ...string) {
 
 // DescendRelativeFromState descends the relative pointer relative to the provided state
 func (vs *ValidationState) DescendRelativeFromState(base *ValidationState, token ...string) {
-	newPtr := base.InstanceLocation.RawDescendant(token...)
+	newPtr := base.RelativeLocation.RawDescendant(token...)
 	vs.RelativeLocation = &newPtr
 }
 
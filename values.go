@@ -0,0 +1,476 @@
+package jsonschema
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxValueDepth bounds the nesting of Go values converted for validation,
+// catching values that reference themselves
+const maxValueDepth = 1000
+
+var (
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	numberType        = reflect.TypeOf(json.Number(""))
+	bigIntType        = reflect.TypeOf(big.Int{})
+	bigFloatType      = reflect.TypeOf(big.Float{})
+	bigRatType        = reflect.TypeOf(big.Rat{})
+)
+
+// jsonValue converts a Go value into the values produced by decoding its
+// JSON encoding, following the rules of encoding/json: structs become
+// maps keyed by their json tags, typed maps and slices become
+// map[string]interface{} and []interface{}, and json.Marshaler and
+// encoding.TextMarshaler implementations are used when present. Numbers
+// keep their Go types. Values that already are decoded JSON are returned
+// as they are without being copied
+func jsonValue(data interface{}) (interface{}, error) {
+	if data == nil {
+		return nil, nil
+	}
+	val, _, err := convertValue(reflect.ValueOf(data), 0)
+	return val, err
+}
+
+// convertValue converts v, reporting whether the result differs from
+// the value v holds
+func convertValue(v reflect.Value, depth int) (interface{}, bool, error) {
+	if depth > maxValueDepth {
+		return nil, false, fmt.Errorf("value of type %s is nested too deeply or contains a cycle", v.Type())
+	}
+
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil, false, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, true, nil
+		}
+		return convertValue(v.Elem(), depth)
+	}
+
+	t := v.Type()
+	switch t {
+	case numberType:
+		return v.Interface(), false, nil
+	case reflect.PtrTo(bigIntType), reflect.PtrTo(bigFloatType), reflect.PtrTo(bigRatType):
+		if v.IsNil() {
+			return nil, true, nil
+		}
+		return v.Interface(), false, nil
+	case bigIntType, bigFloatType, bigRatType:
+		if v.CanAddr() {
+			return v.Addr().Interface(), true, nil
+		}
+	}
+
+	if t.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(t).Implements(marshalerType) {
+		return convertMarshaler(v.Addr())
+	}
+	if t.Implements(marshalerType) {
+		return convertMarshaler(v)
+	}
+	if t.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(t).Implements(textMarshalerType) {
+		return convertTextMarshaler(v.Addr())
+	}
+	if t.Implements(textMarshalerType) {
+		return convertTextMarshaler(v)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if t.PkgPath() == "" {
+			return v.Interface(), false, nil
+		}
+		return v.Bool(), true, nil
+	case reflect.String:
+		if t.PkgPath() == "" {
+			return v.Interface(), false, nil
+		}
+		return v.String(), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if t.PkgPath() == "" {
+			return v.Interface(), false, nil
+		}
+		return v.Int(), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if t.PkgPath() == "" {
+			return v.Interface(), false, nil
+		}
+		return v.Uint(), true, nil
+	case reflect.Float32, reflect.Float64:
+		if t.PkgPath() == "" {
+			return v.Interface(), false, nil
+		}
+		return v.Float(), true, nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, true, nil
+		}
+		val, _, err := convertValue(v.Elem(), depth+1)
+		return val, true, err
+	case reflect.Map:
+		return convertMap(v, depth)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, true, nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 && !reflect.PtrTo(t.Elem()).Implements(marshalerType) && !reflect.PtrTo(t.Elem()).Implements(textMarshalerType) {
+			return base64.StdEncoding.EncodeToString(v.Bytes()), true, nil
+		}
+		return convertSlice(v, depth)
+	case reflect.Array:
+		return convertSlice(v, depth)
+	case reflect.Struct:
+		return convertStruct(v, depth)
+	}
+	return nil, false, fmt.Errorf("unsupported type: %s", t)
+}
+
+// convertMarshaler decodes the JSON produced by a json.Marshaler
+func convertMarshaler(v reflect.Value) (interface{}, bool, error) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, true, nil
+	}
+	data, err := v.Interface().(json.Marshaler).MarshalJSON()
+	if err != nil {
+		return nil, false, fmt.Errorf("error calling MarshalJSON for type %s: %w", v.Type(), err)
+	}
+	var val interface{}
+	if err := decodeJSON(data, &val); err != nil {
+		return nil, false, fmt.Errorf("error calling MarshalJSON for type %s: %w", v.Type(), err)
+	}
+	return val, true, nil
+}
+
+// convertTextMarshaler converts an encoding.TextMarshaler to a string
+func convertTextMarshaler(v reflect.Value) (interface{}, bool, error) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, true, nil
+	}
+	text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return nil, false, fmt.Errorf("error calling MarshalText for type %s: %w", v.Type(), err)
+	}
+	return string(text), true, nil
+}
+
+// convertMap converts a map with string, integer or encoding.TextMarshaler
+// keys to a map[string]interface{}
+func convertMap(v reflect.Value, depth int) (interface{}, bool, error) {
+	if v.IsNil() {
+		return nil, true, nil
+	}
+
+	if m, ok := v.Interface().(map[string]interface{}); ok {
+		var converted map[string]interface{}
+		for key, val := range m {
+			cval, changed, err := convertValue(reflect.ValueOf(val), depth+1)
+			if err != nil {
+				return nil, false, err
+			}
+			if changed {
+				if converted == nil {
+					converted = make(map[string]interface{}, len(m))
+					for k, v := range m {
+						converted[k] = v
+					}
+				}
+				converted[key] = cval
+			}
+		}
+		if converted == nil {
+			return m, false, nil
+		}
+		return converted, true, nil
+	}
+
+	converted := make(map[string]interface{}, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := mapKey(iter.Key())
+		if err != nil {
+			return nil, false, err
+		}
+		val, _, err := convertValue(iter.Value(), depth+1)
+		if err != nil {
+			return nil, false, err
+		}
+		converted[key] = val
+	}
+	return converted, true, nil
+}
+
+// mapKey converts a map key to the string encoding/json uses for it
+func mapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", nil
+		}
+		text, err := tm.MarshalText()
+		if err != nil {
+			return "", fmt.Errorf("error calling MarshalText for type %s: %w", k.Type(), err)
+		}
+		return string(text), nil
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported map key type: %s", k.Type())
+}
+
+// convertSlice converts a slice or array to a []interface{}
+func convertSlice(v reflect.Value, depth int) (interface{}, bool, error) {
+	if s, ok := v.Interface().([]interface{}); ok {
+		var converted []interface{}
+		for i, val := range s {
+			cval, changed, err := convertValue(reflect.ValueOf(val), depth+1)
+			if err != nil {
+				return nil, false, err
+			}
+			if changed {
+				if converted == nil {
+					converted = make([]interface{}, len(s))
+					copy(converted, s)
+				}
+				converted[i] = cval
+			}
+		}
+		if converted == nil {
+			return s, false, nil
+		}
+		return converted, true, nil
+	}
+
+	converted := make([]interface{}, v.Len())
+	for i := range converted {
+		val, _, err := convertValue(v.Index(i), depth+1)
+		if err != nil {
+			return nil, false, err
+		}
+		converted[i] = val
+	}
+	return converted, true, nil
+}
+
+// convertStruct converts a struct to a map[string]interface{} keyed by the
+// names encoding/json gives its fields
+func convertStruct(v reflect.Value, depth int) (interface{}, bool, error) {
+	converted := map[string]interface{}{}
+	for _, f := range cachedStructFields(v.Type()) {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		val, _, err := convertValue(fv, depth+1)
+		if err != nil {
+			return nil, false, err
+		}
+		if f.quoted {
+			val = quoteValue(val)
+		}
+		converted[f.name] = val
+	}
+	return converted, true, nil
+}
+
+// fieldByIndex returns the field of v at index, reporting false when the
+// field is promoted through a nil embedded pointer
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isEmptyValue reports whether a field tagged omitempty is left out
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// quoteValue encodes a scalar as a string, as the string option of a json
+// struct tag does
+func quoteValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case nil:
+		return nil
+	case string:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return fmt.Sprint(val)
+}
+
+// structField describes a field of a struct as encoding/json sees it
+type structField struct {
+	name      string
+	index     []int
+	tagged    bool
+	omitEmpty bool
+	quoted    bool
+}
+
+var structFieldCache sync.Map
+
+// cachedStructFields returns the fields of t, caching the result
+func cachedStructFields(t reflect.Type) []structField {
+	if fields, ok := structFieldCache.Load(t); ok {
+		return fields.([]structField)
+	}
+	fields, _ := structFieldCache.LoadOrStore(t, structFields(t))
+	return fields.([]structField)
+}
+
+// structFields lists the fields of t encoding/json encodes, promoting the
+// fields of embedded structs. Of several fields with the same name the
+// least nested one wins, preferring tagged fields, and fields that
+// remain ambiguous are dropped
+func structFields(t reflect.Type) []structField {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	fields := []structField{}
+	visited := map[reflect.Type]bool{}
+	next := []embedded{{typ: t}}
+	for len(next) > 0 {
+		current := next
+		next = nil
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if sf.Anonymous {
+					if sf.PkgPath != "" && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if sf.PkgPath != "" {
+					continue
+				}
+
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts := tag, ""
+				if i := strings.Index(tag, ","); i >= 0 {
+					name, opts = tag[:i], tag[i:]
+				}
+				if !validTagName(name) {
+					name = ""
+				}
+
+				index := make([]int, len(e.index)+1)
+				copy(index, e.index)
+				index[len(e.index)] = i
+
+				if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{typ: ft, index: index})
+					continue
+				}
+
+				f := structField{
+					name:      name,
+					index:     index,
+					tagged:    name != "",
+					omitEmpty: strings.Contains(opts+",", ",omitempty,"),
+				}
+				if f.name == "" {
+					f.name = sf.Name
+				}
+				if strings.Contains(opts+",", ",string,") {
+					switch ft.Kind() {
+					case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+						reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+						reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+						f.quoted = true
+					}
+				}
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		if fields[i].name != fields[j].name {
+			return fields[i].name < fields[j].name
+		}
+		if len(fields[i].index) != len(fields[j].index) {
+			return len(fields[i].index) < len(fields[j].index)
+		}
+		return fields[i].tagged && !fields[j].tagged
+	})
+
+	dominant := fields[:0]
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].name == fields[i].name {
+			j++
+		}
+		group := fields[i:j]
+		if len(group) == 1 || len(group[1].index) > len(group[0].index) || group[0].tagged && !group[1].tagged {
+			dominant = append(dominant, group[0])
+		}
+		i = j
+	}
+	return dominant
+}
+
+// validTagName reports whether name can be used as the key of a field,
+// mirroring the names encoding/json accepts in struct tags
+func validTagName(name string) bool {
+	for _, c := range name {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+		case c == '\\' || c == '"' || c == '\'' || c == '`' || c == ',':
+			return false
+		case !strconv.IsPrint(c):
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,192 @@
+package jsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type valuesColor int
+
+func (c valuesColor) MarshalText() ([]byte, error) {
+	return []byte([]string{"red", "green"}[c]), nil
+}
+
+type valuesPoint struct {
+	X, Y int
+}
+
+func (p *valuesPoint) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("[%d, %d]", p.X, p.Y)), nil
+}
+
+type valuesBase struct {
+	ID      string `json:"id"`
+	Created time.Time
+	Name    string
+}
+
+type valuesItem struct {
+	valuesBase
+	*valuesExtra
+	Name     string            `json:"name"`
+	Count    int               `json:"count,omitempty"`
+	Price    float64           `json:"price,string"`
+	Tags     []string          `json:"tags"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Colors   map[valuesColor]int
+	Location valuesPoint `json:"location"`
+	Raw      json.RawMessage
+	Data     []byte
+	Big      big.Int
+	Parent   *valuesItem `json:"parent,omitempty"`
+	Skipped  string      `json:"-"`
+	internal string
+}
+
+type valuesExtra struct {
+	Note string `json:"note"`
+}
+
+func TestJSONValue(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	item := &valuesItem{
+		valuesBase: valuesBase{ID: "a1", Created: created, Name: "shadowed"},
+		Name:       "widget",
+		Price:      1.5,
+		Tags:       []string{"x", "y"},
+		Colors:     map[valuesColor]int{1: 2},
+		Location:   valuesPoint{X: 1, Y: 2},
+		Raw:        json.RawMessage(`{"a": 1}`),
+		Data:       []byte("hi"),
+		Skipped:    "skipped",
+		internal:   "internal",
+	}
+	item.Big.SetInt64(7)
+
+	got, err := jsonValue(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := got.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected an object. got: %T", got)
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expect map[string]interface{}
+	if err := decodeJSON(data, &expect); err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != len(expect) {
+		t.Errorf("expected keys %v. got: %v", expect, m)
+	}
+	for key, val := range expect {
+		if !jsonEqual(val, m[key]) {
+			t.Errorf("%s: expected %v (%T). got: %v (%T)", key, val, val, m[key], m[key])
+		}
+	}
+
+	doc := map[string]interface{}{"a": []interface{}{1.0, "b", nil}}
+	if got, err := jsonValue(doc); err != nil {
+		t.Fatal(err)
+	} else if reflect.ValueOf(got).Pointer() != reflect.ValueOf(doc).Pointer() {
+		t.Errorf("expected decoded JSON not to be copied")
+	}
+
+	invalid := []interface{}{
+		make(chan int),
+		map[bool]int{true: 1},
+		[]interface{}{func() {}},
+	}
+	for _, val := range invalid {
+		if _, err := jsonValue(val); err == nil {
+			t.Errorf("%T: expected an error", val)
+		}
+	}
+
+	cycle := &valuesItem{}
+	cycle.Parent = cycle
+	if _, err := jsonValue(cycle); err == nil {
+		t.Errorf("expected a cycle to error")
+	}
+}
+
+func TestValidateGoValues(t *testing.T) {
+	ctx := context.Background()
+	rs := &Schema{}
+	if err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["id", "name", "tags"],
+		"properties": {
+			"id": {"type": "string", "minLength": 2},
+			"name": {"type": "string"},
+			"count": {"type": "integer", "minimum": 1},
+			"tags": {"type": "array", "items": {"enum": ["x", "y"]}, "uniqueItems": true},
+			"attrs": {"additionalProperties": {"type": "string", "maxLength": 3}},
+			"Colors": {"propertyNames": {"enum": ["red", "green"]}},
+			"location": {"type": "array", "items": {"type": "integer"}},
+			"note": {"type": "string"},
+			"parent": {"$ref": "#"}
+		}
+	}`), rs); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := &valuesItem{
+		valuesBase: valuesBase{ID: "a1"},
+		Name:       "widget",
+		Tags:       []string{"x", "y"},
+		Colors:     map[valuesColor]int{0: 1},
+	}
+	if errs := *rs.Validate(ctx, valid).Errs; len(errs) != 0 {
+		t.Errorf("expected no errors. got: %v", errs)
+	}
+	// as with encoding/json, MarshalJSON methods with pointer receivers
+	// aren't used for values that can't be addressed
+	if errs := *rs.Validate(ctx, *valid).Errs; len(errs) != 1 || errs[0].PropertyPath != "/location" {
+		t.Errorf("expected a location error validating a struct value. got: %v", errs)
+	}
+
+	invalid := &valuesItem{
+		valuesBase:  valuesBase{ID: "a"},
+		valuesExtra: &valuesExtra{Note: "note"},
+		Name:        "widget",
+		Count:       -1,
+		Tags:        []string{"x", "z", "x"},
+		Attrs:       map[string]string{"size": "large"},
+		Parent:      &valuesItem{Name: "parent"},
+	}
+	expect := []string{
+		`/attrs/size: "large" max length of 3 characters exceeded: large`,
+		`/count: -1 must be greater than or equal to 1`,
+		`/id: "a" min length of 2 characters required: a`,
+		`/parent/id: "" min length of 2 characters required: `,
+		`/parent/tags: type should be array, got null`,
+		`/tags/1: "z" should be one of ["x", "y"]`,
+		`/tags: ["x","z","x"] array items must be unique. duplicated entry: x`,
+	}
+	errs := *rs.Validate(ctx, invalid).Errs
+	got := make([]string, len(errs))
+	for i, e := range errs {
+		got[i] = e.Error()
+	}
+	sort.Strings(got)
+	if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("expected errors:\n%s\ngot:\n%s", strings.Join(expect, "\n"), strings.Join(got, "\n"))
+	}
+
+	errs = *rs.Validate(ctx, map[string]interface{}{"id": make(chan int)}).Errs
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "unsupported type") {
+		t.Errorf("expected an unsupported type error. got: %v", errs)
+	}
+}
@@ -0,0 +1,191 @@
+package jsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// standardVocabularies lists the keywords defined by the vocabularies
+// of draft2019_09 and draft2020_12. The keywords themselves are provided
+// by the draft keyword set loaded in the registry
+var standardVocabularies = map[string][]string{
+	"https://json-schema.org/draft/2019-09/vocab/core": {
+		"$schema", "$id", "$ref", "$recursiveRef", "$anchor", "$recursiveAnchor", "$defs", "$comment", "$vocabulary",
+	},
+	"https://json-schema.org/draft/2019-09/vocab/applicator": {
+		"additionalItems", "unevaluatedItems", "items", "contains", "additionalProperties", "unevaluatedProperties",
+		"properties", "patternProperties", "dependentSchemas", "propertyNames", "if", "then", "else",
+		"allOf", "anyOf", "oneOf", "not",
+	},
+	"https://json-schema.org/draft/2019-09/vocab/validation": validationVocabulary,
+	"https://json-schema.org/draft/2019-09/vocab/meta-data":  metaDataVocabulary,
+	"https://json-schema.org/draft/2019-09/vocab/format":     {"format"},
+	"https://json-schema.org/draft/2019-09/vocab/content":    contentVocabulary,
+
+	"https://json-schema.org/draft/2020-12/vocab/core": {
+		"$schema", "$id", "$ref", "$anchor", "$dynamicRef", "$dynamicAnchor", "$vocabulary", "$comment", "$defs",
+	},
+	"https://json-schema.org/draft/2020-12/vocab/applicator": {
+		"prefixItems", "items", "contains", "additionalProperties", "properties", "patternProperties",
+		"dependentSchemas", "propertyNames", "if", "then", "else", "allOf", "anyOf", "oneOf", "not",
+	},
+	"https://json-schema.org/draft/2020-12/vocab/unevaluated":       {"unevaluatedItems", "unevaluatedProperties"},
+	"https://json-schema.org/draft/2020-12/vocab/validation":        validationVocabulary,
+	"https://json-schema.org/draft/2020-12/vocab/meta-data":         metaDataVocabulary,
+	"https://json-schema.org/draft/2020-12/vocab/format-annotation": {"format"},
+	"https://json-schema.org/draft/2020-12/vocab/format-assertion":  {"format"},
+	"https://json-schema.org/draft/2020-12/vocab/content":           contentVocabulary,
+}
+
+var validationVocabulary = []string{
+	"multipleOf", "maximum", "exclusiveMaximum", "minimum", "exclusiveMinimum", "maxLength", "minLength",
+	"pattern", "maxItems", "minItems", "uniqueItems", "maxContains", "minContains", "maxProperties",
+	"minProperties", "required", "dependentRequired", "const", "enum", "type",
+}
+
+var metaDataVocabulary = []string{
+	"title", "description", "default", "deprecated", "readOnly", "writeOnly", "examples",
+}
+
+var contentVocabulary = []string{
+	"contentEncoding", "contentMediaType", "contentSchema",
+}
+
+// RegisterVocabulary registers a named bundle of keywords with the registry
+// which meta-schemas can enable through the "$vocabulary" keyword
+func (r *KeywordRegistry) RegisterVocabulary(uri string, keywords map[string]KeyMaker) {
+	if r.vocabularies == nil {
+		r.vocabularies = map[string]map[string]KeyMaker{}
+	}
+	r.vocabularies[uri] = keywords
+}
+
+// RegisterVocabulary registers a named bundle of keywords with the registry
+// which meta-schemas can enable through the "$vocabulary" keyword
+func RegisterVocabulary(uri string, keywords map[string]KeyMaker) {
+	r, release := getGlobalKeywordRegistry()
+	defer release()
+
+	r.RegisterVocabulary(uri, keywords)
+}
+
+// isVocabularyKeyword checks if the keyword is defined by any known vocabulary
+func (r *KeywordRegistry) isVocabularyKeyword(prop string) bool {
+	for _, keywords := range standardVocabularies {
+		for _, keyword := range keywords {
+			if keyword == prop {
+				return true
+			}
+		}
+	}
+	for _, keywords := range r.vocabularies {
+		if _, ok := keywords[prop]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadVocabularies restricts the registry to the keywords of the given
+// vocabularies, keyed by URI with a value indicating if the vocabulary
+// is required. Keywords which are not part of any known vocabulary are
+// left in place. An error is returned for unknown required vocabularies
+func (r *KeywordRegistry) LoadVocabularies(vocabularies map[string]bool) error {
+	enabled := map[string]KeyMaker{}
+	for uri, required := range vocabularies {
+		if keywords, ok := standardVocabularies[uri]; ok {
+			for _, prop := range keywords {
+				if maker, ok := r.keywordRegistry[prop]; ok {
+					enabled[prop] = maker
+				}
+			}
+			continue
+		}
+		if keywords, ok := r.vocabularies[uri]; ok {
+			for prop, maker := range keywords {
+				enabled[prop] = maker
+			}
+			continue
+		}
+		if required {
+			return fmt.Errorf("unknown required vocabulary %q", uri)
+		}
+	}
+	if _, ok := vocabularies["https://json-schema.org/draft/2020-12/vocab/format-assertion"]; ok {
+		// the format assertion vocabulary asserts formats by default
+		enabled["format"] = NewFormat
+	}
+
+	for prop := range r.keywordRegistry {
+		if _, ok := enabled[prop]; !ok && r.isVocabularyKeyword(prop) {
+			delete(r.keywordRegistry, prop)
+		}
+	}
+	for prop, maker := range enabled {
+		if _, ok := r.keywordRegistry[prop]; ok {
+			r.keywordRegistry[prop] = maker
+			continue
+		}
+		r.RegisterKeyword(prop, maker)
+	}
+	return nil
+}
+
+// loadMetaSchema configures the registry for schemas declaring a
+// custom meta-schema, using the dialect the meta-schema is written in
+// and the vocabularies it lists
+func (r *KeywordRegistry) loadMetaSchema(meta *Schema) error {
+	if schemaURI, ok := meta.keywords["$schema"].(*SchemaURI); ok && IsKnownDialect(string(*schemaURI)) {
+		r.LoadDialect(string(*schemaURI))
+	}
+	if vocabularies, ok := meta.keywords["$vocabulary"].(*Vocabularies); ok {
+		return r.LoadVocabularies(*vocabularies)
+	}
+	return nil
+}
+
+// metaSchemaFetchCtxKey is the context key of the custom meta-schemas
+// being resolved, guarding against meta-schemas describing each other
+type metaSchemaFetchCtxKey struct{}
+
+// metaSchema resolves the custom meta-schema named by "$schema" with the
+// schema registry of the context the schema is decoded with, or the global
+// registry, fetching it if it isn't registered. A nil schema is returned
+// for meta-schemas in the middle of being resolved
+func (r *KeywordRegistry) metaSchema(uri string) (*Schema, error) {
+	ctx := r.metaSchemaCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	uri = strings.TrimRight(uri, "#")
+	resolving, _ := ctx.Value(metaSchemaFetchCtxKey{}).(map[string]bool)
+	if resolving[uri] {
+		return nil, nil
+	}
+	next := make(map[string]bool, len(resolving)+1)
+	for k := range resolving {
+		next[k] = true
+	}
+	next[uri] = true
+	ctx = context.WithValue(ctx, metaSchemaFetchCtxKey{}, next)
+
+	meta, err := SchemaRegistryFromContext(ctx).root().fetch(ctx, uri, "")
+	if err != nil {
+		return nil, fmt.Errorf("unresolvable $schema %q: %w", uri, err)
+	}
+	return meta, nil
+}
+
+// describesItself reports whether a schema names itself as its
+// meta-schema, as meta-schemas commonly do
+func describesItself(valprops map[string]json.RawMessage, schemaURI string) bool {
+	for _, prop := range []string{"$id", "id"} {
+		var id string
+		if err := json.Unmarshal(valprops[prop], &id); err == nil && strings.TrimRight(id, "#") == strings.TrimRight(schemaURI, "#") {
+			return true
+		}
+	}
+	return false
+}